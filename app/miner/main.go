@@ -0,0 +1,273 @@
+// Package main implements a miner that solves blocks for a node without
+// holding any chain state or private keys.
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/ardanlabs/blockchain/foundation/blockchain/consensus"
+	"github.com/ardanlabs/blockchain/foundation/blockchain/database"
+	"github.com/ardanlabs/blockchain/foundation/blockchain/state"
+	"github.com/ardanlabs/blockchain/foundation/logger"
+	"github.com/ardanlabs/conf/v3"
+	"go.uber.org/zap"
+)
+
+// build is the git version of this program. It is set using build flags in the makefile.
+var build = "develop"
+
+// errNoWork is returned when the node has no transactions to mine.
+var errNoWork = errors.New("no work available")
+
+func main() {
+
+	// Construct the application logger.
+	log, err := logger.New("MINER")
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	defer log.Sync()
+
+	// Perform the startup and shutdown sequence.
+	if err := run(log); err != nil {
+		log.Errorw("startup", "ERROR", err)
+		log.Sync()
+		os.Exit(1)
+	}
+}
+
+func run(log *zap.SugaredLogger) error {
+
+	// =========================================================================
+	// Configuration
+
+	cfg := struct {
+		conf.Version
+		Node         string        `conf:"default:http://localhost:9080"` // Private host of the node
+		Beneficiary  string        // Account receiving the mining reward, the node's beneficiary if empty
+		Token        string        `conf:"mask"`      // API key or JWT with the node role when the node requires authentication
+		Threads      int           `conf:"default:0"` // Zero uses one mining goroutine per CPU
+		PollInterval time.Duration `conf:"default:2s"`
+	}{
+		Version: conf.Version{
+			Build: build,
+			Desc:  "copyright information here",
+		},
+	}
+
+	const prefix = "MINER"
+	help, err := conf.Parse(prefix, &cfg)
+	if err != nil {
+		if errors.Is(err, conf.ErrHelpWanted) {
+			fmt.Println(help)
+			return nil
+		}
+		return fmt.Errorf("parsing config: %w", err)
+	}
+
+	if cfg.Beneficiary != "" && !database.AccountID(cfg.Beneficiary).IsAccountID() {
+		return fmt.Errorf("invalid beneficiary account %q", cfg.Beneficiary)
+	}
+
+	log.Infow("starting miner", "version", build, "node", cfg.Node, "beneficiary", cfg.Beneficiary)
+	defer log.Infow("shutdown complete")
+
+	ev := func(v string, args ...any) {
+		log.Infow(fmt.Sprintf(v, args...))
+	}
+
+	// =========================================================================
+	// Mining Loop
+
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer cancel()
+
+	m := miner{
+		node:        cfg.Node,
+		beneficiary: cfg.Beneficiary,
+		token:       cfg.Token,
+		client:      http.Client{Timeout: 10 * time.Second},
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		default:
+		}
+
+		work, err := m.getWork()
+		if err != nil {
+			if !errors.Is(err, errNoWork) {
+				log.Errorw("getwork", "ERROR", err)
+			}
+			sleep(ctx, cfg.PollInterval)
+			continue
+		}
+
+		log.Infow("getwork", "id", work.ID, "number", work.Header.Number, "target", work.Target)
+
+		block := database.Block{Header: work.Header}
+		if err := m.mine(ctx, &block, work.ID, cfg.Threads, cfg.PollInterval, ev); err != nil {
+			log.Infow("mining stopped", "id", work.ID, "reason", err)
+			continue
+		}
+
+		hash, err := m.submitWork(work.ID, block.Header.Nonce)
+		if err != nil {
+			log.Errorw("submitwork", "id", work.ID, "ERROR", err)
+			continue
+		}
+
+		log.Infow("submitwork", "id", work.ID, "number", work.Header.Number, "hash", hash)
+	}
+}
+
+// =============================================================================
+
+// miner talks to the work api of a node.
+type miner struct {
+	node        string
+	beneficiary string
+	token       string
+	client      http.Client
+}
+
+// mine solves the block while checking the node for new work. Mining is
+// cancelled once the work is no longer the one handed out by the node.
+func (m *miner) mine(ctx context.Context, block *database.Block, id string, threads int, poll time.Duration, ev func(v string, args ...any)) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	go func() {
+		ticker := time.NewTicker(poll)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				work, err := m.getWork()
+				if err == nil && work.ID == id {
+					continue
+				}
+				ev("miner: work[%s] is stale", id)
+				cancel()
+				return
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return consensus.Mine(ctx, block, threads, ev)
+}
+
+func (m *miner) getWork() (state.Work, error) {
+	url := fmt.Sprintf("%s/v1/node/work/get", m.node)
+	if m.beneficiary != "" {
+		url = fmt.Sprintf("%s/%s", url, m.beneficiary)
+	}
+
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return state.Work{}, err
+	}
+
+	resp, err := m.do(req)
+	if err != nil {
+		return state.Work{}, err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return state.Work{}, errNoWork
+	default:
+		return state.Work{}, responseError(resp)
+	}
+
+	var work state.Work
+	if err := json.NewDecoder(resp.Body).Decode(&work); err != nil {
+		return state.Work{}, err
+	}
+
+	return work, nil
+}
+
+func (m *miner) submitWork(id string, nonce uint64) (string, error) {
+	solution := struct {
+		ID    string `json:"id"`
+		Nonce uint64 `json:"nonce"`
+	}{
+		ID:    id,
+		Nonce: nonce,
+	}
+
+	data, err := json.Marshal(solution)
+	if err != nil {
+		return "", err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, m.node+"/v1/node/work/submit", bytes.NewBuffer(data))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := m.do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", responseError(resp)
+	}
+
+	var result struct {
+		Hash string `json:"hash"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", err
+	}
+
+	return result.Hash, nil
+}
+
+// do sends the request to the node with the credentials of the miner.
+func (m *miner) do(req *http.Request) (*http.Response, error) {
+	if m.token != "" {
+		req.Header.Set("Authorization", "Bearer "+m.token)
+	}
+	return m.client.Do(req)
+}
+
+// responseError converts an error response from the node into an error.
+func responseError(resp *http.Response) error {
+	var body struct {
+		Error string `json:"error"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil || body.Error == "" {
+		return fmt.Errorf("node responded with status %d", resp.StatusCode)
+	}
+	return fmt.Errorf("node responded with status %d: %s", resp.StatusCode, body.Error)
+}
+
+// sleep waits for the duration or until the context is cancelled.
+func sleep(ctx context.Context, d time.Duration) {
+	select {
+	case <-time.After(d):
+	case <-ctx.Done():
+	}
+}
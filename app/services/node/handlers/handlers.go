@@ -12,6 +12,7 @@ import (
 	v1 "github.com/ardanlabs/blockchain/app/services/node/handlers/v1"
 	"github.com/ardanlabs/blockchain/business/web/v1/mid"
 	"github.com/ardanlabs/blockchain/foundation/blockchain/state"
+	"github.com/ardanlabs/blockchain/foundation/events"
 	"github.com/ardanlabs/blockchain/foundation/nameservice"
 	"github.com/ardanlabs/blockchain/foundation/web"
 	"go.uber.org/zap"
@@ -23,6 +24,20 @@ type MuxConfig struct {
 	Log      *zap.SugaredLogger
 	State    *state.State
 	NS       *nameservice.NameService
+	Evts     *events.Events
+
+	// Limits of the public routes.
+	PublicLimit mid.RateLimitConfig
+
+	// Credentials and limits of the private routes. The private routes are
+	// open when no credentials are configured.
+	PrivateAuth  mid.AuthConfig
+	PrivateLimit mid.RateLimitConfig
+
+	// Credentials and limits of the admin routes. The admin routes are only
+	// enabled when credentials are configured.
+	AdminAuth  mid.AuthConfig
+	AdminLimit mid.RateLimitConfig
 }
 
 // PublicMux constructs a http.Handler with all application routes defined.
@@ -51,6 +66,9 @@ func PublicMux(cfg MuxConfig) http.Handler {
 		Log:   cfg.Log,
 		State: cfg.State,
 		NS:    cfg.NS,
+		Evts:  cfg.Evts,
+
+		RateLimit: cfg.PublicLimit,
 	})
 
 	return app
@@ -82,8 +100,21 @@ func PrivateMux(cfg MuxConfig) http.Handler {
 		Log:   cfg.Log,
 		State: cfg.State,
 		NS:    cfg.NS,
+
+		Auth:      cfg.PrivateAuth,
+		RateLimit: cfg.PrivateLimit,
 	})
 
+	if cfg.AdminAuth.Enabled() {
+		v1.AdminRoutes(app, v1.Config{
+			Log:   cfg.Log,
+			State: cfg.State,
+
+			Auth:      cfg.AdminAuth,
+			RateLimit: cfg.AdminLimit,
+		})
+	}
+
 	return app
 }
 
@@ -0,0 +1,413 @@
+package handlers_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/ardanlabs/blockchain/app/services/node/handlers"
+	"github.com/ardanlabs/blockchain/foundation/blockchain/consensus"
+	"github.com/ardanlabs/blockchain/foundation/blockchain/database"
+	"github.com/ardanlabs/blockchain/foundation/blockchain/genesis"
+	"github.com/ardanlabs/blockchain/foundation/blockchain/mempool/selector"
+	"github.com/ardanlabs/blockchain/foundation/blockchain/peer"
+	"github.com/ardanlabs/blockchain/foundation/blockchain/state"
+	"github.com/ardanlabs/blockchain/foundation/blockchain/storage/disk"
+	"github.com/ardanlabs/blockchain/foundation/blockchain/transport"
+	"github.com/ethereum/go-ethereum/crypto"
+	"go.uber.org/zap"
+)
+
+// CORE NOTE: These tests run several nodes in the same process connected by
+// the memory transport. The worker goroutines are replaced by a worker that
+// does nothing, so the tests decide when blocks are mined, sent and synced and
+// the outcome doesn't depend on timers. The seed of the network makes the
+// dropped requests and the latency repeatable.
+
+const seed = 42
+
+// TestSyncHeadersFirst checks a new node downloads the chain of a peer over a
+// slow and lossy network, and a third node downloads it from both peers.
+func TestSyncHeadersFirst(t *testing.T) {
+	net := transport.NewNetwork(seed)
+	gen := newGenesis(consensus.PoW)
+	wallet := newWallet(t)
+	gen.Balances[string(wallet.id)] = 1_000_000
+
+	n1 := newNode(t, net, gen, "node1:9080")
+
+	const blocks = 8
+	for i := 0; i < blocks; i++ {
+		wallet.send(t, n1)
+		mine(t, n1)
+	}
+
+	net.SetLatency(time.Millisecond, 2*time.Millisecond)
+	net.SetDropRate(0.2)
+
+	n2 := newNode(t, net, gen, "node2:9080", "node1:9080")
+	syncUntil(t, n2, blocks)
+	sameChain(t, n1, n2)
+
+	net.SetDropRate(0)
+
+	n3 := newNode(t, net, gen, "node3:9080", "node1:9080", "node2:9080")
+	syncUntil(t, n3, blocks)
+	sameChain(t, n1, n3)
+}
+
+// TestPartitionHeal checks a node cut off from the network can't sync while
+// partitioned and catches up once the partition heals.
+func TestPartitionHeal(t *testing.T) {
+	net := transport.NewNetwork(seed)
+	gen := newGenesis(consensus.PoW)
+	wallet := newWallet(t)
+	gen.Balances[string(wallet.id)] = 1_000_000
+
+	n1 := newNode(t, net, gen, "node1:9080")
+	n2 := newNode(t, net, gen, "node2:9080", "node1:9080")
+
+	net.Partition([]string{"node1:9080"}, []string{"node2:9080"})
+
+	for i := 0; i < 3; i++ {
+		wallet.send(t, n1)
+		mine(t, n1)
+	}
+
+	if _, err := n2.NetRequestPeerStatus(peer.New("node1:9080")); !errors.Is(err, transport.ErrUnreachable) {
+		t.Fatalf("expected the peer to be unreachable while partitioned, got %v", err)
+	}
+
+	net.Heal()
+
+	syncUntil(t, n2, 3)
+	sameChain(t, n1, n2)
+}
+
+// TestForkRefused checks the nodes of a partition that mined different blocks
+// at the same height keep their own chain once the partition heals, refusing
+// both the blocks and the headers of the other side.
+func TestForkRefused(t *testing.T) {
+	net := transport.NewNetwork(seed)
+	gen := newGenesis(consensus.PoW)
+	w1 := newWallet(t)
+	w2 := newWallet(t)
+	gen.Balances[string(w1.id)] = 1_000_000
+	gen.Balances[string(w2.id)] = 1_000_000
+
+	n1 := newNode(t, net, gen, "node1:9080", "node2:9080")
+	n2 := newNode(t, net, gen, "node2:9080", "node1:9080")
+
+	net.Partition([]string{"node1:9080"}, []string{"node2:9080"})
+
+	w1.send(t, n1)
+	mine(t, n1)
+	w1.send(t, n1)
+	fork := mine(t, n1)
+
+	w2.send(t, n2)
+	local := mine(t, n2)
+
+	net.Heal()
+
+	results := n1.NetSendBlockToPeers(fork)
+	if len(results) != 1 || results[0].Status != state.BroadcastRejected {
+		t.Fatalf("expected the forked block to be rejected, got %+v", results)
+	}
+
+	statuses := map[peer.Peer]peer.PeerStatus{}
+	pr := peer.New("node1:9080")
+	status, err := n2.NetRequestPeerStatus(pr)
+	if err != nil {
+		t.Fatalf("requesting status: %s", err)
+	}
+	statuses[pr] = status
+
+	if err := n2.NetSyncBlocks(statuses); !errors.Is(err, state.ErrSyncHeaders) {
+		t.Fatalf("expected the forked headers to be refused, got %v", err)
+	}
+
+	if latest := n2.LatestBlock(); latest.Hash() != local.Hash() {
+		t.Fatalf("expected node2 to keep its block %s, got %s", local.Hash(), latest.Hash())
+	}
+}
+
+// TestPoARotation checks exactly one node is selected to produce every block,
+// the other nodes accept the block and the selection rotates between the
+// nodes.
+func TestPoARotation(t *testing.T) {
+	net := transport.NewNetwork(seed)
+	net.SetLatency(time.Millisecond, time.Millisecond)
+
+	gen := newGenesis(consensus.PoA)
+	wallet := newWallet(t)
+	gen.Balances[string(wallet.id)] = 1_000_000
+
+	hosts := []string{"node1:9080", "node2:9080", "node3:9080"}
+	keys := make([]*ecdsa.PrivateKey, len(hosts))
+	for i := range keys {
+		keys[i] = newKey(t)
+		gen.Authorities = append(gen.Authorities, string(database.PublicKeyToAccountID(keys[i].PublicKey)))
+	}
+
+	nodes := make([]*state.State, len(hosts))
+	for i, host := range hosts {
+		nodes[i] = newAuthorityNode(t, net, gen, keys[i], host, hosts...)
+	}
+
+	producers := make(map[string]int)
+	for round := 1; round <= 20 && len(producers) < len(hosts); round++ {
+		tx := wallet.sign(t, gen.ChainID)
+		for _, n := range nodes {
+			if _, err := n.UpsertWalletTx(tx); err != nil {
+				t.Fatalf("round %d: %s: submitting tx: %s", round, n.Host(), err)
+			}
+		}
+
+		var producer *state.State
+		var block database.Block
+		for _, n := range nodes {
+			b, err := n.MineNewBlock(context.Background())
+			if errors.Is(err, consensus.ErrNotProposer) {
+				continue
+			}
+			if err != nil {
+				t.Fatalf("round %d: %s: mining: %s", round, n.Host(), err)
+			}
+			if producer != nil {
+				t.Fatalf("round %d: both %s and %s produced a block", round, producer.Host(), n.Host())
+			}
+			producer, block = n, b
+		}
+
+		if producer == nil {
+			t.Fatalf("round %d: no node was selected", round)
+		}
+		producers[producer.Host()]++
+
+		for _, result := range producer.NetSendBlockToPeers(block) {
+			if result.Status != state.BroadcastAccepted {
+				t.Fatalf("round %d: %s: block not accepted: %+v", round, result.Host, result)
+			}
+		}
+
+		for _, n := range nodes {
+			if latest := n.LatestBlock(); latest.Hash() != block.Hash() {
+				t.Fatalf("round %d: %s: expected head %s, got %s", round, n.Host(), block.Hash(), latest.Hash())
+			}
+		}
+	}
+
+	if len(producers) < 2 {
+		t.Fatalf("expected the selection to rotate, got producers %v", producers)
+	}
+}
+
+// =============================================================================
+
+// newGenesis constructs the genesis of the test networks.
+func newGenesis(consensus string) genesis.Genesis {
+	return genesis.Genesis{
+		Date:          time.Date(2022, time.January, 1, 0, 0, 0, 0, time.UTC),
+		ChainID:       1,
+		Consensus:     consensus,
+		TransPerBlock: 10,
+		Difficulty:    1,
+		MiningReward:  700,
+		GasPrice:      15,
+		Balances:      make(map[string]uint64),
+	}
+}
+
+// newNode constructs a node at the host knowing the origin peers and registers
+// its private api on the network.
+func newNode(t *testing.T, net *transport.Network, gen genesis.Genesis, host string, origins ...string) *state.State {
+	t.Helper()
+
+	return newAuthorityNode(t, net, gen, newKey(t), host, origins...)
+}
+
+// newAuthorityNode constructs a node like newNode with the beneficiary key
+// that seals its blocks.
+func newAuthorityNode(t *testing.T, net *transport.Network, gen genesis.Genesis, key *ecdsa.PrivateKey, host string, origins ...string) *state.State {
+	t.Helper()
+
+	storage, err := disk.New(t.TempDir() + "/")
+	if err != nil {
+		t.Fatalf("%s: creating storage: %s", host, err)
+	}
+
+	peers := peer.NewPeerSet()
+	for _, origin := range origins {
+		peers.Add(peer.New(origin))
+	}
+	peers.Add(peer.New(host))
+
+	st, err := state.New(state.Config{
+		Beneficiary:    database.PublicKeyToAccountID(key.PublicKey),
+		BeneficiaryKey: key,
+		NodeKey:        newKey(t),
+		Host:           host,
+		Storage:        storage,
+		Genesis:        gen,
+		SelectStrategy: selector.StrategyTip,
+		KnownPeers:     peers,
+		Transport:      net.Transport(host),
+	})
+	if err != nil {
+		t.Fatalf("%s: creating state: %s", host, err)
+	}
+	st.Worker = idleWorker{}
+
+	net.Register(host, handlers.PrivateMux(handlers.MuxConfig{
+		Shutdown: make(chan os.Signal, 1),
+		Log:      zap.NewNop().Sugar(),
+		State:    st,
+	}))
+
+	t.Cleanup(func() {
+		net.Unregister(host)
+		st.Shutdown()
+	})
+
+	return st
+}
+
+// mine mines a block with the transactions in the mempool of the node.
+func mine(t *testing.T, st *state.State) database.Block {
+	t.Helper()
+
+	block, err := st.MineNewBlock(context.Background())
+	if err != nil {
+		t.Fatalf("%s: mining: %s", st.Host(), err)
+	}
+	return block
+}
+
+// syncUntil syncs the node with its peers until it reaches the height. A sync
+// that fails part way, like the worker does, resumes on the next attempt.
+func syncUntil(t *testing.T, st *state.State, height uint64) {
+	t.Helper()
+
+	for attempt := 0; attempt < 50; attempt++ {
+		statuses := make(map[peer.Peer]peer.PeerStatus)
+		for _, pr := range st.KnowExternalPeers() {
+			status, err := st.NetRequestPeerStatus(pr)
+			if err != nil {
+				continue
+			}
+			statuses[pr] = status
+		}
+
+		if err := st.NetSyncBlocks(statuses); err != nil {
+			t.Logf("%s: attempt %d: sync: %s", st.Host(), attempt, err)
+		}
+
+		if st.LatestBlock().Header.Number >= height {
+			return
+		}
+	}
+
+	t.Fatalf("%s: expected height %d, got %d", st.Host(), height, st.LatestBlock().Header.Number)
+}
+
+// sameChain checks both nodes hold the same blocks and accounts.
+func sameChain(t *testing.T, a *state.State, b *state.State) {
+	t.Helper()
+
+	latest := a.LatestBlock().Header.Number
+	if b.LatestBlock().Header.Number != latest {
+		t.Fatalf("%s at blk[%d], %s at blk[%d]", a.Host(), latest, b.Host(), b.LatestBlock().Header.Number)
+	}
+
+	blocksA, err := a.QueryBlocksByNumber(1, latest)
+	if err != nil {
+		t.Fatalf("%s: querying blocks: %s", a.Host(), err)
+	}
+	blocksB, err := b.QueryBlocksByNumber(1, latest)
+	if err != nil {
+		t.Fatalf("%s: querying blocks: %s", b.Host(), err)
+	}
+
+	for i := range blocksA {
+		if blocksA[i].Hash() != blocksB[i].Hash() {
+			t.Fatalf("blk[%d]: %s has %s, %s has %s", i+1, a.Host(), blocksA[i].Hash(), b.Host(), blocksB[i].Hash())
+		}
+	}
+}
+
+// =============================================================================
+
+// wallet signs transfers from an account funded by the genesis.
+type wallet struct {
+	key   *ecdsa.PrivateKey
+	id    database.AccountID
+	nonce uint64
+}
+
+func newWallet(t *testing.T) *wallet {
+	key := newKey(t)
+	return &wallet{
+		key: key,
+		id:  database.PublicKeyToAccountID(key.PublicKey),
+	}
+}
+
+// sign signs the next transfer of the wallet.
+func (w *wallet) sign(t *testing.T, chainID uint16) database.SignedTx {
+	t.Helper()
+
+	w.nonce++
+	to := database.PublicKeyToAccountID(newKey(t).PublicKey)
+
+	tx, err := database.NewTx(chainID, w.nonce, w.id, to, 10, 1, nil)
+	if err != nil {
+		t.Fatalf("constructing tx: %s", err)
+	}
+
+	signedTx, err := tx.Sign(w.key)
+	if err != nil {
+		t.Fatalf("signing tx: %s", err)
+	}
+
+	return signedTx
+}
+
+// send submits the next transfer of the wallet to the node.
+func (w *wallet) send(t *testing.T, st *state.State) {
+	t.Helper()
+
+	if _, err := st.UpsertWalletTx(w.sign(t, 1)); err != nil {
+		t.Fatalf("%s: submitting tx: %s", st.Host(), err)
+	}
+}
+
+func newKey(t *testing.T) *ecdsa.PrivateKey {
+	t.Helper()
+
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatalf("generating key: %s", err)
+	}
+	return key
+}
+
+// =============================================================================
+
+// idleWorker replaces the worker goroutines so the tests drive the nodes.
+type idleWorker struct{}
+
+func (idleWorker) Shutdown()                           {}
+func (idleWorker) Sync()                               {}
+func (idleWorker) SignalStartMining()                  {}
+func (idleWorker) SignalCancelMining()                 {}
+func (idleWorker) SignalShareTx(database.BlockTx)      {}
+func (idleWorker) SignalShareBlock(database.Block)     {}
+func (idleWorker) SignalResync()                       {}
+func (idleWorker) SignalShareVote(database.SignedVote) {}
+func (idleWorker) PauseMining()                        {}
+func (idleWorker) ResumeMining()                       {}
+func (idleWorker) Status() state.WorkerStatus          { return state.WorkerStatus{} }
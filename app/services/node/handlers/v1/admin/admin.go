@@ -0,0 +1,171 @@
+// Package admin maintains the group of handlers for node operators.
+package admin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	v1 "github.com/ardanlabs/blockchain/business/web/v1"
+	"github.com/ardanlabs/blockchain/foundation/blockchain/peer"
+	"github.com/ardanlabs/blockchain/foundation/blockchain/state"
+	"github.com/ardanlabs/blockchain/foundation/web"
+	"go.uber.org/zap"
+)
+
+// CORE NOTE: The admin routes let an operator change a running node instead
+// of restarting it with new flags. They sit on the private host behind a
+// bearer token and are only registered when a token is configured.
+
+// Handlers manages the set of admin endpoints.
+type Handlers struct {
+	Log   *zap.SugaredLogger
+	State *state.State
+}
+
+// Peers returns the active peers along with the health of every peer the
+// node knows about.
+func (h Handlers) Peers(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	resp := peers{
+		Active: h.State.KnownPeers(),
+		Health: h.State.PeerHealth(),
+	}
+
+	return web.Respond(ctx, w, resp, http.StatusOK)
+}
+
+// AddPeer adds the peer to the active peers.
+func (h Handlers) AddPeer(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	var req peerHost
+	if err := web.Decode(r, &req); err != nil {
+		return v1.NewRequestError(err, http.StatusBadRequest)
+	}
+
+	if req.Host == "" {
+		return v1.NewRequestError(errors.New("host is required"), http.StatusBadRequest)
+	}
+
+	if !h.State.AddKnownPeer(peer.New(req.Host)) {
+		return v1.NewRequestError(fmt.Errorf("peer %s not added, it's already active, banned or the peers are full", req.Host), http.StatusConflict)
+	}
+
+	return web.Respond(ctx, w, status{Status: "peer added"}, http.StatusOK)
+}
+
+// RemovePeer removes the peer from the active peers.
+func (h Handlers) RemovePeer(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	host := web.Param(r, "host")
+
+	if !h.State.RemovePeer(host) {
+		return v1.NewRequestError(fmt.Errorf("peer %s is not active", host), http.StatusNotFound)
+	}
+
+	return web.Respond(ctx, w, status{Status: "peer removed"}, http.StatusOK)
+}
+
+// BanPeer bans the peer. Without a duration the ban is for good.
+func (h Handlers) BanPeer(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	var req ban
+	if err := web.Decode(r, &req); err != nil {
+		return v1.NewRequestError(err, http.StatusBadRequest)
+	}
+
+	var d time.Duration
+	if req.Duration != "" {
+		var err error
+		if d, err = time.ParseDuration(req.Duration); err != nil || d < 0 {
+			return v1.NewRequestError(fmt.Errorf("invalid duration %q", req.Duration), http.StatusBadRequest)
+		}
+	}
+
+	if req.Reason == "" {
+		req.Reason = "banned by operator"
+	}
+
+	h.State.BanPeer(web.Param(r, "host"), d, req.Reason)
+
+	return web.Respond(ctx, w, status{Status: "peer banned"}, http.StatusOK)
+}
+
+// UnbanPeer lifts the ban of the peer.
+func (h Handlers) UnbanPeer(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	h.State.UnbanPeer(web.Param(r, "host"))
+
+	return web.Respond(ctx, w, status{Status: "peer unbanned"}, http.StatusOK)
+}
+
+// PauseMining stops the node from producing blocks.
+func (h Handlers) PauseMining(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	h.State.Worker.PauseMining()
+
+	return web.Respond(ctx, w, status{Status: "mining paused"}, http.StatusOK)
+}
+
+// ResumeMining lets the node produce blocks again.
+func (h Handlers) ResumeMining(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	h.State.Worker.ResumeMining()
+
+	return web.Respond(ctx, w, status{Status: "mining resumed"}, http.StatusOK)
+}
+
+// Sync asks the worker to sync the node with the network. The sync runs in
+// the background.
+func (h Handlers) Sync(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	h.State.Worker.SignalResync()
+
+	return web.Respond(ctx, w, status{Status: "sync signaled"}, http.StatusAccepted)
+}
+
+// Mempool returns the transactions in the mempool in the order they would be
+// selected for a block.
+func (h Handlers) Mempool(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	txs := h.State.Mempool()
+
+	resp := mempool{
+		Strategy: h.State.SelectStrategy(),
+		Count:    len(txs),
+		Txs:      txs,
+	}
+
+	return web.Respond(ctx, w, resp, http.StatusOK)
+}
+
+// FlushMempool removes every transaction from the mempool.
+func (h Handlers) FlushMempool(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	n := h.State.FlushMempool()
+
+	return web.Respond(ctx, w, status{Status: fmt.Sprintf("removed %d transactions", n)}, http.StatusOK)
+}
+
+// SetStrategy changes the strategy selecting the transactions of a new block.
+func (h Handlers) SetStrategy(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	var req strategy
+	if err := web.Decode(r, &req); err != nil {
+		return v1.NewRequestError(err, http.StatusBadRequest)
+	}
+
+	if err := h.State.SetSelectStrategy(req.Strategy); err != nil {
+		return v1.NewRequestError(err, http.StatusBadRequest)
+	}
+
+	return web.Respond(ctx, w, status{Status: "strategy changed to " + req.Strategy}, http.StatusOK)
+}
+
+// Worker reports what the worker goroutines are doing.
+func (h Handlers) Worker(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	ws := h.State.Worker.Status()
+
+	resp := worker{
+		Operations:   ws.Operations,
+		MiningPaused: ws.MiningPaused,
+		Mining:       ws.Mining,
+		Syncing:      ws.Syncing,
+		TxQueue:      ws.TxQueue,
+		BlockQueue:   ws.BlockQueue,
+		VoteQueue:    ws.VoteQueue,
+	}
+
+	return web.Respond(ctx, w, resp, http.StatusOK)
+}
@@ -0,0 +1,44 @@
+package admin
+
+import (
+	"github.com/ardanlabs/blockchain/foundation/blockchain/database"
+	"github.com/ardanlabs/blockchain/foundation/blockchain/peer"
+)
+
+type status struct {
+	Status string `json:"status"`
+}
+
+type peers struct {
+	Active []peer.Peer   `json:"active"`
+	Health []peer.Health `json:"health"`
+}
+
+type peerHost struct {
+	Host string `json:"host"`
+}
+
+type ban struct {
+	Duration string `json:"duration"`
+	Reason   string `json:"reason"`
+}
+
+type mempool struct {
+	Strategy string             `json:"strategy"`
+	Count    int                `json:"count"`
+	Txs      []database.BlockTx `json:"txs"`
+}
+
+type strategy struct {
+	Strategy string `json:"strategy"`
+}
+
+type worker struct {
+	Operations   map[string]bool `json:"operations"`
+	MiningPaused bool            `json:"mining_paused"`
+	Mining       bool            `json:"mining"`
+	Syncing      bool            `json:"syncing"`
+	TxQueue      int             `json:"tx_queue"`
+	BlockQueue   int             `json:"block_queue"`
+	VoteQueue    int             `json:"vote_queue"`
+}
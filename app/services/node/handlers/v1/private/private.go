@@ -5,6 +5,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"net"
 	"net/http"
 	"strconv"
 
@@ -29,6 +30,7 @@ func (h Handlers) Status(ctx context.Context, w http.ResponseWriter, r *http.Req
 	latestBlock := h.State.LatestBlock()
 
 	status := peer.PeerStatus{
+		NodeID:          h.State.NodeID(),
 		LatestBlockHash: latestBlock.Hash(),
 		LatestBlockNum:  latestBlock.Header.Number,
 		KnownPeers:      h.State.KnowExternalPeers(),
@@ -67,8 +69,27 @@ func (h Handlers) BlocksByNumber(ctx context.Context, w http.ResponseWriter, r *
 		return v1.NewRequestError(fmt.Errorf("to must be greater than from"), http.StatusBadRequest)
 	}
 
+	// The range is limited so a sync pulls blocks in pages.
+	latest := h.State.LatestBlock().Header.Number
+	if from == state.QueryLatest {
+		from = latest
+	}
+	if to > latest {
+		to = latest
+	}
+	if from > to {
+		return v1.NewRequestError(fmt.Errorf("no blocks found"), http.StatusNotFound)
+	}
+	if to-from >= state.MaxBlocksPerRequest {
+		to = from + state.MaxBlocksPerRequest - 1
+	}
+
+	// A node restored from a snapshot can't serve the blocks before it.
 	blocks, err := h.State.QueryBlocksByNumber(from, to)
-	if err != nil {
+	switch {
+	case errors.Is(err, database.ErrBlockPruned):
+		return v1.NewRequestError(err, http.StatusGone)
+	case err != nil:
 		return v1.NewRequestError(err, http.StatusInternalServerError)
 	}
 	if len(blocks) == 0 {
@@ -83,22 +104,139 @@ func (h Handlers) BlocksByNumber(ctx context.Context, w http.ResponseWriter, r *
 	return web.Respond(ctx, w, blockData, http.StatusOK)
 }
 
+// HeadersByNumber returns the block headers in the specified range. The range
+// is limited so a sync pulls headers in pages.
+func (h Handlers) HeadersByNumber(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	from, err := strconv.ParseUint(web.Param(r, "from"), 10, 64)
+	if err != nil {
+		return v1.NewRequestError(err, http.StatusBadRequest)
+	}
+
+	to, err := strconv.ParseUint(web.Param(r, "to"), 10, 64)
+	if err != nil {
+		return v1.NewRequestError(err, http.StatusBadRequest)
+	}
+
+	if from > to {
+		return v1.NewRequestError(fmt.Errorf("to must be greater than from"), http.StatusBadRequest)
+	}
+
+	if to-from >= state.MaxHeadersPerRequest {
+		to = from + state.MaxHeadersPerRequest - 1
+	}
+
+	if latest := h.State.LatestBlock().Header.Number; to > latest {
+		to = latest
+	}
+
+	if from > to {
+		return v1.NewRequestError(fmt.Errorf("no blocks found"), http.StatusNotFound)
+	}
+
+	headers, err := h.State.QueryHeadersByNumber(from, to)
+	if err != nil {
+		return v1.NewRequestError(err, http.StatusInternalServerError)
+	}
+
+	return web.Respond(ctx, w, headers, http.StatusOK)
+}
+
+// Snapshot returns the description of the latest state snapshot.
+func (h Handlers) Snapshot(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	info, err := h.State.LatestSnapshotInfo()
+	if err != nil {
+		return v1.NewRequestError(err, http.StatusNotFound)
+	}
+
+	return web.Respond(ctx, w, info, http.StatusOK)
+}
+
+// SnapshotChunk returns a chunk of the accounts of a state snapshot.
+func (h Handlers) SnapshotChunk(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	number, err := strconv.ParseUint(web.Param(r, "number"), 10, 64)
+	if err != nil {
+		return v1.NewRequestError(err, http.StatusBadRequest)
+	}
+
+	chunk, err := strconv.Atoi(web.Param(r, "chunk"))
+	if err != nil {
+		return v1.NewRequestError(err, http.StatusBadRequest)
+	}
+
+	sc, err := h.State.QuerySnapshotChunk(number, chunk)
+	if err != nil {
+		return v1.NewRequestError(err, http.StatusNotFound)
+	}
+
+	return web.Respond(ctx, w, sc, http.StatusOK)
+}
+
+// TxByHash returns the transaction in the mempool with the specified hash.
+func (h Handlers) TxByHash(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	txs := h.State.QueryTxs([]string{web.Param(r, "hash")})
+	if len(txs) == 0 {
+		return v1.NewRequestError(fmt.Errorf("transaction not found"), http.StatusNotFound)
+	}
+
+	return web.Respond(ctx, w, txs[0], http.StatusOK)
+}
+
+// BlockByHash returns the block with the specified hash. The compact form of
+// the block is returned when the compact query parameter is true.
+func (h Handlers) BlockByHash(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	block, err := h.State.QueryBlockByHash(web.Param(r, "hash"))
+	if err != nil {
+		return v1.NewRequestError(err, http.StatusNotFound)
+	}
+
+	if r.URL.Query().Get("compact") == "true" {
+		return web.Respond(ctx, w, database.NewCompactBlock(block), http.StatusOK)
+	}
+
+	return web.Respond(ctx, w, database.NewBlockData(block), http.StatusOK)
+}
+
+// PeerHealth returns the health score, backoff and ban state of the peers.
+func (h Handlers) PeerHealth(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	return web.Respond(ctx, w, h.State.PeerHealth(), http.StatusOK)
+}
+
+// AddressBook returns the peers recorded in the address book.
+func (h Handlers) AddressBook(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	return web.Respond(ctx, w, h.State.AddressBook(), http.StatusOK)
+}
+
+// Handshake returns the signed handshake of this node so a peer can confirm
+// the node at this host.
+func (h Handlers) Handshake(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	shs, err := h.State.SignedHandshake()
+	if err != nil {
+		return v1.NewRequestError(err, http.StatusInternalServerError)
+	}
+
+	return web.Respond(ctx, w, shs, http.StatusOK)
+}
+
+// SubmitPeer adds the peer that sent its handshake. The peer is only added
+// once the node at the host of the handshake confirms it's the same node.
 func (h Handlers) SubmitPeer(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
 	v, err := web.GetValues(ctx)
 	if err != nil {
 		return web.NewShutdownError("web value misisng from context")
 	}
 
-	var peer peer.Peer
-	if err := web.Decode(r, &peer); err != nil {
+	var shs peer.SignedHandshake
+	if err := web.Decode(r, &shs); err != nil {
 		return web.NewShutdownError("unabled to decode peer payload")
 	}
 
-	ok := h.State.AddKnownPeer(peer)
-	if !ok {
-		h.Log.Infow("adding peer", "traceId", v.TraceID, "host", peer.Host)
+	pr, err := h.State.AddPeer(shs)
+	if err != nil {
+		return v1.NewRequestError(err, http.StatusBadRequest)
 	}
 
+	h.Log.Infow("adding peer", "traceId", v.TraceID, "host", pr.Host, "nodeId", pr.ID)
+
 	return web.Respond(ctx, w, nil, http.StatusOK)
 
 }
@@ -115,7 +253,7 @@ func (h Handlers) SubmitNodeTransaction(ctx context.Context, w http.ResponseWrit
 	}
 
 	h.Log.Infow("submitting transaction", "traceId", v.TraceID, "tx", tx)
-	if err := h.State.UpsertNodeTransaction(tx); err != nil {
+	if err := h.State.UpsertNodeTransaction(remoteHost(r), tx); err != nil {
 		return v1.NewRequestError(err, http.StatusBadRequest)
 	}
 
@@ -127,6 +265,15 @@ func (h Handlers) SubmitNodeTransaction(ctx context.Context, w http.ResponseWrit
 	return web.Respond(ctx, w, resp, http.StatusOK)
 }
 
+// Stream upgrades the connection to a stream with the peer.
+func (h Handlers) Stream(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	if err := h.State.AcceptStream(w, r); err != nil {
+		return v1.NewRequestError(err, http.StatusBadRequest)
+	}
+
+	return nil
+}
+
 // ProposeBlock
 func (h Handlers) ProposeBlock(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
 
@@ -144,12 +291,37 @@ func (h Handlers) ProposeBlock(ctx context.Context, w http.ResponseWriter, r *ht
 		return err
 	}
 
-	if err := h.State.ProcessProposedBlock(block); err != nil {
+	if err := h.State.ProcessProposedBlock(remoteHost(r), block); err != nil {
 		// if errors.Is(err, database.ErrChainForked) {
 		// h.State.Reorganize()
 		// }
 
-		return v1.NewRequestError(errors.New("block rejected"), http.StatusNotAcceptable)
+		return v1.NewRequestError(fmt.Errorf("block rejected: %w", err), http.StatusNotAcceptable)
+	}
+
+	resp := struct {
+		Status string `json:"status"`
+	}{
+		Status: "ok",
+	}
+	return web.Respond(ctx, w, resp, http.StatusOK)
+}
+
+// SubmitVote accepts a finality vote from another authority.
+func (h Handlers) SubmitVote(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	v, err := web.GetValues(ctx)
+	if err != nil {
+		return web.NewShutdownError("web value misisng from context")
+	}
+
+	var vote database.SignedVote
+	if err := web.Decode(r, &vote); err != nil {
+		return v1.NewRequestError(err, http.StatusBadRequest)
+	}
+
+	h.Log.Infow("submitting vote", "traceId", v.TraceID, "vote", vote, "hash", vote.BlockHash)
+	if err := h.State.ProcessVote(vote); err != nil {
+		return v1.NewRequestError(err, http.StatusBadRequest)
 	}
 
 	resp := struct {
@@ -159,3 +331,70 @@ func (h Handlers) ProposeBlock(ctx context.Context, w http.ResponseWriter, r *ht
 	}
 	return web.Respond(ctx, w, resp, http.StatusOK)
 }
+
+// GetWork returns a block template for an external miner to solve.
+func (h Handlers) GetWork(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	beneficiary := web.Param(r, "beneficiary")
+	if beneficiary != "" && !database.AccountID(beneficiary).IsAccountID() {
+		return v1.NewRequestError(errors.New("invalid beneficiary account"), http.StatusBadRequest)
+	}
+
+	work, err := h.State.GetWork(database.AccountID(beneficiary))
+	if err != nil {
+		switch {
+		case errors.Is(err, state.ErrNoTransactions):
+			return v1.NewRequestError(err, http.StatusNotFound)
+		case errors.Is(err, state.ErrWorkNotSupported):
+			return v1.NewRequestError(err, http.StatusBadRequest)
+		}
+		return err
+	}
+
+	return web.Respond(ctx, w, work, http.StatusOK)
+}
+
+// SubmitWork accepts the nonce an external miner found for a block template.
+func (h Handlers) SubmitWork(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	v, err := web.GetValues(ctx)
+	if err != nil {
+		return web.NewShutdownError("web value misisng from context")
+	}
+
+	var solution struct {
+		ID    string `json:"id"`
+		Nonce uint64 `json:"nonce"`
+	}
+	if err := web.Decode(r, &solution); err != nil {
+		return v1.NewRequestError(err, http.StatusBadRequest)
+	}
+
+	block, err := h.State.SubmitWork(solution.ID, solution.Nonce)
+	if err != nil {
+		return v1.NewRequestError(err, http.StatusNotAcceptable)
+	}
+
+	h.Log.Infow("submitting work", "traceId", v.TraceID, "id", solution.ID, "block", block.Header.Number)
+
+	resp := struct {
+		Status string `json:"status"`
+		Number uint64 `json:"number"`
+		Hash   string `json:"hash"`
+	}{
+		Status: "ok",
+		Number: block.Header.Number,
+		Hash:   block.Hash(),
+	}
+	return web.Respond(ctx, w, resp, http.StatusOK)
+}
+
+// =============================================================================
+
+// remoteHost returns the address the request came from. A peer relaying over
+// HTTP doesn't prove its host, so its penalties are kept by its address.
+func remoteHost(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
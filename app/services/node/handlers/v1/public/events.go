@@ -0,0 +1,231 @@
+package public
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	v1 "github.com/ardanlabs/blockchain/business/web/v1"
+	"github.com/ardanlabs/blockchain/foundation/blockchain/database"
+	"github.com/ardanlabs/blockchain/foundation/events"
+	"github.com/gorilla/websocket"
+)
+
+// Set of values used to keep an event stream alive.
+const (
+	pingInterval = 30 * time.Second
+	writeWait    = 10 * time.Second
+)
+
+// Events streams the events that pass the filter in the query string. The
+// stream is a WebSocket when the client asks for an upgrade and Server-Sent
+// Events otherwise. The types and accounts parameters take a comma separated
+// list and an empty list matches every event describing the chain.
+func (h Handlers) Events(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	filter, err := parseFilter(r)
+	if err != nil {
+		return err
+	}
+
+	if websocket.IsWebSocketUpgrade(r) {
+		return h.streamWebSocket(w, r, filter)
+	}
+
+	return h.streamSSE(w, filter)
+}
+
+// streamWebSocket sends the events as JSON messages over a WebSocket.
+func (h Handlers) streamWebSocket(w http.ResponseWriter, r *http.Request, filter events.Filter) error {
+
+	// The upgrader responds to the client when the upgrade fails.
+	conn, err := h.WS.Upgrade(w, r, nil)
+	if err != nil {
+		h.Log.Infow("events", "status", "websocket upgrade", "ERROR", err)
+		return nil
+	}
+	defer conn.Close()
+
+	// The client never sends anything but reading is required to process
+	// the close and pong messages.
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	send := func(evt events.Event) error {
+		msg := struct {
+			Type string       `json:"type"`
+			Data events.Event `json:"data"`
+		}{
+			Type: evt.EventType(),
+			Data: evt,
+		}
+
+		conn.SetWriteDeadline(time.Now().Add(writeWait))
+		return conn.WriteJSON(msg)
+	}
+
+	ping := func() error {
+		return conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(writeWait))
+	}
+
+	if h.stream(filter, done, send, ping) {
+		msg := websocket.FormatCloseMessage(websocket.CloseGoingAway, "node shutting down")
+		conn.WriteControl(websocket.CloseMessage, msg, time.Now().Add(writeWait))
+	}
+
+	return nil
+}
+
+// streamSSE sends the events as Server-Sent Events. The connection is taken
+// over from the http server so its write timeout doesn't end the stream.
+func (h Handlers) streamSSE(w http.ResponseWriter, filter events.Filter) error {
+	hj, ok := w.(http.Hijacker)
+	if !ok {
+		return v1.NewRequestError(fmt.Errorf("streaming not supported"), http.StatusInternalServerError)
+	}
+
+	conn, rw, err := hj.Hijack()
+	if err != nil {
+		return v1.NewRequestError(err, http.StatusInternalServerError)
+	}
+	defer conn.Close()
+
+	// The headers set by the middleware are lost with the hijack.
+	conn.SetDeadline(time.Time{})
+	header := "HTTP/1.1 200 OK\r\n" +
+		"Content-Type: text/event-stream\r\n" +
+		"Cache-Control: no-cache\r\n" +
+		"Connection: close\r\n" +
+		"Access-Control-Allow-Origin: *\r\n\r\n"
+
+	if err := write(conn, rw.Writer, header); err != nil {
+		return nil
+	}
+
+	// The client doesn't send anything after the request, reading only
+	// tells when it goes away.
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		buf := make([]byte, 512)
+		for {
+			if _, err := rw.Read(buf); err != nil {
+				return
+			}
+		}
+	}()
+
+	send := func(evt events.Event) error {
+		data, err := json.Marshal(evt)
+		if err != nil {
+			return err
+		}
+		return write(conn, rw.Writer, fmt.Sprintf("event: %s\ndata: %s\n\n", evt.EventType(), data))
+	}
+
+	ping := func() error {
+		return write(conn, rw.Writer, ": ping\n\n")
+	}
+
+	h.stream(filter, done, send, ping)
+
+	return nil
+}
+
+// stream subscribes to the events and sends them until the client goes away
+// or a write fails. It reports if the stream ended because the node is
+// shutting down.
+func (h Handlers) stream(filter events.Filter, done <-chan struct{}, send func(events.Event) error, ping func() error) bool {
+	sub := h.Evts.Subscribe(filter)
+	defer h.Evts.Unsubscribe(sub)
+
+	ticker := time.NewTicker(pingInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case evt, ok := <-sub.C:
+			if !ok {
+				return true
+			}
+			if err := send(evt); err != nil {
+				return false
+			}
+
+		case <-ticker.C:
+			if err := ping(); err != nil {
+				return false
+			}
+
+		case <-done:
+			return false
+		}
+	}
+}
+
+// =============================================================================
+
+// write sends the text on the connection and flushes it.
+func write(conn net.Conn, bw *bufio.Writer, text string) error {
+	conn.SetWriteDeadline(time.Now().Add(writeWait))
+	if _, err := bw.WriteString(text); err != nil {
+		return err
+	}
+	return bw.Flush()
+}
+
+// parseFilter reads the types and accounts query parameters. Only the events
+// describing the chain are streamed, never the traces.
+func parseFilter(r *http.Request) (events.Filter, error) {
+	var filter events.Filter
+
+	for _, typ := range split(r.URL.Query().Get("types")) {
+		if !validType(typ) {
+			return events.Filter{}, v1.NewRequestError(fmt.Errorf("unknown event type %q, must be one of %s", typ, strings.Join(events.ChainTypes, ",")), http.StatusBadRequest)
+		}
+		filter.Types = append(filter.Types, typ)
+	}
+
+	if len(filter.Types) == 0 {
+		filter.Types = events.ChainTypes
+	}
+
+	for _, account := range split(r.URL.Query().Get("accounts")) {
+		if _, err := database.ToAccountID(account); err != nil {
+			return events.Filter{}, v1.NewRequestError(fmt.Errorf("account %q: %w", account, err), http.StatusBadRequest)
+		}
+		filter.Accounts = append(filter.Accounts, account)
+	}
+
+	return filter, nil
+}
+
+func validType(typ string) bool {
+	for _, t := range events.ChainTypes {
+		if t == typ {
+			return true
+		}
+	}
+	return false
+}
+
+func split(list string) []string {
+	var values []string
+	for _, v := range strings.Split(list, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			values = append(values, v)
+		}
+	}
+	return values
+}
@@ -0,0 +1,314 @@
+package public
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	v1 "github.com/ardanlabs/blockchain/business/web/v1"
+	"github.com/ardanlabs/blockchain/foundation/blockchain/database"
+	"github.com/ardanlabs/blockchain/foundation/blockchain/state"
+	"github.com/ardanlabs/blockchain/foundation/web"
+)
+
+// Set of values used to page the explorer results.
+const (
+	defaultRows = 20
+	maxRows     = 100
+)
+
+// Blocks returns a page of the committed blocks, newest first.
+func (h Handlers) Blocks(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	pg, rows, err := parsePage(r)
+	if err != nil {
+		return err
+	}
+
+	blocks, total, err := h.State.QueryRecentBlocks(pg, rows)
+	if err != nil {
+		return v1.NewRequestError(err, http.StatusInternalServerError)
+	}
+
+	items := make([]blockInfo, len(blocks))
+	for i, block := range blocks {
+		items[i] = h.toBlockInfo(block, false)
+	}
+
+	resp := page{
+		Page:  pg,
+		Rows:  rows,
+		Total: int(total),
+		Items: items,
+	}
+
+	return web.Respond(ctx, w, resp, http.StatusOK)
+}
+
+// BlockByNumber returns the committed block with the specified number and its
+// transactions.
+func (h Handlers) BlockByNumber(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	number, err := strconv.ParseUint(web.Param(r, "number"), 10, 64)
+	if err != nil {
+		return v1.NewRequestError(err, http.StatusBadRequest)
+	}
+
+	block, err := h.queryBlockByNumber(number)
+	if err != nil {
+		return err
+	}
+
+	return web.Respond(ctx, w, h.toBlockInfo(block, true), http.StatusOK)
+}
+
+// BlockByHash returns the committed block with the specified hash and its
+// transactions.
+func (h Handlers) BlockByHash(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	block, err := h.State.QueryBlockByHash(web.Param(r, "hash"))
+	if err != nil {
+		return v1.NewRequestError(err, http.StatusNotFound)
+	}
+
+	return web.Respond(ctx, w, h.toBlockInfo(block, true), http.StatusOK)
+}
+
+// TxByHash returns the transaction with the specified hash, its block and
+// confirmations. A transaction still in the mempool is reported as pending.
+func (h Handlers) TxByHash(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	rec, err := h.State.QueryTx(web.Param(r, "hash"))
+	if err != nil {
+		return v1.NewRequestError(err, http.StatusNotFound)
+	}
+
+	info := h.toTxInfo(rec)
+
+	// The proof lets the client check the transaction is part of the block
+	// with only the transaction root of the header.
+	if !rec.Pending {
+		block, err := h.State.QueryBlockByHash(rec.BlockHash)
+		if err != nil {
+			return v1.NewRequestError(err, http.StatusInternalServerError)
+		}
+
+		proof, order, err := block.MerkleTree.Proof(rec.Tx)
+		if err != nil {
+			return v1.NewRequestError(err, http.StatusInternalServerError)
+		}
+
+		info.Proof = make([]string, len(proof))
+		for i, p := range proof {
+			info.Proof[i] = "0x" + hex.EncodeToString(p)
+		}
+		info.ProofOfOrder = order
+	}
+
+	return web.Respond(ctx, w, info, http.StatusOK)
+}
+
+// AccountTxs returns the account and a page of its committed transactions,
+// newest first.
+func (h Handlers) AccountTxs(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	accountID, err := database.ToAccountID(web.Param(r, "account"))
+	if err != nil {
+		return v1.NewRequestError(err, http.StatusBadRequest)
+	}
+
+	pg, rows, err := parsePage(r)
+	if err != nil {
+		return err
+	}
+
+	resp, err := h.accountTxs(accountID, pg, rows)
+	if err != nil {
+		return err
+	}
+
+	return web.Respond(ctx, w, resp, http.StatusOK)
+}
+
+// Search finds the block, transaction or account identified by the query. The
+// query can be a block number, a block or transaction hash, an account or the
+// name of an account.
+func (h Handlers) Search(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	query := web.Param(r, "query")
+
+	if number, err := strconv.ParseUint(query, 10, 64); err == nil {
+		block, err := h.queryBlockByNumber(number)
+		if err != nil {
+			return err
+		}
+		return web.Respond(ctx, w, searchResult{Type: "block", Result: h.toBlockInfo(block, true)}, http.StatusOK)
+	}
+
+	if block, err := h.State.QueryBlockByHash(query); err == nil {
+		return web.Respond(ctx, w, searchResult{Type: "block", Result: h.toBlockInfo(block, true)}, http.StatusOK)
+	}
+
+	if rec, err := h.State.QueryTx(query); err == nil {
+		return web.Respond(ctx, w, searchResult{Type: "tx", Result: h.toTxInfo(rec)}, http.StatusOK)
+	}
+
+	accountID, err := database.ToAccountID(query)
+	if err != nil {
+		var exists bool
+		if accountID, exists = h.NS.Reverse(query); !exists {
+			return v1.NewRequestError(fmt.Errorf("nothing found for %q", query), http.StatusNotFound)
+		}
+	}
+
+	resp, err := h.accountTxs(accountID, 1, defaultRows)
+	if err != nil {
+		return err
+	}
+
+	return web.Respond(ctx, w, searchResult{Type: "account", Result: resp}, http.StatusOK)
+}
+
+// =============================================================================
+
+// queryBlockByNumber returns the committed block with the specified number.
+func (h Handlers) queryBlockByNumber(number uint64) (database.Block, error) {
+	if number == 0 || number > h.State.LatestBlock().Header.Number {
+		return database.Block{}, v1.NewRequestError(database.ErrBlockNotFound, http.StatusNotFound)
+	}
+
+	blocks, err := h.State.QueryBlocksByNumber(number, number)
+	switch {
+	case errors.Is(err, database.ErrBlockPruned):
+		return database.Block{}, v1.NewRequestError(err, http.StatusGone)
+	case err != nil:
+		return database.Block{}, v1.NewRequestError(err, http.StatusInternalServerError)
+	}
+
+	return blocks[0], nil
+}
+
+// accountTxs builds the account information with a page of its transactions.
+func (h Handlers) accountTxs(accountID database.AccountID, pg int, rows int) (actTxs, error) {
+	account, err := h.State.QueryAccount(accountID)
+	if err != nil {
+		return actTxs{}, v1.NewRequestError(err, http.StatusNotFound)
+	}
+
+	recs, total, err := h.State.QueryAccountTxs(accountID, pg, rows)
+	if err != nil {
+		return actTxs{}, v1.NewRequestError(err, http.StatusInternalServerError)
+	}
+
+	items := make([]txInfo, len(recs))
+	for i, rec := range recs {
+		items[i] = h.toTxInfo(rec)
+	}
+
+	resp := actTxs{
+		act: act{
+			AccountID: accountID,
+			Name:      h.NS.Lookup(accountID),
+			Balance:   account.Balance,
+			Stake:     account.Stake,
+			Unbonding: account.Unbonding,
+			Nonce:     account.Nonce,
+		},
+		Txs: page{
+			Page:  pg,
+			Rows:  rows,
+			Total: total,
+			Items: items,
+		},
+	}
+
+	return resp, nil
+}
+
+func (h Handlers) toBlockInfo(block database.Block, withTxs bool) blockInfo {
+	values := block.MerkleTree.Values()
+
+	info := blockInfo{
+		Number:          block.Header.Number,
+		Hash:            block.Hash(),
+		PrevBlockHash:   block.Header.PrevBlockHash,
+		Timestamp:       block.Header.Timestamp,
+		Beneficiary:     block.Header.BeneficiaryID,
+		BeneficiaryName: h.NS.Lookup(block.Header.BeneficiaryID),
+		Difficulty:      block.Header.Difficulty,
+		MiningReward:    block.Header.MiningReward,
+		StateRoot:       block.Header.StateRoot,
+		TransRoot:       block.Header.TransRoot,
+		Nonce:           block.Header.Nonce,
+		Finalized:       block.Header.Number <= h.State.Finalized().Number,
+		TxCount:         len(values),
+	}
+
+	if withTxs {
+		latest := h.State.LatestBlock().Header.Number
+
+		info.Txs = make([]txInfo, len(values))
+		for i, tran := range values {
+			info.Txs[i] = h.toTxInfo(state.TxRecord{
+				Tx:            tran,
+				BlockNumber:   block.Header.Number,
+				BlockHash:     info.Hash,
+				Index:         i,
+				Confirmations: latest - block.Header.Number + 1,
+			})
+		}
+	}
+
+	return info
+}
+
+func (h Handlers) toTxInfo(rec state.TxRecord) txInfo {
+	status := "committed"
+	if rec.Pending {
+		status = "pending"
+	}
+
+	return txInfo{
+		tx: tx{
+			FromAccount: rec.Tx.FromID,
+			ToAccount:   rec.Tx.ToID,
+			FromName:    h.NS.Lookup(rec.Tx.FromID),
+			ToName:      h.NS.Lookup(rec.Tx.ToID),
+			ChainID:     rec.Tx.ChainID,
+			Nonce:       rec.Tx.Nonce,
+			Value:       rec.Tx.Value,
+			Tip:         rec.Tx.Tip,
+			Data:        rec.Tx.Data,
+			TimeStamp:   rec.Tx.TimeStamp,
+			GasPrice:    rec.Tx.GasPrice,
+			GasUnits:    rec.Tx.GasUnits,
+			Sig:         rec.Tx.SignatureString(),
+		},
+		Hash:          rec.Tx.HashString(),
+		Status:        status,
+		BlockNumber:   rec.BlockNumber,
+		BlockHash:     rec.BlockHash,
+		Index:         rec.Index,
+		Confirmations: rec.Confirmations,
+	}
+}
+
+// parsePage reads the page and rows query parameters. Pages start at 1.
+func parsePage(r *http.Request) (int, int, error) {
+	pg, rows := 1, defaultRows
+
+	if v := r.URL.Query().Get("page"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			return 0, 0, v1.NewRequestError(errors.New("page must be a number greater than zero"), http.StatusBadRequest)
+		}
+		pg = n
+	}
+
+	if v := r.URL.Query().Get("rows"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > maxRows {
+			return 0, 0, v1.NewRequestError(fmt.Errorf("rows must be a number between 1 and %d", maxRows), http.StatusBadRequest)
+		}
+		rows = n
+	}
+
+	return pg, rows, nil
+}
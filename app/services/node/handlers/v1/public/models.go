@@ -1,6 +1,11 @@
 package public
 
-import "github.com/ardanlabs/blockchain/foundation/blockchain/database"
+import (
+	"time"
+
+	"github.com/ardanlabs/blockchain/foundation/blockchain/database"
+	"github.com/ardanlabs/blockchain/foundation/blockchain/state"
+)
 
 type tx struct {
 	FromAccount database.AccountID `json:"from"`
@@ -26,6 +31,8 @@ type act struct {
 	AccountID database.AccountID `json:"account"`
 	Name      string             `json:"name"`
 	Balance   uint64             `json:"balance"`
+	Stake     uint64             `json:"stake"`
+	Unbonding uint64             `json:"unbonding"`
 	Nonce     uint64             `json:"nonce"`
 }
 
@@ -39,3 +46,125 @@ type block struct {
 	Hash   string `json:"hash"`
 	Number uint64 `json:"number"`
 }
+
+type finalized struct {
+	Number     uint64 `json:"number"`
+	Hash       string `json:"hash"`
+	Precommits int    `json:"precommits"`
+}
+
+type blockInfo struct {
+	Number          uint64             `json:"number"`
+	Hash            string             `json:"hash"`
+	PrevBlockHash   string             `json:"prev_block_hash"`
+	Timestamp       uint64             `json:"timestamp"`
+	Beneficiary     database.AccountID `json:"beneficiary"`
+	BeneficiaryName string             `json:"beneficiary_name"`
+	Difficulty      uint16             `json:"difficulty"`
+	MiningReward    uint64             `json:"mining_reward"`
+	StateRoot       string             `json:"state_root"`
+	TransRoot       string             `json:"trans_root"`
+	Nonce           uint64             `json:"nonce"`
+	Finalized       bool               `json:"finalized"`
+	TxCount         int                `json:"tx_count"`
+	Txs             []txInfo           `json:"txs,omitempty"`
+}
+
+type txInfo struct {
+	tx
+	Hash          string `json:"hash"`
+	Status        string `json:"status"`
+	BlockNumber   uint64 `json:"block_number,omitempty"`
+	BlockHash     string `json:"block_hash,omitempty"`
+	Index         int    `json:"index"`
+	Confirmations uint64 `json:"confirmations"`
+}
+
+type actTxs struct {
+	act
+	Txs page `json:"txs"`
+}
+
+type page struct {
+	Page  int `json:"page"`
+	Rows  int `json:"rows"`
+	Total int `json:"total"`
+	Items any `json:"items"`
+}
+
+type searchResult struct {
+	Type   string `json:"type"`
+	Result any    `json:"result"`
+}
+
+type feeEstimate struct {
+	NextBlock     uint64 `json:"next_block"`
+	Fast          uint64 `json:"within_3_blocks"`
+	Slow          uint64 `json:"within_10_blocks"`
+	MempoolDepth  int    `json:"mempool_depth"`
+	TransPerBlock uint16 `json:"trans_per_block"`
+	BlocksSampled int    `json:"blocks_sampled"`
+}
+
+type txStatus struct {
+	Status        string             `json:"status"`
+	Hash          string             `json:"hash,omitempty"`
+	FromAccount   database.AccountID `json:"from,omitempty"`
+	Nonce         uint64             `json:"nonce,omitempty"`
+	Position      int                `json:"mempool_position,omitempty"`
+	BlockNumber   uint64             `json:"block_number,omitempty"`
+	BlockHash     string             `json:"block_hash,omitempty"`
+	Confirmations uint64             `json:"confirmations,omitempty"`
+	Success       *bool              `json:"success,omitempty"`
+	Reason        string             `json:"reason,omitempty"`
+	ReplacedBy    string             `json:"replaced_by,omitempty"`
+	Time          *time.Time         `json:"time,omitempty"`
+}
+
+// toTxStatus leaves out the fields that don't apply to the status.
+func toTxStatus(ts state.TxStatus) txStatus {
+	resp := txStatus{
+		Status:        ts.Status,
+		Hash:          ts.Hash,
+		FromAccount:   ts.FromID,
+		Nonce:         ts.Nonce,
+		Position:      ts.Position,
+		BlockNumber:   ts.BlockNumber,
+		BlockHash:     ts.BlockHash,
+		Confirmations: ts.Confirmations,
+		Reason:        ts.Reason,
+		ReplacedBy:    ts.ReplacedBy,
+	}
+
+	if ts.Status == state.TxMined {
+		resp.Success = &ts.Success
+	}
+
+	if !ts.Time.IsZero() {
+		resp.Time = &ts.Time
+	}
+
+	return resp
+}
+
+type simulateTx struct {
+	database.SignedTx
+	Sender database.AccountID `json:"sender"`
+}
+
+type simBalance struct {
+	AccountID database.AccountID `json:"account"`
+	Name      string             `json:"name"`
+	Before    uint64             `json:"before"`
+	After     uint64             `json:"after"`
+}
+
+type simulateResult struct {
+	Hash     string       `json:"hash"`
+	Success  bool         `json:"success"`
+	Reason   string       `json:"reason,omitempty"`
+	GasCost  uint64       `json:"gas_cost"`
+	Nonce    uint64       `json:"nonce"`
+	Pending  int          `json:"pending_applied"`
+	Balances []simBalance `json:"balances"`
+}
@@ -3,13 +3,18 @@ package public
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"net/http"
+	"strconv"
 
 	v1 "github.com/ardanlabs/blockchain/business/web/v1"
 	"github.com/ardanlabs/blockchain/foundation/blockchain/database"
 	"github.com/ardanlabs/blockchain/foundation/blockchain/state"
+	"github.com/ardanlabs/blockchain/foundation/events"
 	"github.com/ardanlabs/blockchain/foundation/nameservice"
 	"github.com/ardanlabs/blockchain/foundation/web"
+	"github.com/gorilla/websocket"
 	"go.uber.org/zap"
 )
 
@@ -18,8 +23,8 @@ type Handlers struct {
 	Log   *zap.SugaredLogger
 	State *state.State
 	NS    *nameservice.NameService
-	// WS websocket.Upgrader
-	// Evts *events.Events
+	WS    websocket.Upgrader
+	Evts  *events.Events
 }
 
 func (h Handlers) SubmitWalletTx(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
@@ -39,13 +44,18 @@ func (h Handlers) SubmitWalletTx(ctx context.Context, w http.ResponseWriter, r *
 	// Only the checks that the transaction signature and the reciept account format
 	// Its up to wallet to check the balance and nonce.
 	// Fee will be taken if this transaction is included in a block.
-	if err := h.State.UpsertWalletTx(signedTx); err != nil {
+	tx, err := h.State.UpsertWalletTx(signedTx)
+	if err != nil {
 		return v1.NewRequestError(err, http.StatusBadRequest)
 	}
+
+	// The hash is what the status of the transaction is tracked by.
 	resp := struct {
 		Status string `json:"status"`
+		Hash   string `json:"hash"`
 	}{
 		Status: "transaction added to mempool",
+		Hash:   tx.HashString(),
 	}
 
 	return web.Respond(ctx, w, resp, http.StatusOK)
@@ -58,6 +68,19 @@ func (h Handlers) Genesis(ctx context.Context, w http.ResponseWriter, r *http.Re
 	return web.Respond(ctx, w, gen, http.StatusOK)
 }
 
+// Finalized returns the latest block that reached finality.
+func (h Handlers) Finalized(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	cert := h.State.Finalized()
+
+	resp := finalized{
+		Number:     cert.Number,
+		Hash:       cert.BlockHash,
+		Precommits: len(cert.Precommits),
+	}
+
+	return web.Respond(ctx, w, resp, http.StatusOK)
+}
+
 func (h Handlers) Accounts(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
 	accouStr := web.Param(r, "account")
 
@@ -85,6 +108,8 @@ func (h Handlers) Accounts(ctx context.Context, w http.ResponseWriter, r *http.R
 			AccountID: accountID,
 			Name:      h.NS.Lookup(accountID),
 			Balance:   account.Balance,
+			Stake:     account.Stake,
+			Unbonding: account.Unbonding,
 			Nonce:     account.Nonce,
 		})
 	}
@@ -103,6 +128,106 @@ func (h Handlers) Accounts(ctx context.Context, w http.ResponseWriter, r *http.R
 	return web.Respond(ctx, w, ai, http.StatusOK)
 }
 
+// SimulateTx reports what the transaction would do if it was mined now,
+// without adding it to the mempool. A transaction without a signature is
+// simulated for the sender, which can be given instead of the from account.
+func (h Handlers) SimulateTx(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	var req simulateTx
+	if err := web.Decode(r, &req); err != nil {
+		return v1.NewRequestError(err, http.StatusBadRequest)
+	}
+
+	var res state.SimulateResult
+	var err error
+
+	if req.V != nil {
+		res, err = h.State.SimulateWalletTx(req.SignedTx)
+	} else {
+		if req.FromID == "" {
+			req.FromID = req.Sender
+		}
+		if req.Sender != "" && req.Sender != req.FromID {
+			return v1.NewRequestError(errors.New("sender doesn't match the from account"), http.StatusBadRequest)
+		}
+		res, err = h.State.SimulateTx(req.Tx)
+	}
+
+	if err != nil {
+		return v1.NewRequestError(err, http.StatusBadRequest)
+	}
+
+	resp := simulateResult{
+		Hash:     res.Tx.HashString(),
+		Success:  res.Success,
+		Reason:   res.Reason,
+		GasCost:  res.GasCost,
+		Nonce:    res.Nonce,
+		Pending:  res.Pending,
+		Balances: make([]simBalance, len(res.Balances)),
+	}
+	for i, b := range res.Balances {
+		resp.Balances[i] = simBalance{
+			AccountID: b.AccountID,
+			Name:      h.NS.Lookup(b.AccountID),
+			Before:    b.Before,
+			After:     b.After,
+		}
+	}
+
+	return web.Respond(ctx, w, resp, http.StatusOK)
+}
+
+// EstimateFee returns the tips recommended to get a transaction mined in the
+// next block, within 3 blocks and within 10 blocks.
+func (h Handlers) EstimateFee(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	fe, err := h.State.EstimateTip()
+	if err != nil {
+		return err
+	}
+
+	resp := feeEstimate{
+		NextBlock:     fe.NextBlock,
+		Fast:          fe.Fast,
+		Slow:          fe.Slow,
+		MempoolDepth:  fe.MempoolDepth,
+		TransPerBlock: fe.TransPerBlock,
+		BlocksSampled: fe.BlocksSampled,
+	}
+
+	return web.Respond(ctx, w, resp, http.StatusOK)
+}
+
+// TxStatus returns what happened to the transaction with the specified hash.
+func (h Handlers) TxStatus(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	ts, err := h.State.QueryTxStatus(web.Param(r, "hash"))
+	if err != nil {
+		return v1.NewRequestError(err, http.StatusInternalServerError)
+	}
+
+	return web.Respond(ctx, w, toTxStatus(ts), http.StatusOK)
+}
+
+// TxStatusByNonce returns what happened to the latest transaction sent by the
+// account with the specified nonce.
+func (h Handlers) TxStatusByNonce(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	accountID, err := database.ToAccountID(web.Param(r, "account"))
+	if err != nil {
+		return v1.NewRequestError(err, http.StatusBadRequest)
+	}
+
+	nonce, err := strconv.ParseUint(web.Param(r, "nonce"), 10, 64)
+	if err != nil {
+		return v1.NewRequestError(fmt.Errorf("invalid nonce: %w", err), http.StatusBadRequest)
+	}
+
+	ts, err := h.State.QueryTxStatusByNonce(accountID, nonce)
+	if err != nil {
+		return v1.NewRequestError(err, http.StatusInternalServerError)
+	}
+
+	return web.Respond(ctx, w, toTxStatus(ts), http.StatusOK)
+}
+
 func (h Handlers) Mempool(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
 	acct := web.Param(r, "account")
 
@@ -0,0 +1,265 @@
+package rpc
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/ardanlabs/blockchain/foundation/blockchain/database"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+// errRLPTransaction is returned when a raw transaction is signed the Ethereum
+// way, which the chain can't verify.
+var errRLPTransaction = errors.New("ethereum encoded transactions are not supported, send the hex encoded json of an ardan signed transaction")
+
+// methods returns the functions implementing the supported methods.
+func (h Handlers) methods() map[string]func(json.RawMessage) (any, error) {
+	return map[string]func(json.RawMessage) (any, error){
+		"net_version":              h.netVersion,
+		"net_listening":            h.netListening,
+		"net_peerCount":            h.netPeerCount,
+		"eth_chainId":              h.chainID,
+		"eth_blockNumber":          h.blockNumber,
+		"eth_gasPrice":             h.gasPrice,
+		"eth_getBalance":           h.getBalance,
+		"eth_getTransactionCount":  h.getTransactionCount,
+		"eth_getBlockByNumber":     h.getBlockByNumber,
+		"eth_getBlockByHash":       h.getBlockByHash,
+		"eth_getTransactionByHash": h.getTransactionByHash,
+		"eth_sendRawTransaction":   h.sendRawTransaction,
+	}
+}
+
+// =============================================================================
+
+func (h Handlers) netVersion(raw json.RawMessage) (any, error) {
+	return strconv.Itoa(int(h.State.Genesis().ChainID)), nil
+}
+
+func (h Handlers) netListening(raw json.RawMessage) (any, error) {
+	return true, nil
+}
+
+func (h Handlers) netPeerCount(raw json.RawMessage) (any, error) {
+	return hexutil.Uint64(len(h.State.KnowExternalPeers())), nil
+}
+
+func (h Handlers) chainID(raw json.RawMessage) (any, error) {
+	return hexutil.Uint64(h.State.Genesis().ChainID), nil
+}
+
+func (h Handlers) blockNumber(raw json.RawMessage) (any, error) {
+	return hexutil.Uint64(h.State.LatestBlock().Header.Number), nil
+}
+
+func (h Handlers) gasPrice(raw json.RawMessage) (any, error) {
+	return hexutil.Uint64(h.State.Genesis().GasPrice), nil
+}
+
+// getBalance returns the balance of the account. The node only keeps the
+// state of the latest block.
+func (h Handlers) getBalance(raw json.RawMessage) (any, error) {
+	var address string
+	tag := "latest"
+	if err := params(raw, &address, &tag); err != nil {
+		return nil, err
+	}
+
+	accountID, err := toAccountID(address)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := h.latestOnly(tag); err != nil {
+		return nil, err
+	}
+
+	account, err := h.State.QueryAccount(accountID)
+	if err != nil {
+		return hexutil.Uint64(0), nil
+	}
+
+	return hexutil.Uint64(account.Balance), nil
+}
+
+// getTransactionCount returns the number of transactions sent by the account.
+// The nonces of the chain start at 1, so it's the nonce of the last
+// transaction and the next one must use the count plus one. The pending count
+// includes the transactions in the mempool.
+func (h Handlers) getTransactionCount(raw json.RawMessage) (any, error) {
+	var address string
+	tag := "latest"
+	if err := params(raw, &address, &tag); err != nil {
+		return nil, err
+	}
+
+	accountID, err := toAccountID(address)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := h.latestOnly(tag); err != nil {
+		return nil, err
+	}
+
+	var count uint64
+	if account, err := h.State.QueryAccount(accountID); err == nil {
+		count = account.Nonce
+	}
+
+	if tag == "pending" {
+		for _, tx := range h.State.Mempool() {
+			if tx.FromID == accountID && tx.Nonce > count {
+				count = tx.Nonce
+			}
+		}
+	}
+
+	return hexutil.Uint64(count), nil
+}
+
+// getBlockByNumber returns the block or null when there is no such block. The
+// genesis block isn't stored, so block 0 is always null.
+func (h Handlers) getBlockByNumber(raw json.RawMessage) (any, error) {
+	var tag string
+	var full bool
+	if err := params(raw, &tag, &full); err != nil {
+		return nil, err
+	}
+
+	number, err := h.blockNumberOf(tag)
+	if err != nil {
+		return nil, err
+	}
+
+	if number == 0 || number > h.State.LatestBlock().Header.Number {
+		return nil, nil
+	}
+
+	blocks, err := h.State.QueryBlocksByNumber(number, number)
+	if err != nil {
+		return nil, err
+	}
+
+	return toRPCBlock(blocks[0], h.gasLimit(), full), nil
+}
+
+// getBlockByHash returns the block or null when there is no such block.
+func (h Handlers) getBlockByHash(raw json.RawMessage) (any, error) {
+	var hash string
+	var full bool
+	if err := params(raw, &hash, &full); err != nil {
+		return nil, err
+	}
+
+	block, err := h.State.QueryBlockByHash(strings.ToLower(hash))
+	if err != nil {
+		if errors.Is(err, database.ErrBlockNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return toRPCBlock(block, h.gasLimit(), full), nil
+}
+
+// getTransactionByHash returns the transaction from the chain or the mempool,
+// or null when it's unknown.
+func (h Handlers) getTransactionByHash(raw json.RawMessage) (any, error) {
+	var hash string
+	if err := params(raw, &hash); err != nil {
+		return nil, err
+	}
+
+	rec, err := h.State.QueryTx(strings.ToLower(hash))
+	if err != nil {
+		if errors.Is(err, database.ErrTxNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return toRPCTx(rec), nil
+}
+
+// sendRawTransaction adds the transaction to the mempool and returns its hash.
+// The data must be the JSON of a signed transaction encoded as hex since the
+// signature of an Ethereum encoded transaction can't be verified.
+func (h Handlers) sendRawTransaction(raw json.RawMessage) (any, error) {
+	var data hexutil.Bytes
+	if err := params(raw, &data); err != nil {
+		return nil, err
+	}
+
+	if len(data) == 0 || data[0] != '{' {
+		return nil, &rpcError{Code: codeInvalidParams, Message: errRLPTransaction.Error()}
+	}
+
+	var signedTx database.SignedTx
+	if err := json.Unmarshal(data, &signedTx); err != nil {
+		return nil, &rpcError{Code: codeInvalidParams, Message: fmt.Sprintf("invalid transaction: %s", err)}
+	}
+
+	tx, err := h.State.UpsertWalletTx(signedTx)
+	if err != nil {
+		return nil, &rpcError{Code: codeServer, Message: err.Error()}
+	}
+
+	return tx.HashString(), nil
+}
+
+// =============================================================================
+
+// blockNumberOf resolves a block tag or a hex block number.
+func (h Handlers) blockNumberOf(tag string) (uint64, error) {
+	switch tag {
+	case "latest", "pending", "":
+		return h.State.LatestBlock().Header.Number, nil
+	case "earliest":
+		return 0, nil
+	case "safe", "finalized":
+		return h.State.Finalized().Number, nil
+	}
+
+	number, err := hexutil.DecodeUint64(tag)
+	if err != nil {
+		return 0, &rpcError{Code: codeInvalidParams, Message: fmt.Sprintf("invalid block number %q: %s", tag, err)}
+	}
+
+	return number, nil
+}
+
+// latestOnly returns an error unless the tag resolves to the latest block,
+// since the node doesn't keep the state of older blocks.
+func (h Handlers) latestOnly(tag string) error {
+	number, err := h.blockNumberOf(tag)
+	if err != nil {
+		return err
+	}
+
+	if number != h.State.LatestBlock().Header.Number {
+		return &rpcError{Code: codeServer, Message: "only the state of the latest block is available"}
+	}
+
+	return nil
+}
+
+// gasLimit returns the gas all the transactions of a full block use. Every
+// transaction uses one unit of gas.
+func (h Handlers) gasLimit() uint64 {
+	return uint64(h.State.Genesis().TransPerBlock)
+}
+
+// toAccountID converts the address to the checksummed form the accounts are
+// stored with. Ethereum tooling often sends addresses in lower case.
+func toAccountID(address string) (database.AccountID, error) {
+	if !database.AccountID(address).IsAccountID() {
+		return "", &rpcError{Code: codeInvalidParams, Message: fmt.Sprintf("invalid address %q", address)}
+	}
+
+	return database.AccountID(common.HexToAddress(address).Hex()), nil
+}
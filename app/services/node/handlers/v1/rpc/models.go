@@ -0,0 +1,157 @@
+package rpc
+
+import (
+	"strings"
+
+	"github.com/ardanlabs/blockchain/foundation/blockchain/database"
+	"github.com/ardanlabs/blockchain/foundation/blockchain/signature"
+	"github.com/ardanlabs/blockchain/foundation/blockchain/state"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+// Set of values Ethereum expects for the parts of a block the chain doesn't
+// have. They are the hashes of empty uncle and receipt lists and an empty
+// logs bloom filter.
+const (
+	emptyUncleHash = "0x1dcc4de8dec75d7aab85b567b6ccd41ad312451b948a7413f0a142fd40d49347"
+	emptyRootHash  = "0x56e81f171bcc55a6ff8345e692c0f86e5b48e01b996cadc001622fb5e363b421"
+	zeroHash32     = "0x0000000000000000000000000000000000000000000000000000000000000000"
+)
+
+var emptyBloom = "0x" + strings.Repeat("0", 512)
+
+type rpcBlock struct {
+	Number           hexutil.Uint64 `json:"number"`
+	Hash             string         `json:"hash"`
+	ParentHash       string         `json:"parentHash"`
+	Nonce            string         `json:"nonce"`
+	Sha3Uncles       string         `json:"sha3Uncles"`
+	LogsBloom        string         `json:"logsBloom"`
+	TransactionsRoot string         `json:"transactionsRoot"`
+	StateRoot        string         `json:"stateRoot"`
+	ReceiptsRoot     string         `json:"receiptsRoot"`
+	Miner            string         `json:"miner"`
+	Difficulty       hexutil.Uint64 `json:"difficulty"`
+	ExtraData        hexutil.Bytes  `json:"extraData"`
+	GasLimit         hexutil.Uint64 `json:"gasLimit"`
+	GasUsed          hexutil.Uint64 `json:"gasUsed"`
+	Timestamp        hexutil.Uint64 `json:"timestamp"`
+	Transactions     []any          `json:"transactions"`
+	Uncles           []string       `json:"uncles"`
+}
+
+type rpcTx struct {
+	Hash             string          `json:"hash"`
+	Nonce            hexutil.Uint64  `json:"nonce"`
+	BlockHash        *string         `json:"blockHash"`
+	BlockNumber      *hexutil.Uint64 `json:"blockNumber"`
+	TransactionIndex *hexutil.Uint64 `json:"transactionIndex"`
+	From             string          `json:"from"`
+	To               string          `json:"to"`
+	Value            hexutil.Uint64  `json:"value"`
+	Gas              hexutil.Uint64  `json:"gas"`
+	GasPrice         hexutil.Uint64  `json:"gasPrice"`
+	Input            hexutil.Bytes   `json:"input"`
+	V                *hexutil.Big    `json:"v"`
+	R                *hexutil.Big    `json:"r"`
+	S                *hexutil.Big    `json:"s"`
+	ChainID          hexutil.Uint64  `json:"chainId"`
+	Type             hexutil.Uint64  `json:"type"`
+
+	// Tip has no place in a legacy Ethereum transaction.
+	Tip hexutil.Uint64 `json:"tip"`
+}
+
+// toRPCBlock translates the block. The transactions are listed by hash unless
+// full is set. The chain keeps block times in milliseconds where Ethereum uses
+// seconds.
+func toRPCBlock(block database.Block, gasLimit uint64, full bool) rpcBlock {
+	values := block.MerkleTree.Values()
+	hash := block.Hash()
+
+	var gasUsed uint64
+	txs := make([]any, len(values))
+	for i, tx := range values {
+		gasUsed += tx.GasUnits
+
+		if !full {
+			txs[i] = tx.HashString()
+			continue
+		}
+
+		txs[i] = toRPCTx(state.TxRecord{
+			Tx:          tx,
+			BlockNumber: block.Header.Number,
+			BlockHash:   hash,
+			Index:       i,
+		})
+	}
+
+	return rpcBlock{
+		Number:           hexutil.Uint64(block.Header.Number),
+		Hash:             toHash32(hash),
+		ParentHash:       toHash32(block.Header.PrevBlockHash),
+		Nonce:            hexutil.EncodeUint64(block.Header.Nonce),
+		Sha3Uncles:       emptyUncleHash,
+		LogsBloom:        emptyBloom,
+		TransactionsRoot: toRoot(block.Header.TransRoot),
+		StateRoot:        toRoot(block.Header.StateRoot),
+		ReceiptsRoot:     emptyRootHash,
+		Miner:            string(block.Header.BeneficiaryID),
+		Difficulty:       hexutil.Uint64(block.Header.Difficulty),
+		ExtraData:        hexutil.Bytes{},
+		GasLimit:         hexutil.Uint64(gasLimit),
+		GasUsed:          hexutil.Uint64(gasUsed),
+		Timestamp:        hexutil.Uint64(block.Header.Timestamp / 1000),
+		Transactions:     txs,
+		Uncles:           []string{},
+	}
+}
+
+// toRPCTx translates the transaction. A pending transaction has no block.
+func toRPCTx(rec state.TxRecord) rpcTx {
+	tx := rpcTx{
+		Hash:     rec.Tx.HashString(),
+		Nonce:    hexutil.Uint64(rec.Tx.Nonce),
+		From:     string(rec.Tx.FromID),
+		To:       string(rec.Tx.ToID),
+		Value:    hexutil.Uint64(rec.Tx.Value),
+		Gas:      hexutil.Uint64(rec.Tx.GasUnits),
+		GasPrice: hexutil.Uint64(rec.Tx.GasPrice),
+		Input:    hexutil.Bytes(rec.Tx.Data),
+		V:        (*hexutil.Big)(rec.Tx.V),
+		R:        (*hexutil.Big)(rec.Tx.R),
+		S:        (*hexutil.Big)(rec.Tx.S),
+		ChainID:  hexutil.Uint64(rec.Tx.ChainID),
+		Tip:      hexutil.Uint64(rec.Tx.Tip),
+	}
+
+	if !rec.Pending {
+		hash := toHash32(rec.BlockHash)
+		number := hexutil.Uint64(rec.BlockNumber)
+		index := hexutil.Uint64(rec.Index)
+
+		tx.BlockHash = &hash
+		tx.BlockNumber = &number
+		tx.TransactionIndex = &index
+	}
+
+	return tx
+}
+
+// toHash32 widens the zero hash used by the chain, which has the length of an
+// address, to the length of a hash.
+func toHash32(hash string) string {
+	if hash == signature.ZeroHash {
+		return zeroHash32
+	}
+	return hash
+}
+
+// toRoot returns the root of an empty trie when the block has no root.
+func toRoot(root string) string {
+	if root == "" || root == "0x" {
+		return emptyRootHash
+	}
+	return toHash32(root)
+}
@@ -0,0 +1,161 @@
+// Package rpc maintains the handler for the Ethereum compatible JSON-RPC API.
+package rpc
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/ardanlabs/blockchain/foundation/blockchain/state"
+	"github.com/ardanlabs/blockchain/foundation/web"
+	"go.uber.org/zap"
+)
+
+// CORE NOTE: The JSON-RPC API lets Ethereum tooling read the chain. Blocks,
+// transactions and accounts are translated to the Ethereum shapes with the
+// values in the smallest unit the chain has. The signatures can't be
+// translated since Ardan signs a stamp of the JSON encoded transaction where
+// Ethereum signs the RLP encoding, so raw transactions must be Ardan signed
+// transactions encoded as hex JSON.
+
+// version is the only JSON-RPC version supported.
+const version = "2.0"
+
+// Set of error codes defined by JSON-RPC and used by the Ethereum nodes.
+const (
+	codeParse          = -32700
+	codeInvalidRequest = -32600
+	codeMethodNotFound = -32601
+	codeInvalidParams  = -32602
+	codeInternal       = -32603
+	codeServer         = -32000
+)
+
+// Handlers manages the JSON-RPC endpoint.
+type Handlers struct {
+	Log   *zap.SugaredLogger
+	State *state.State
+}
+
+// RPC executes a JSON-RPC request or a batch of them.
+func (h Handlers) RPC(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	var raw json.RawMessage
+	if err := json.NewDecoder(r.Body).Decode(&raw); err != nil {
+		return web.Respond(ctx, w, errorResponse(nil, codeParse, err.Error()), http.StatusOK)
+	}
+
+	if raw = bytes.TrimSpace(raw); len(raw) > 0 && raw[0] == '[' {
+		var batch []json.RawMessage
+		if err := json.Unmarshal(raw, &batch); err != nil {
+			return web.Respond(ctx, w, errorResponse(nil, codeParse, err.Error()), http.StatusOK)
+		}
+
+		if len(batch) == 0 {
+			return web.Respond(ctx, w, errorResponse(nil, codeInvalidRequest, "empty batch"), http.StatusOK)
+		}
+
+		resps := make([]response, len(batch))
+		for i, req := range batch {
+			resps[i] = h.call(req)
+		}
+
+		return web.Respond(ctx, w, resps, http.StatusOK)
+	}
+
+	return web.Respond(ctx, w, h.call(raw), http.StatusOK)
+}
+
+// =============================================================================
+
+type request struct {
+	Version string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params"`
+}
+
+type response struct {
+	Version string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  any             `json:"result,omitempty"`
+	Error   *rpcError       `json:"error,omitempty"`
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// Error implements the error interface.
+func (e *rpcError) Error() string {
+	return e.Message
+}
+
+func errorResponse(id json.RawMessage, code int, msg string) response {
+	return response{
+		Version: version,
+		ID:      id,
+		Error:   &rpcError{Code: code, Message: msg},
+	}
+}
+
+// call executes a single request.
+func (h Handlers) call(raw json.RawMessage) response {
+	var req request
+	if err := json.Unmarshal(raw, &req); err != nil {
+		return errorResponse(nil, codeInvalidRequest, err.Error())
+	}
+
+	if req.Version != version || req.Method == "" {
+		return errorResponse(req.ID, codeInvalidRequest, "invalid request")
+	}
+
+	fn, exists := h.methods()[req.Method]
+	if !exists {
+		return errorResponse(req.ID, codeMethodNotFound, fmt.Sprintf("the method %s does not exist/is not available", req.Method))
+	}
+
+	result, err := fn(req.Params)
+	if err != nil {
+		if re, ok := err.(*rpcError); ok {
+			return errorResponse(req.ID, re.Code, re.Message)
+		}
+		return errorResponse(req.ID, codeInternal, err.Error())
+	}
+
+	// A result of null must still be sent, which omitempty would drop.
+	if result == nil {
+		result = json.RawMessage("null")
+	}
+
+	return response{
+		Version: version,
+		ID:      req.ID,
+		Result:  result,
+	}
+}
+
+// params decodes the positional parameters into the values. Trailing
+// parameters can be left out by the client.
+func params(raw json.RawMessage, values ...any) error {
+	var list []json.RawMessage
+	if len(raw) > 0 {
+		if err := json.Unmarshal(raw, &list); err != nil {
+			return &rpcError{Code: codeInvalidParams, Message: "params must be an array"}
+		}
+	}
+
+	if len(list) > len(values) {
+		return &rpcError{Code: codeInvalidParams, Message: fmt.Sprintf("too many arguments, want at most %d", len(values))}
+	}
+
+	for i, p := range list {
+		if err := json.Unmarshal(p, values[i]); err != nil {
+			return &rpcError{Code: codeInvalidParams, Message: fmt.Sprintf("invalid argument %d: %s", i, err)}
+		}
+	}
+
+	return nil
+}
@@ -6,12 +6,17 @@ import (
 	"fmt"
 	"net/http"
 
+	"github.com/ardanlabs/blockchain/app/services/node/handlers/v1/admin"
 	"github.com/ardanlabs/blockchain/app/services/node/handlers/v1/private"
 	"github.com/ardanlabs/blockchain/app/services/node/handlers/v1/public"
+	"github.com/ardanlabs/blockchain/app/services/node/handlers/v1/rpc"
+	"github.com/ardanlabs/blockchain/business/web/v1/mid"
 	"github.com/ardanlabs/blockchain/foundation/blockchain/peer"
 	"github.com/ardanlabs/blockchain/foundation/blockchain/state"
+	"github.com/ardanlabs/blockchain/foundation/events"
 	"github.com/ardanlabs/blockchain/foundation/nameservice"
 	"github.com/ardanlabs/blockchain/foundation/web"
+	"github.com/gorilla/websocket"
 	"go.uber.org/zap"
 )
 
@@ -22,49 +27,144 @@ type Config struct {
 	Log   *zap.SugaredLogger
 	State *state.State
 	NS    *nameservice.NameService
+	Evts  *events.Events
+
+	// Auth and RateLimit protect the group of routes being bound.
+	Auth      mid.AuthConfig
+	RateLimit mid.RateLimitConfig
 }
 
 // PublicRoutes binds all the version 1 public routes.
 func PublicRoutes(app *web.App, cfg Config) {
+	mw := group(cfg)
+
 	pbl := public.Handlers{
 		Log:   cfg.Log,
 		State: cfg.State,
 		NS:    cfg.NS,
+		Evts:  cfg.Evts,
+
+		// The wallets connect from their own origin, like the rest of the
+		// public API that allows any origin.
+		WS: websocket.Upgrader{
+			CheckOrigin: func(r *http.Request) bool { return true },
+		},
 	}
 
-	// app.Handle(http.MethodGet, version, "/events", pbl.Events)
-	app.Handle(http.MethodGet, version, "/genesis/list", pbl.Genesis)
+	app.Handle(http.MethodGet, version, "/events", pbl.Events, mw...)
+	app.Handle(http.MethodGet, version, "/genesis/list", pbl.Genesis, mw...)
+
+	app.Handle(http.MethodGet, version, "/accounts/list", pbl.Accounts, mw...)
+	app.Handle(http.MethodGet, version, "/accounts/list/:account", pbl.Accounts, mw...)
 
-	app.Handle(http.MethodGet, version, "/accounts/list", pbl.Accounts)
-	app.Handle(http.MethodGet, version, "/accounts/list/:account", pbl.Accounts)
+	app.Handle(http.MethodGet, version, "/blocks/finalized", pbl.Finalized, mw...)
 
-	// app.Handle(http.MethodGet, version, "/blocks/list/", pbl.BlocksByAccount)
-	// app.Handle(http.MethodGet, version, "/blocks/list/:account", pbl.BlocksByAccount)
+	app.Handle(http.MethodGet, version, "/blocks/list", pbl.Blocks, mw...)
+	app.Handle(http.MethodGet, version, "/blocks/number/:number", pbl.BlockByNumber, mw...)
+	app.Handle(http.MethodGet, version, "/blocks/hash/:hash", pbl.BlockByHash, mw...)
 
-	app.Handle(http.MethodGet, version, "/tx/uncommited/list", pbl.Mempool)
-	app.Handle(http.MethodGet, version, "/tx/uncommited/list/:account", pbl.Mempool)
+	app.Handle(http.MethodGet, version, "/accounts/txs/:account", pbl.AccountTxs, mw...)
+	app.Handle(http.MethodGet, version, "/search/:query", pbl.Search, mw...)
 
-	app.Handle(http.MethodPost, version, "/tx/commit", pbl.SubmitWalletTx)
-	// app.Handle(http.MethodPost, version, "/tx/proof/:block", pbl.SubmitWalletTx)
+	app.Handle(http.MethodGet, version, "/tx/uncommited/list", pbl.Mempool, mw...)
+	app.Handle(http.MethodGet, version, "/tx/uncommited/list/:account", pbl.Mempool, mw...)
+
+	app.Handle(http.MethodGet, version, "/tx/fee", pbl.EstimateFee, mw...)
+	app.Handle(http.MethodGet, version, "/tx/hash/:hash", pbl.TxByHash, mw...)
+	app.Handle(http.MethodGet, version, "/tx/status/hash/:hash", pbl.TxStatus, mw...)
+	app.Handle(http.MethodGet, version, "/tx/status/nonce/:account/:nonce", pbl.TxStatusByNonce, mw...)
+	app.Handle(http.MethodPost, version, "/tx/commit", pbl.SubmitWalletTx, mw...)
+	app.Handle(http.MethodPost, version, "/tx/simulate", pbl.SimulateTx, mw...)
+
+	// Ethereum tooling talks to the node over JSON-RPC.
+	eth := rpc.Handlers{
+		Log:   cfg.Log,
+		State: cfg.State,
+	}
+	app.Handle(http.MethodPost, version, "/rpc", eth.RPC, mw...)
+	// app.Handle(http.MethodPost, version, "/tx/proof/:block", pbl.SubmitWalletTx, mw...)
 
 }
 
 // PrivateRoutes binds all the version 1 private routes.
 func PrivateRoutes(app *web.App, cfg Config) {
+	mw := group(cfg)
+
 	prv := private.Handlers{
 		Log:   cfg.Log,
 		NS:    cfg.NS,
 		State: cfg.State,
 	}
 
-	app.Handle(http.MethodPost, version, "/node/peers", prv.SubmitPeer)
-	app.Handle(http.MethodGet, version, "/node/status", prv.Status)
-	app.Handle(http.MethodGet, version, "/node/tx/list", prv.Mempool)
+	app.Handle(http.MethodPost, version, "/node/peers", prv.SubmitPeer, mw...)
+	app.Handle(http.MethodGet, version, "/node"+peer.HandshakeUri, prv.Handshake, mw...)
+	app.Handle(http.MethodGet, version, "/node/peers/health", prv.PeerHealth, mw...)
+	app.Handle(http.MethodGet, version, "/node/peers/book", prv.AddressBook, mw...)
+	app.Handle(http.MethodGet, version, "/node"+peer.StreamUri, prv.Stream, mw...)
+	app.Handle(http.MethodGet, version, "/node/status", prv.Status, mw...)
+	app.Handle(http.MethodGet, version, "/node/tx/list", prv.Mempool, mw...)
 
-	app.Handle(http.MethodPost, version, "/node/tx/submit", prv.SubmitNodeTransaction)
+	app.Handle(http.MethodPost, version, "/node/tx/submit", prv.SubmitNodeTransaction, mw...)
 
-	app.Handle(http.MethodPost, version, "/node/block/propose", prv.ProposeBlock)
+	app.Handle(http.MethodPost, version, "/node/block/propose", prv.ProposeBlock, mw...)
+	app.Handle(http.MethodPost, version, "/node"+peer.VoteSubmitUri, prv.SubmitVote, mw...)
+
+	app.Handle(http.MethodGet, version, "/node/work/get", prv.GetWork, mw...)
+	app.Handle(http.MethodGet, version, "/node/work/get/:beneficiary", prv.GetWork, mw...)
+	app.Handle(http.MethodPost, version, "/node/work/submit", prv.SubmitWork, mw...)
 
 	blocskUri := fmt.Sprintf(peer.BlocksUri, ":from", ":to")
-	app.Handle(http.MethodGet, version, "/node"+blocskUri, prv.BlocksByNumber)
+	app.Handle(http.MethodGet, version, "/node"+blocskUri, prv.BlocksByNumber, mw...)
+
+	headersUri := fmt.Sprintf(peer.HeadersUri, ":from", ":to")
+	app.Handle(http.MethodGet, version, "/node"+headersUri, prv.HeadersByNumber, mw...)
+
+	txByHashUri := fmt.Sprintf(peer.TxByHashUri, ":hash")
+	app.Handle(http.MethodGet, version, "/node"+txByHashUri, prv.TxByHash, mw...)
+
+	blockByHashUri := fmt.Sprintf(peer.BlockByHashUri, ":hash")
+	app.Handle(http.MethodGet, version, "/node"+blockByHashUri, prv.BlockByHash, mw...)
+
+	app.Handle(http.MethodGet, version, "/node"+peer.SnapshotUri, prv.Snapshot, mw...)
+	snapshotChunkUri := fmt.Sprintf(peer.SnapshotChunkUri, ":number", ":chunk")
+	app.Handle(http.MethodGet, version, "/node"+snapshotChunkUri, prv.SnapshotChunk, mw...)
+}
+
+// AdminRoutes binds all the version 1 admin routes. The routes must only be
+// bound with authentication configured.
+func AdminRoutes(app *web.App, cfg Config) {
+	mw := group(cfg)
+
+	adm := admin.Handlers{
+		Log:   cfg.Log,
+		State: cfg.State,
+	}
+
+	app.Handle(http.MethodGet, version, "/admin/peers", adm.Peers, mw...)
+	app.Handle(http.MethodPost, version, "/admin/peers", adm.AddPeer, mw...)
+	app.Handle(http.MethodDelete, version, "/admin/peers/:host", adm.RemovePeer, mw...)
+	app.Handle(http.MethodPost, version, "/admin/peers/:host/ban", adm.BanPeer, mw...)
+	app.Handle(http.MethodDelete, version, "/admin/peers/:host/ban", adm.UnbanPeer, mw...)
+
+	app.Handle(http.MethodPost, version, "/admin/mining/pause", adm.PauseMining, mw...)
+	app.Handle(http.MethodPost, version, "/admin/mining/resume", adm.ResumeMining, mw...)
+	app.Handle(http.MethodPost, version, "/admin/sync", adm.Sync, mw...)
+
+	app.Handle(http.MethodGet, version, "/admin/mempool", adm.Mempool, mw...)
+	app.Handle(http.MethodDelete, version, "/admin/mempool", adm.FlushMempool, mw...)
+	app.Handle(http.MethodPut, version, "/admin/mempool/strategy", adm.SetStrategy, mw...)
+
+	app.Handle(http.MethodGet, version, "/admin/worker", adm.Worker, mw...)
+}
+
+// group returns the middleware protecting a group of routes. Every IP is
+// limited before authentication so failed attempts are throttled too, then
+// every authenticated key or token is limited whatever IP it comes from.
+func group(cfg Config) []web.Middleware {
+	mw := []web.Middleware{mid.RateLimit(cfg.RateLimit)}
+	if cfg.Auth.Enabled() {
+		mw = append(mw, mid.Authenticate(cfg.Auth), mid.RateLimitClient(cfg.RateLimit))
+	}
+
+	return mw
 }
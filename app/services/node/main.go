@@ -2,21 +2,27 @@ package main
 
 import (
 	"context"
+	"crypto/ecdsa"
 	"errors"
 	"fmt"
 	"net/http"
 	"os"
 	"os/signal"
+	"path/filepath"
 	"syscall"
 	"time"
 
 	"github.com/ardanlabs/blockchain/app/services/node/handlers"
+	"github.com/ardanlabs/blockchain/business/web/metrics"
+	"github.com/ardanlabs/blockchain/business/web/v1/auth"
+	"github.com/ardanlabs/blockchain/business/web/v1/mid"
 	"github.com/ardanlabs/blockchain/foundation/blockchain/database"
 	"github.com/ardanlabs/blockchain/foundation/blockchain/genesis"
 	"github.com/ardanlabs/blockchain/foundation/blockchain/peer"
 	"github.com/ardanlabs/blockchain/foundation/blockchain/state"
 	"github.com/ardanlabs/blockchain/foundation/blockchain/storage/disk"
 	"github.com/ardanlabs/blockchain/foundation/blockchain/worker"
+	"github.com/ardanlabs/blockchain/foundation/events"
 	"github.com/ardanlabs/blockchain/foundation/logger"
 	"github.com/ardanlabs/blockchain/foundation/nameservice"
 	"github.com/ardanlabs/conf/v3"
@@ -63,13 +69,31 @@ func run(log *zap.SugaredLogger) error {
 			DebugHost       string        `conf:"default:0.0.0.0:7080"`
 			PublicHost      string        `conf:"default:0.0.0.0:8080"`
 			PrivateHost     string        `conf:"default:0.0.0.0:9080"`
+			AdminToken      string        `conf:"mask"` // Bearer token of the admin routes, empty disables them unless a JWT secret is set
+			APIKeys         []string      `conf:"mask"` // Keys accepted by the private routes, empty with no JWT secret leaves them open
+			JWTSecret       string        `conf:"mask"` // Secret signing the JWTs accepted by the private and admin routes
+		}
+		RateLimit struct {
+			PublicRate   float64 `conf:"default:20"` // Requests per second of a client, zero disables the limit
+			PublicBurst  int     `conf:"default:40"`
+			PrivateRate  float64 `conf:"default:200"`
+			PrivateBurst int     `conf:"default:400"`
+			AdminRate    float64 `conf:"default:5"`
+			AdminBurst   int     `conf:"default:10"`
 		}
 		State struct {
-			Beneficiary    string   `conf:"default:miner1"`
-			DBPath         string   `conf:"default:zblock/miner1/"`
-			SelectStrategy string   `conf:"default:Tip"`
-			OriginPeers    []string `conf:"default:0.0.0.0:9080"`
-			Consensus      string   `conf:"default:PoW"` // Change to PoA to run proof of authority
+			Beneficiary       string        `conf:"default:miner1"`
+			DBPath            string        `conf:"default:zblock/miner1/"`
+			NodeKey           string        // Node identity key, defaults to node.ecdsa in the DBPath
+			SelectStrategy    string        `conf:"default:Tip"`
+			OriginPeers       []string      `conf:"default:0.0.0.0:9080"`
+			MaxPeers          int           `conf:"default:32"`    // Maximum number of active peers, zero means no limit
+			MiningThreads     int           `conf:"default:0"`     // Zero uses one mining goroutine per CPU
+			HeartbeatInterval time.Duration `conf:"default:0s"`    // Produce empty blocks after this long without a block, zero disables
+			MinBlockInterval  time.Duration `conf:"default:0s"`    // Batch transactions by waiting this long after the latest block
+			SnapshotInterval  uint64        `conf:"default:100"`   // Blocks between state snapshots served to fast syncing nodes
+			FastSync          bool          `conf:"default:false"` // Start an empty node from a peer snapshot (PoW only)
+			PeerToken         string        `conf:"mask"`          // API key or JWT sent to the peers, defaults to the first API key
 		}
 		NameService struct {
 			Folder string `conf:"default:zblock/accounts/"`
@@ -132,6 +156,20 @@ func run(log *zap.SugaredLogger) error {
 	if err != nil {
 		return fmt.Errorf("unable to load private key: %w", err)
 	}
+
+	// The node identity key is separate from the beneficiary key. It's
+	// created the first time the node starts.
+	nodeKeyPath := cfg.State.NodeKey
+	if nodeKeyPath == "" {
+		nodeKeyPath = cfg.State.DBPath + "node.ecdsa"
+	}
+
+	nodeKey, err := loadNodeKey(nodeKeyPath)
+	if err != nil {
+		return fmt.Errorf("unable to load node key: %w", err)
+	}
+	log.Infow("startup", "status", "node identity", "nodeId", peer.NodeID(nodeKey))
+
 	// ======== Peer initialization ===
 	peerSet := peer.NewPeerSet()
 	for _, host := range cfg.State.OriginPeers {
@@ -139,11 +177,26 @@ func run(log *zap.SugaredLogger) error {
 	}
 	peerSet.Add(peer.New(cfg.Web.PrivateHost))
 
-	ev := func(v string, args ...any) {
-		s := fmt.Sprintf(v, args...)
-		log.Infow(s, "traceid", "0000000-0000-0000-0000-000000000000")
+	// The peers discovered in previous runs are kept in the data directory.
+	addrBook, err := peer.LoadAddrBook(cfg.State.DBPath + "peers.json")
+	if err != nil {
+		return fmt.Errorf("unable to load address book: %w", err)
 	}
 
+	// Everything the node does is published on the event bus. The logs and
+	// the metrics are fed from it as are the event streams of the public API.
+	evts := events.New()
+
+	evts.Handle(func(evt events.Event) {
+		switch evt := evt.(type) {
+		case events.Trace:
+			log.Infow(evt.Message)
+		default:
+			log.Infow("event", "type", evt.EventType(), "data", evt)
+			metrics.AddEvent(evt.EventType())
+		}
+	})
+
 	// Construct the disk storage
 	storage, err := disk.New(cfg.State.DBPath)
 	if err != nil {
@@ -157,15 +210,33 @@ func run(log *zap.SugaredLogger) error {
 	}
 
 	// Create the blockchain state.
+	// The node authenticates with its peers using its own key unless told
+	// otherwise, since the nodes of a network usually share their keys.
+	peerToken := cfg.State.PeerToken
+	if peerToken == "" && len(cfg.Web.APIKeys) > 0 {
+		peerToken = cfg.Web.APIKeys[0]
+	}
+
 	state, err := state.New(state.Config{
 		Beneficiary:    database.PublicKeyToAccountID(privateKey.PublicKey),
+		BeneficiaryKey: privateKey,
+		NodeKey:        nodeKey,
 		Host:           cfg.Web.PrivateHost,
 		Genesis:        genesis,
 		Storage:        storage,
 		SelectStrategy: cfg.State.SelectStrategy,
 		KnownPeers:     peerSet,
-		Consensus:      cfg.State.Consensus,
-	}, ev)
+		AddrBook:       addrBook,
+		Events:         evts,
+		MaxPeers:       cfg.State.MaxPeers,
+		MiningThreads:  cfg.State.MiningThreads,
+
+		HeartbeatInterval: cfg.State.HeartbeatInterval,
+		MinBlockInterval:  cfg.State.MinBlockInterval,
+		SnapshotInterval:  cfg.State.SnapshotInterval,
+		FastSync:          cfg.State.FastSync,
+		PeerToken:         peerToken,
+	})
 
 	if err != nil {
 		return fmt.Errorf("unable to create state: %w", err)
@@ -173,11 +244,21 @@ func run(log *zap.SugaredLogger) error {
 
 	defer state.Shutdown()
 
+	// Expose the miner hashrate and attempts with the rest of the metrics.
+	metrics.PublishMining(func() any {
+		return state.MiningStats()
+	})
+
+	// Expose the per peer outcome of the block broadcasts.
+	metrics.PublishBroadcast(func() any {
+		return state.BroadcastStats()
+	})
+
 	// The worker package implements the different workflows such as mining and
 	// transaction peer sharing and peer updates. The worker will register itself
 	// with the state.
 
-	worker.Run(state, ev)
+	worker.Run(state)
 
 	// =========================================================================
 	// Start Debug Service
@@ -221,6 +302,12 @@ func run(log *zap.SugaredLogger) error {
 		Log:      log,
 		NS:       ns,
 		State:    state,
+		Evts:     evts,
+
+		PublicLimit: mid.RateLimitConfig{
+			Rate:  cfg.RateLimit.PublicRate,
+			Burst: cfg.RateLimit.PublicBurst,
+		},
 	})
 
 	// Construct a server to service the requests against the mux.
@@ -244,12 +331,44 @@ func run(log *zap.SugaredLogger) error {
 
 	log.Infow("startup", "status", "initializing V1 private API support")
 
+	privateAuth := mid.AuthConfig{
+		Keys:      cfg.Web.APIKeys,
+		JWTSecret: cfg.Web.JWTSecret,
+		Role:      auth.RoleNode,
+	}
+
+	adminAuth := mid.AuthConfig{
+		JWTSecret: cfg.Web.JWTSecret,
+		Role:      auth.RoleAdmin,
+	}
+	if cfg.Web.AdminToken != "" {
+		adminAuth.Keys = []string{cfg.Web.AdminToken}
+	}
+
+	if !privateAuth.Enabled() {
+		log.Infow("startup", "status", "WARNING: private API open, no API keys or JWT secret configured")
+	}
+	if !adminAuth.Enabled() {
+		log.Infow("startup", "status", "admin API disabled, no admin token or JWT secret configured")
+	}
+
 	// Construct the mux for the private API calls.
 	privateMux := handlers.PrivateMux(handlers.MuxConfig{
 		Shutdown: shutdown,
 		Log:      log,
 		NS:       ns,
 		State:    state,
+
+		PrivateAuth: privateAuth,
+		PrivateLimit: mid.RateLimitConfig{
+			Rate:  cfg.RateLimit.PrivateRate,
+			Burst: cfg.RateLimit.PrivateBurst,
+		},
+		AdminAuth: adminAuth,
+		AdminLimit: mid.RateLimitConfig{
+			Rate:  cfg.RateLimit.AdminRate,
+			Burst: cfg.RateLimit.AdminBurst,
+		},
 	})
 
 	// Construct a server to service the requests against the mux.
@@ -280,6 +399,10 @@ func run(log *zap.SugaredLogger) error {
 		log.Infow("shutdown", "status", "shutdown started", "signal", sig)
 		defer log.Infow("shutdown", "status", "shutdown complete", "signal", sig)
 
+		// The event streams are taken over from the servers and would
+		// otherwise stay open.
+		evts.Shutdown()
+
 		// Give outstanding requests a deadline for completion.
 		ctx, cancelPub := context.WithTimeout(context.Background(), cfg.Web.ShutdownTimeout)
 		defer cancelPub()
@@ -305,3 +428,31 @@ func run(log *zap.SugaredLogger) error {
 
 	return nil
 }
+
+// loadNodeKey loads the node identity key, generating it when the file
+// doesn't exist yet.
+func loadNodeKey(path string) (*ecdsa.PrivateKey, error) {
+	key, err := crypto.LoadECDSA(path)
+	if err == nil {
+		return key, nil
+	}
+
+	if !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+
+	key, err = crypto.GenerateKey()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, err
+	}
+
+	if err := crypto.SaveECDSA(path, key); err != nil {
+		return nil, err
+	}
+
+	return key, nil
+}
@@ -0,0 +1,127 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	adminURL      string
+	adminToken    string
+	adminDuration string
+	adminReason   string
+)
+
+var adminCmd = &cobra.Command{
+	Use:   "admin",
+	Short: "Manage a running node through its admin API",
+}
+
+func init() {
+	rootCmd.AddCommand(adminCmd)
+	adminCmd.PersistentFlags().StringVarP(&adminURL, "url", "u", "http://localhost:9080", "URL of the private API of the node")
+	adminCmd.PersistentFlags().StringVarP(&adminToken, "token", "k", os.Getenv("NODE_WEB_ADMIN_TOKEN"), "Admin token or JWT with the admin role")
+
+	banCmd := adminCommand("ban HOST", "Ban a peer", 1, func(args []string) (string, string, any) {
+		return http.MethodPost, "/peers/" + args[0] + "/ban", map[string]string{"duration": adminDuration, "reason": adminReason}
+	})
+	banCmd.Flags().StringVarP(&adminDuration, "duration", "d", "", "How long the ban lasts, empty bans for good")
+	banCmd.Flags().StringVarP(&adminReason, "reason", "r", "", "Why the peer is banned")
+
+	adminCmd.AddCommand(
+		adminCommand("peers", "Show the peers and their health", 0, func(args []string) (string, string, any) {
+			return http.MethodGet, "/peers", nil
+		}),
+		adminCommand("peer-add HOST", "Add a peer", 1, func(args []string) (string, string, any) {
+			return http.MethodPost, "/peers", map[string]string{"host": args[0]}
+		}),
+		adminCommand("peer-remove HOST", "Remove a peer", 1, func(args []string) (string, string, any) {
+			return http.MethodDelete, "/peers/" + args[0], nil
+		}),
+		banCmd,
+		adminCommand("unban HOST", "Lift the ban of a peer", 1, func(args []string) (string, string, any) {
+			return http.MethodDelete, "/peers/" + args[0] + "/ban", nil
+		}),
+		adminCommand("pause", "Pause mining", 0, func(args []string) (string, string, any) {
+			return http.MethodPost, "/mining/pause", nil
+		}),
+		adminCommand("resume", "Resume mining", 0, func(args []string) (string, string, any) {
+			return http.MethodPost, "/mining/resume", nil
+		}),
+		adminCommand("sync", "Sync the node with the network", 0, func(args []string) (string, string, any) {
+			return http.MethodPost, "/sync", nil
+		}),
+		adminCommand("mempool", "Show the mempool", 0, func(args []string) (string, string, any) {
+			return http.MethodGet, "/mempool", nil
+		}),
+		adminCommand("flush", "Remove every transaction from the mempool", 0, func(args []string) (string, string, any) {
+			return http.MethodDelete, "/mempool", nil
+		}),
+		adminCommand("strategy NAME", "Change the strategy selecting the transactions of a block", 1, func(args []string) (string, string, any) {
+			return http.MethodPut, "/mempool/strategy", map[string]string{"strategy": args[0]}
+		}),
+		adminCommand("worker", "Show what the worker goroutines are doing", 0, func(args []string) (string, string, any) {
+			return http.MethodGet, "/worker", nil
+		}),
+	)
+}
+
+// adminCommand constructs a command that sends the request built from the
+// arguments to the admin API and prints the response.
+func adminCommand(use string, short string, nargs int, request func(args []string) (method string, path string, body any)) *cobra.Command {
+	return &cobra.Command{
+		Use:   use,
+		Short: short,
+		Args:  cobra.ExactArgs(nargs),
+		Run: func(cmd *cobra.Command, args []string) {
+			method, path, body := request(args)
+			if err := adminSend(method, path, body); err != nil {
+				log.Fatal(err)
+			}
+		},
+	}
+}
+
+func adminSend(method string, path string, body any) error {
+	var data io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		data = bytes.NewReader(b)
+	}
+
+	req, err := http.NewRequest(method, adminURL+"/v1/admin"+path, data)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+adminToken)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	out, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	fmt.Println(string(out))
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		return fmt.Errorf("admin: status %s", resp.Status)
+	}
+
+	return nil
+}
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"crypto/ecdsa"
 	"encoding/json"
+	"fmt"
 	"log"
 	"net/http"
 
@@ -20,6 +21,8 @@ var (
 	value uint64
 	tip   uint64
 	data  []byte
+
+	autoTip uint64
 )
 
 var sendCmd = &cobra.Command{
@@ -37,6 +40,8 @@ func init() {
 	sendCmd.Flags().Uint64VarP(&value, "value", "v", 0, "Value of the transaction")
 	sendCmd.Flags().Uint64VarP(&tip, "tip", "c", 0, "Tip of the transaction")
 	sendCmd.Flags().BytesHexVarP(&data, "data", "d", nil, "Data of the transaction")
+	sendCmd.Flags().Uint64VarP(&autoTip, "auto-tip", "e", 0, "Use the tip the node recommends to be mined within 1, 3 or 10 blocks")
+	sendCmd.Flags().Lookup("auto-tip").NoOptDefVal = "3"
 }
 
 func sendRun(cmd *cobra.Command, args []string) {
@@ -60,6 +65,14 @@ func sendWithDetails(privateKey *ecdsa.PrivateKey) {
 	}
 	const chainId = 1
 
+	if autoTip != 0 {
+		tip, err = recommendedTip(url, autoTip)
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Printf("using tip %d to be mined within %d blocks\n", tip, autoTip)
+	}
+
 	tx, err := database.NewTx(chainId, nonce, fromAccount, toAccount, value, tip, data)
 	if err != nil {
 		log.Fatal(err)
@@ -82,3 +95,37 @@ func sendWithDetails(privateKey *ecdsa.PrivateKey) {
 	defer resp.Body.Close()
 
 }
+
+// recommendedTip asks the node for the tip to get the transaction mined
+// within the number of blocks.
+func recommendedTip(url string, blocks uint64) (uint64, error) {
+	resp, err := http.Get(url + "/v1/tx/fee")
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("fee estimate: status %s", resp.Status)
+	}
+
+	var fe struct {
+		NextBlock uint64 `json:"next_block"`
+		Fast      uint64 `json:"within_3_blocks"`
+		Slow      uint64 `json:"within_10_blocks"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&fe); err != nil {
+		return 0, err
+	}
+
+	switch blocks {
+	case 1:
+		return fe.NextBlock, nil
+	case 3:
+		return fe.Fast, nil
+	case 10:
+		return fe.Slow, nil
+	}
+
+	return 0, fmt.Errorf("auto tip must be 1, 3 or 10 blocks, got %d", blocks)
+}
@@ -0,0 +1,76 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"github.com/ardanlabs/blockchain/foundation/blockchain/database"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/spf13/cobra"
+)
+
+var (
+	stakeURL     string
+	stakeNonce   uint64
+	stakeValue   uint64
+	stakeTip     uint64
+	stakeUnstake bool
+)
+
+var stakeCmd = &cobra.Command{
+	Use:   "stake",
+	Short: "Lock or release stake for PoS",
+	Run:   stakeRun,
+}
+
+func init() {
+	rootCmd.AddCommand(stakeCmd)
+	stakeCmd.Flags().StringVarP(&stakeURL, "url", "u", "http://localhost:8080", "URL of the blockchain node")
+	stakeCmd.Flags().Uint64VarP(&stakeNonce, "nonce", "n", 0, "Nonce of the transaction")
+	stakeCmd.Flags().Uint64VarP(&stakeValue, "value", "v", 0, "Amount to stake or unstake")
+	stakeCmd.Flags().Uint64VarP(&stakeTip, "tip", "c", 0, "Tip of the transaction")
+	stakeCmd.Flags().BoolVarP(&stakeUnstake, "unstake", "r", false, "Release stake, returned to the balance once it unbonds")
+}
+
+func stakeRun(cmd *cobra.Command, args []string) {
+	privateKey, err := crypto.LoadECDSA(getPrivateKeyPath())
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	op := database.StakeOpStake
+	if stakeUnstake {
+		op = database.StakeOpUnstake
+	}
+
+	data, err := database.StakeData{Op: op}.Encode()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	const chainId = 1
+	fromAccount := database.PublicKeyToAccountID(privateKey.PublicKey)
+
+	tx, err := database.NewTx(chainId, stakeNonce, fromAccount, database.StakingAccountID, stakeValue, stakeTip, data)
+	if err != nil {
+		log.Fatal(err)
+	}
+	signedTx, err := tx.Sign(privateKey)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	body, err := json.Marshal(signedTx)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	resp, err := http.Post(stakeURL+"/v1/tx/commit", "application/json", bytes.NewBuffer(body))
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	defer resp.Body.Close()
+}
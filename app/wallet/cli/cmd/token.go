@@ -0,0 +1,53 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/ardanlabs/blockchain/business/web/v1/auth"
+	"github.com/spf13/cobra"
+)
+
+var (
+	tokenSecret  string
+	tokenSubject string
+	tokenRoles   []string
+	tokenTTL     time.Duration
+)
+
+var tokenCmd = &cobra.Command{
+	Use:   "token",
+	Short: "Generate a JWT accepted by the private and admin APIs of the nodes",
+	Run:   tokenRun,
+}
+
+func init() {
+	rootCmd.AddCommand(tokenCmd)
+	tokenCmd.Flags().StringVarP(&tokenSecret, "secret", "s", os.Getenv("NODE_WEB_JWT_SECRET"), "Secret the nodes verify the token with")
+	tokenCmd.Flags().StringVarP(&tokenSubject, "subject", "n", "operator", "Who the token is issued to")
+	tokenCmd.Flags().StringSliceVarP(&tokenRoles, "role", "r", []string{auth.RoleAdmin}, "Roles of the token: admin, node")
+	tokenCmd.Flags().DurationVarP(&tokenTTL, "ttl", "d", 24*time.Hour, "How long the token is valid")
+}
+
+func tokenRun(cmd *cobra.Command, args []string) {
+	if tokenTTL <= 0 {
+		log.Fatal("ttl must be positive")
+	}
+
+	now := time.Now()
+	claims := auth.Claims{
+		Subject:   tokenSubject,
+		Roles:     tokenRoles,
+		IssuedAt:  now.Unix(),
+		ExpiresAt: now.Add(tokenTTL).Unix(),
+	}
+
+	token, err := auth.GenerateToken(tokenSecret, claims)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	fmt.Println(token)
+}
@@ -22,6 +22,7 @@ type metrics struct {
 	requests   *expvar.Int
 	errors     *expvar.Int
 	panics     *expvar.Int
+	events     *expvar.Map
 }
 
 // init constructs the metrics value that will be used to capture metrics.
@@ -34,6 +35,7 @@ func init() {
 		requests:   expvar.NewInt("requests"),
 		errors:     expvar.NewInt("errors"),
 		panics:     expvar.NewInt("panics"),
+		events:     expvar.NewMap("events"),
 	}
 }
 
@@ -87,3 +89,20 @@ func AddPanics(ctx context.Context) {
 		v.panics.Add(1)
 	}
 }
+
+// AddEvent increments the count of the events of the specified type.
+func AddEvent(eventType string) {
+	m.events.Add(eventType, 1)
+}
+
+// PublishMining registers a function that reports the mining statistics. The
+// function is called each time the metrics are read.
+func PublishMining(f func() any) {
+	expvar.Publish("mining", expvar.Func(f))
+}
+
+// PublishBroadcast registers a function that reports the outcome of the block
+// broadcasts. The function is called each time the metrics are read.
+func PublishBroadcast(f func() any) {
+	expvar.Publish("broadcast", expvar.Func(f))
+}
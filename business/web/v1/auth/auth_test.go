@@ -0,0 +1,108 @@
+package auth_test
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ardanlabs/blockchain/business/web/v1/auth"
+)
+
+const secret = "test-secret"
+
+func TestValidateToken(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+
+	valid := auth.Claims{
+		Subject:   "node1",
+		Roles:     []string{auth.RoleNode},
+		IssuedAt:  now.Unix(),
+		ExpiresAt: now.Add(time.Hour).Unix(),
+	}
+
+	tt := []struct {
+		name  string
+		token string
+		err   string
+	}{
+		{name: "valid", token: generate(t, secret, valid)},
+		{name: "expired", token: generate(t, secret, withExpiry(valid, now.Add(-time.Second))), err: "token expired"},
+		{name: "expires now", token: generate(t, secret, withExpiry(valid, now)), err: "token expired"},
+		{name: "missing exp", token: generate(t, secret, withExpiry(valid, time.Time{})), err: "token expired"},
+		{name: "other secret", token: generate(t, "other-secret", valid), err: "invalid signature"},
+		{name: "alg none", token: sign(t, secret, `{"alg":"none","typ":"JWT"}`, valid), err: "unsupported algorithm"},
+		{name: "alg HS512", token: sign(t, secret, `{"alg":"HS512","typ":"JWT"}`, valid), err: "unsupported algorithm"},
+		{name: "alg missing", token: sign(t, secret, `{"typ":"JWT"}`, valid), err: "unsupported algorithm"},
+		{name: "unsigned", token: strings.Join(strings.Split(generate(t, secret, valid), ".")[:2], ".") + ".", err: "invalid signature"},
+		{name: "malformed", token: "not-a-token", err: "malformed token"},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			claims, err := auth.ValidateToken(secret, tc.token, now)
+
+			if tc.err == "" {
+				if err != nil {
+					t.Fatalf("expected the token to be valid: %s", err)
+				}
+				if claims.Subject != valid.Subject || !claims.HasRole(auth.RoleNode) {
+					t.Fatalf("unexpected claims %+v", claims)
+				}
+				return
+			}
+
+			if err == nil || !strings.Contains(err.Error(), tc.err) {
+				t.Fatalf("expected error %q, got %v", tc.err, err)
+			}
+		})
+	}
+}
+
+func TestGenerateTokenRequiresSecret(t *testing.T) {
+	if _, err := auth.GenerateToken("", auth.Claims{ExpiresAt: 1}); err == nil {
+		t.Fatal("expected a token without a secret to be refused")
+	}
+}
+
+// =============================================================================
+
+func generate(t *testing.T, secret string, claims auth.Claims) string {
+	t.Helper()
+
+	token, err := auth.GenerateToken(secret, claims)
+	if err != nil {
+		t.Fatalf("generating token: %s", err)
+	}
+	return token
+}
+
+// sign builds a token with the header, signed with HMAC SHA-256 whatever
+// algorithm the header names.
+func sign(t *testing.T, secret string, header string, claims auth.Claims) string {
+	t.Helper()
+
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		t.Fatalf("encoding claims: %s", err)
+	}
+
+	enc := base64.RawURLEncoding
+	unsigned := enc.EncodeToString([]byte(header)) + "." + enc.EncodeToString(payload)
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(unsigned))
+
+	return unsigned + "." + enc.EncodeToString(mac.Sum(nil))
+}
+
+func withExpiry(claims auth.Claims, exp time.Time) auth.Claims {
+	claims.ExpiresAt = 0
+	if !exp.IsZero() {
+		claims.ExpiresAt = exp.Unix()
+	}
+	return claims
+}
@@ -0,0 +1,99 @@
+package mid
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	v1 "github.com/ardanlabs/blockchain/business/web/v1"
+	"github.com/ardanlabs/blockchain/business/web/v1/auth"
+	"github.com/ardanlabs/blockchain/foundation/web"
+)
+
+// ctxKey represents the type of value for the context key.
+type ctxKey int
+
+// clientKey is how the authenticated client is stored in the context.
+const clientKey ctxKey = 1
+
+// GetClient returns the client identified by Authenticate.
+func GetClient(ctx context.Context) (string, bool) {
+	client, ok := ctx.Value(clientKey).(string)
+	return client, ok
+}
+
+// AuthConfig represents the credentials accepted for a group of routes. A
+// request carries an API key or a JWT signed with the secret as a bearer
+// token. When a role is set, the JWT must include it.
+type AuthConfig struct {
+	Keys      []string
+	JWTSecret string
+	Role      string
+}
+
+// Enabled reports if any credentials are configured.
+func (cfg AuthConfig) Enabled() bool {
+	return len(cfg.Keys) > 0 || cfg.JWTSecret != ""
+}
+
+// Authenticate checks the request carries one of the accepted credentials and
+// stores the client it identifies in the context. A key is identified by a
+// digest so it never shows in the logs, a JWT by its subject.
+func Authenticate(cfg AuthConfig) web.Middleware {
+
+	// This is the actual middleware function to be executed.
+	m := func(handler web.Handler) web.Handler {
+
+		// Create the handler that will be attached in the middleware chain.
+		h := func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+			authz := r.Header.Get("Authorization")
+			bearer := strings.TrimPrefix(authz, "Bearer ")
+			if bearer == "" || bearer == authz {
+				return v1.NewRequestError(errors.New("authentication required"), http.StatusUnauthorized)
+			}
+
+			client, err := cfg.identify(bearer)
+			if err != nil {
+				return v1.NewRequestError(err, http.StatusUnauthorized)
+			}
+
+			ctx = context.WithValue(ctx, clientKey, client)
+
+			// Call the next handler.
+			return handler(ctx, w, r)
+		}
+
+		return h
+	}
+
+	return m
+}
+
+// identify returns the client the token belongs to.
+func (cfg AuthConfig) identify(token string) (string, error) {
+	for _, key := range cfg.Keys {
+		if subtle.ConstantTimeCompare([]byte(token), []byte(key)) == 1 {
+			return fmt.Sprintf("key:%x", sha256.Sum256([]byte(key)))[:20], nil
+		}
+	}
+
+	if cfg.JWTSecret == "" {
+		return "", errors.New("invalid api key")
+	}
+
+	claims, err := auth.ValidateToken(cfg.JWTSecret, token, time.Now())
+	if err != nil {
+		return "", err
+	}
+
+	if cfg.Role != "" && !claims.HasRole(cfg.Role) {
+		return "", fmt.Errorf("token is missing the %s role", cfg.Role)
+	}
+
+	return "jwt:" + claims.Subject, nil
+}
@@ -0,0 +1,103 @@
+package mid_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	v1 "github.com/ardanlabs/blockchain/business/web/v1"
+	"github.com/ardanlabs/blockchain/business/web/v1/auth"
+	"github.com/ardanlabs/blockchain/business/web/v1/mid"
+)
+
+func TestAuthenticate(t *testing.T) {
+	const secret = "test-secret"
+
+	token := func(roles ...string) string {
+		tkn, err := auth.GenerateToken(secret, auth.Claims{
+			Subject:   "node1",
+			Roles:     roles,
+			ExpiresAt: time.Now().Add(time.Hour).Unix(),
+		})
+		if err != nil {
+			t.Fatalf("generating token: %s", err)
+		}
+		return tkn
+	}
+
+	keys := mid.AuthConfig{Keys: []string{"key1", "key2"}}
+	jwt := mid.AuthConfig{JWTSecret: secret, Role: auth.RoleNode}
+	both := mid.AuthConfig{Keys: []string{"key1"}, JWTSecret: secret}
+
+	tt := []struct {
+		name   string
+		cfg    mid.AuthConfig
+		authz  string
+		client string
+	}{
+		{name: "known key", cfg: keys, authz: "Bearer key2", client: "key:"},
+		{name: "unknown key", cfg: keys, authz: "Bearer key3"},
+		{name: "key prefix", cfg: keys, authz: "Bearer key"},
+		{name: "unknown key with jwt", cfg: both, authz: "Bearer key2"},
+		{name: "no credentials", cfg: keys},
+		{name: "not a bearer", cfg: keys, authz: "key1"},
+		{name: "empty bearer", cfg: keys, authz: "Bearer "},
+		{name: "jwt", cfg: jwt, authz: "Bearer " + token(auth.RoleNode), client: "jwt:node1"},
+		{name: "jwt missing role", cfg: jwt, authz: "Bearer " + token(auth.RoleAdmin)},
+		{name: "jwt without a secret", cfg: keys, authz: "Bearer " + token(auth.RoleNode)},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			var client string
+			handler := mid.Authenticate(tc.cfg)(func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+				client, _ = mid.GetClient(ctx)
+				return nil
+			})
+
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tc.authz != "" {
+				r.Header.Set("Authorization", tc.authz)
+			}
+
+			err := handler(context.Background(), httptest.NewRecorder(), r)
+
+			if tc.client != "" {
+				if err != nil {
+					t.Fatalf("expected the request to be authenticated: %s", err)
+				}
+				if !strings.HasPrefix(client, tc.client) {
+					t.Fatalf("expected client %s, got %s", tc.client, client)
+				}
+				return
+			}
+
+			re := v1.GetRequestError(err)
+			if re == nil || re.Status != http.StatusUnauthorized {
+				t.Fatalf("expected a 401, got %v", err)
+			}
+		})
+	}
+}
+
+func TestAuthenticateHidesKeys(t *testing.T) {
+	var client string
+	handler := mid.Authenticate(mid.AuthConfig{Keys: []string{"secret-key"}})(func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		client, _ = mid.GetClient(ctx)
+		return nil
+	})
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("Authorization", "Bearer secret-key")
+
+	if err := handler(context.Background(), httptest.NewRecorder(), r); err != nil {
+		t.Fatalf("authenticating: %s", err)
+	}
+
+	if strings.Contains(client, "secret-key") {
+		t.Fatalf("expected the key not to show in the client, got %s", client)
+	}
+}
@@ -0,0 +1,160 @@
+package mid
+
+import (
+	"context"
+	"errors"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	v1 "github.com/ardanlabs/blockchain/business/web/v1"
+	"github.com/ardanlabs/blockchain/foundation/web"
+)
+
+// pruneInterval is how often the buckets of idle clients are forgotten.
+const pruneInterval = time.Minute
+
+// RateLimitConfig represents the limit of a group of routes. Every client
+// gets a bucket of Burst requests refilled at Rate requests per second. A
+// zero rate disables the limit.
+type RateLimitConfig struct {
+	Rate  float64
+	Burst int
+}
+
+// RateLimit limits the requests of every client IP. It must run before
+// Authenticate so the requests failing authentication are limited too. A
+// client over the limit gets a 429 telling it when to retry.
+func RateLimit(cfg RateLimitConfig) web.Middleware {
+	return rateLimit(cfg, func(ctx context.Context, r *http.Request) (string, bool) {
+		return clientIP(r), true
+	})
+}
+
+// RateLimitClient limits the requests of every key or token identified by
+// Authenticate, whatever IP they come from. It must run after Authenticate.
+func RateLimitClient(cfg RateLimitConfig) web.Middleware {
+	return rateLimit(cfg, func(ctx context.Context, r *http.Request) (string, bool) {
+		return GetClient(ctx)
+	})
+}
+
+// rateLimit limits the requests of every client returned by the function.
+// Requests without a client are not limited.
+func rateLimit(cfg RateLimitConfig, client func(ctx context.Context, r *http.Request) (string, bool)) web.Middleware {
+	if cfg.Rate <= 0 {
+		return nil
+	}
+	if cfg.Burst < 1 {
+		cfg.Burst = 1
+	}
+
+	clients := newBuckets(cfg)
+
+	// This is the actual middleware function to be executed.
+	m := func(handler web.Handler) web.Handler {
+
+		// Create the handler that will be attached in the middleware chain.
+		h := func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+			c, ok := client(ctx, r)
+			if !ok {
+				return handler(ctx, w, r)
+			}
+
+			if wait := clients.take(c, time.Now()); wait > 0 {
+				w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+				return v1.NewRequestError(errors.New("rate limit exceeded"), http.StatusTooManyRequests)
+			}
+
+			// Call the next handler.
+			return handler(ctx, w, r)
+		}
+
+		return h
+	}
+
+	return m
+}
+
+// =============================================================================
+
+// bucket holds the requests a client can still make.
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// buckets is a token bucket per client.
+type buckets struct {
+	cfg RateLimitConfig
+
+	mu        sync.Mutex
+	clients   map[string]*bucket
+	lastPrune time.Time
+}
+
+func newBuckets(cfg RateLimitConfig) *buckets {
+	return &buckets{
+		cfg:     cfg,
+		clients: make(map[string]*bucket),
+	}
+}
+
+// take uses a request from the bucket of the client. It returns how long the
+// client must wait when the bucket is empty and zero otherwise.
+func (b *buckets) take(client string, now time.Time) time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.prune(now)
+
+	bk, exists := b.clients[client]
+	if !exists {
+		bk = &bucket{tokens: float64(b.cfg.Burst), last: now}
+		b.clients[client] = bk
+	}
+
+	bk.tokens = math.Min(float64(b.cfg.Burst), bk.tokens+now.Sub(bk.last).Seconds()*b.cfg.Rate)
+	bk.last = now
+
+	if bk.tokens < 1 {
+		wait := time.Duration((1 - bk.tokens) / b.cfg.Rate * float64(time.Second))
+		if wait <= 0 {
+			wait = time.Nanosecond
+		}
+		return wait
+	}
+
+	bk.tokens--
+
+	return 0
+}
+
+// prune forgets the clients idle long enough for their bucket to be full
+// again, since a new bucket starts full anyway.
+func (b *buckets) prune(now time.Time) {
+	if now.Sub(b.lastPrune) < pruneInterval {
+		return
+	}
+	b.lastPrune = now
+
+	full := time.Duration(float64(b.cfg.Burst) / b.cfg.Rate * float64(time.Second))
+	for client, bk := range b.clients {
+		if now.Sub(bk.last) >= full {
+			delete(b.clients, client)
+		}
+	}
+}
+
+// clientIP returns the address the request came from. Forwarding headers are
+// ignored since any client can set them.
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
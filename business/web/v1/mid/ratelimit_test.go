@@ -0,0 +1,81 @@
+package mid
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBucketRefill(t *testing.T) {
+	start := time.Unix(1_700_000_000, 0)
+
+	tt := []struct {
+		name    string
+		elapsed time.Duration
+		allowed int
+	}{
+		{name: "no time", elapsed: 0, allowed: 0},
+		{name: "less than a token", elapsed: 400 * time.Millisecond, allowed: 0},
+		{name: "one token", elapsed: 500 * time.Millisecond, allowed: 1},
+		{name: "two tokens", elapsed: time.Second, allowed: 2},
+		{name: "capped to the burst", elapsed: time.Minute, allowed: 3},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			b := newBuckets(RateLimitConfig{Rate: 2, Burst: 3})
+
+			// The bucket starts full.
+			for i := 0; i < 3; i++ {
+				if wait := b.take("client", start); wait != 0 {
+					t.Fatalf("request %d: expected the full burst to be allowed, waited %v", i, wait)
+				}
+			}
+
+			now := start.Add(tc.elapsed)
+
+			var allowed int
+			for b.take("client", now) == 0 {
+				allowed++
+			}
+
+			if allowed != tc.allowed {
+				t.Fatalf("expected %d requests after %v, got %d", tc.allowed, tc.elapsed, allowed)
+			}
+		})
+	}
+}
+
+func TestBucketWait(t *testing.T) {
+	start := time.Unix(1_700_000_000, 0)
+	b := newBuckets(RateLimitConfig{Rate: 2, Burst: 1})
+
+	if wait := b.take("client", start); wait != 0 {
+		t.Fatalf("expected the first request to be allowed, waited %v", wait)
+	}
+
+	if wait := b.take("client", start); wait != 500*time.Millisecond {
+		t.Fatalf("expected to wait for one token, got %v", wait)
+	}
+
+	// Every client has its own bucket.
+	if wait := b.take("other", start); wait != 0 {
+		t.Fatalf("expected another client to be allowed, waited %v", wait)
+	}
+}
+
+func TestBucketPrune(t *testing.T) {
+	start := time.Unix(1_700_000_000, 0)
+	b := newBuckets(RateLimitConfig{Rate: 1, Burst: 5})
+
+	b.take("idle", start)
+
+	// By the next prune the idle bucket is full again.
+	b.take("busy", start.Add(pruneInterval))
+
+	if _, exists := b.clients["idle"]; exists {
+		t.Fatal("expected the idle client to be forgotten")
+	}
+	if _, exists := b.clients["busy"]; !exists {
+		t.Fatal("expected the busy client to be kept")
+	}
+}
@@ -0,0 +1,88 @@
+// Package consensus provides the engines that decide when a node produces a
+// block and how blocks are sealed and verified.
+package consensus
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ardanlabs/blockchain/foundation/blockchain/database"
+)
+
+// Set of engines supported by the node.
+const (
+	PoW = "PoW"
+	PoA = "PoA"
+	PoS = "PoS"
+)
+
+// ErrNotProposer is returned by PrepareHeader when this node is not allowed to
+// produce the next block.
+var ErrNotProposer = errors.New("node is not the proposer for the next block")
+
+// ErrHeaderNotVerifiable is returned by VerifyHeader when the engine needs
+// more than the header chain to verify the seal.
+var ErrHeaderNotVerifiable = errors.New("seal can't be verified from the header alone")
+
+// Engine represents the behavior required to be implemented by a consensus
+// mechanism so it can be used by the worker, state and database packages.
+type Engine interface {
+
+	// Name returns the name of the engine as used in the genesis file.
+	Name() string
+
+	// PrepareHeader sets the consensus fields of a new header and reports
+	// ErrNotProposer if this node should not produce the block.
+	PrepareHeader(db *database.Database, header *database.BlockHeader) error
+
+	// Seal performs the work required to make the block valid. Sealing can
+	// be cancelled through the context.
+	Seal(ctx context.Context, block *database.Block) error
+
+	// VerifySeal checks the block was produced and sealed according to the
+	// rules of the engine.
+	VerifySeal(db *database.Database, block database.Block, prevBlock database.Block) error
+
+	// VerifyHeader checks the seal of a header against its parent using only
+	// the header chain, so made up headers are rejected before any block is
+	// downloaded. Engines that need the state to verify the seal return
+	// ErrHeaderNotVerifiable.
+	VerifyHeader(header database.BlockHeader, parent database.BlockHeader) error
+
+	// Schedule returns the next time this node should try to produce a block.
+	// The zero time means blocks are produced on demand when transactions
+	// are received.
+	Schedule(now time.Time) time.Time
+}
+
+// Miner is implemented by engines that seal blocks with proof of work.
+type Miner interface {
+	Stats() MiningStats
+}
+
+// Config contains what the engines need from the node.
+type Config struct {
+	Authorities    []database.AccountID // Accounts of the genesis allowed to produce blocks under PoA.
+	BeneficiaryKey *ecdsa.PrivateKey
+	MiningThreads  int
+	Difficulty     uint16 // Difficulty of the genesis, the lowest a PoW block can have.
+	EvHandler      func(v string, args ...any)
+}
+
+// New constructs the engine with the specified name. An empty name selects
+// proof of work.
+func New(name string, cfg Config) (Engine, error) {
+	switch name {
+	case PoW, "":
+		return newPoW(cfg), nil
+	case PoA:
+		return newPoA(cfg)
+	case PoS:
+		return newPoS(cfg), nil
+	}
+
+	return nil, fmt.Errorf("consensus: unknown engine %q", name)
+}
@@ -0,0 +1,140 @@
+package consensus
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"errors"
+	"hash/fnv"
+	"sort"
+	"time"
+
+	"github.com/ardanlabs/blockchain/foundation/blockchain/database"
+)
+
+// CORE NOTE: Under PoA the nodes work in cycles of 5 seconds. At the beginning of
+// each cycle the selection algorithm is executed to determine which authority
+// of the genesis needs to produce the next block. If the beneficiary of this
+// node is not selected, it waits for the next cycle. The selection only
+// depends on the previous block, so every node can check a block was produced
+// by the selected authority. The block is sealed with a PoW at difficulty 1
+// and signed by the authority, which is what proves who produced it.
+
+// cycleDuration sets the operations to every 5 seconds
+const secondsPerCycle = 5
+const cycleDuration = secondsPerCycle * time.Second
+
+// ErrNoAuthorities is returned when PoA is selected without authorities in
+// the genesis.
+var ErrNoAuthorities = errors.New("PoA requires authorities in the genesis")
+
+type poa struct {
+	authorities    []database.AccountID
+	beneficiaryKey *ecdsa.PrivateKey
+	miner          *pow
+	evHandler      func(v string, args ...any)
+}
+
+func newPoA(cfg Config) (*poa, error) {
+	if len(cfg.Authorities) == 0 {
+		return nil, ErrNoAuthorities
+	}
+
+	authorities := make([]database.AccountID, len(cfg.Authorities))
+	copy(authorities, cfg.Authorities)
+	sort.Slice(authorities, func(i, j int) bool {
+		return authorities[i] < authorities[j]
+	})
+
+	return &poa{
+		authorities:    authorities,
+		beneficiaryKey: cfg.BeneficiaryKey,
+		miner:          newPoW(cfg),
+		evHandler:      cfg.EvHandler,
+	}, nil
+}
+
+func (e *poa) Name() string {
+	return PoA
+}
+
+// PrepareHeader drops the difficulty to 1 and checks the beneficiary was
+// selected for the cycle.
+func (e *poa) PrepareHeader(db *database.Database, header *database.BlockHeader) error {
+	header.Difficulty = 1
+
+	selected := e.selection(db.LatestBlock())
+	e.evHandler("consensus: poa: PrepareHeader: Beneficiary %s, SELECTED AUTHORITY %s", header.BeneficiaryID, selected)
+
+	if selected != header.BeneficiaryID {
+		return ErrNotProposer
+	}
+
+	return nil
+}
+
+// Seal solves the hash at difficulty 1 and signs the block with the key of
+// the authority.
+func (e *poa) Seal(ctx context.Context, block *database.Block) error {
+	if e.beneficiaryKey == nil {
+		return errors.New("no beneficiary key to sign the block")
+	}
+
+	if err := e.miner.performPOW(ctx, block); err != nil {
+		return err
+	}
+
+	return block.Sign(e.beneficiaryKey)
+}
+
+// Stats returns the statistics of the PoW miner sealing the blocks.
+func (e *poa) Stats() MiningStats {
+	return e.miner.Stats()
+}
+
+// VerifySeal checks the block was produced and signed by the authority
+// selected after the previous block.
+func (e *poa) VerifySeal(db *database.Database, block database.Block, prevBlock database.Block) error {
+	if err := verifyWork(block.Header, prevBlock.Header, 1); err != nil {
+		return err
+	}
+
+	if block.Header.BeneficiaryID != e.selection(prevBlock) {
+		return ErrWrongProposer
+	}
+
+	return block.VerifySignature()
+}
+
+// VerifyHeader checks the hash of the header solves the difficulty of 1 every
+// PoA block is sealed with and the beneficiary is the selected authority. The
+// signature isn't part of the header, so it's only checked with the block.
+func (e *poa) VerifyHeader(header database.BlockHeader, parent database.BlockHeader) error {
+	if err := verifyWork(header, parent, 1); err != nil {
+		return err
+	}
+
+	if header.BeneficiaryID != e.selection(database.Block{Header: parent}) {
+		return ErrWrongProposer
+	}
+
+	return nil
+}
+
+// Schedule returns the start of the next cycle.
+func (e *poa) Schedule(now time.Time) time.Time {
+	return now.Truncate(cycleDuration).Add(cycleDuration)
+}
+
+// =============================================================================
+
+// selection picks the authority that produces the block after the specified
+// block.
+func (e *poa) selection(prevBlock database.Block) database.AccountID {
+	// Based on the previous block, pick an index number from the registry
+	h := fnv.New32a()
+	h.Write([]byte(prevBlock.Hash()))
+	integerHash := h.Sum32()
+	i := integerHash % uint32(len(e.authorities))
+
+	return e.authorities[i]
+}
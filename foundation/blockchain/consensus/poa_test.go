@@ -0,0 +1,133 @@
+package consensus_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"errors"
+	"testing"
+
+	"github.com/ardanlabs/blockchain/foundation/blockchain/consensus"
+	"github.com/ardanlabs/blockchain/foundation/blockchain/database"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+func TestPoAOnlySelectedAuthority(t *testing.T) {
+	keys := make([]*ecdsa.PrivateKey, 3)
+	authorities := make([]database.AccountID, len(keys))
+	for i := range keys {
+		keys[i] = newKey(t)
+		authorities[i] = database.PublicKeyToAccountID(keys[i].PublicKey)
+	}
+
+	verifier := newPoA(t, authorities, nil)
+	prevBlock := database.Block{Header: database.BlockHeader{Number: 7, Nonce: 42, Difficulty: 1}}
+
+	var selected []database.Block
+	for i, key := range keys {
+		block := sealPoA(t, newPoA(t, authorities, key), prevBlock, authorities[i])
+
+		err := verifier.VerifySeal(nil, block, prevBlock)
+		switch {
+		case err == nil:
+			selected = append(selected, block)
+		case !errors.Is(err, consensus.ErrWrongProposer):
+			t.Fatalf("authority %d: expected ErrWrongProposer, got %v", i, err)
+		}
+	}
+
+	if len(selected) != 1 {
+		t.Fatalf("expected exactly one authority to be selected, got %d", len(selected))
+	}
+	block := selected[0]
+
+	// The header alone shows the same producer.
+	if err := verifier.VerifyHeader(block.Header, prevBlock.Header); err != nil {
+		t.Fatalf("verifying header: %s", err)
+	}
+
+	t.Run("unsigned", func(t *testing.T) {
+		unsigned := block
+		unsigned.Sig = ""
+
+		if err := verifier.VerifySeal(nil, unsigned, prevBlock); !errors.Is(err, database.ErrInvalidSignature) {
+			t.Fatalf("expected ErrInvalidSignature, got %v", err)
+		}
+	})
+
+	t.Run("signed by another key", func(t *testing.T) {
+		forged := block
+		if err := forged.Sign(newKey(t)); err != nil {
+			t.Fatalf("signing: %s", err)
+		}
+
+		if err := verifier.VerifySeal(nil, forged, prevBlock); !errors.Is(err, database.ErrInvalidSignature) {
+			t.Fatalf("expected ErrInvalidSignature, got %v", err)
+		}
+	})
+
+	t.Run("not an authority", func(t *testing.T) {
+		outsider := newKey(t)
+		forged := sealPoA(t, newPoA(t, authorities, outsider), prevBlock, database.PublicKeyToAccountID(outsider.PublicKey))
+
+		if err := verifier.VerifySeal(nil, forged, prevBlock); !errors.Is(err, consensus.ErrWrongProposer) {
+			t.Fatalf("expected ErrWrongProposer, got %v", err)
+		}
+		if err := verifier.VerifyHeader(forged.Header, prevBlock.Header); !errors.Is(err, consensus.ErrWrongProposer) {
+			t.Fatalf("expected the header to be refused with ErrWrongProposer, got %v", err)
+		}
+	})
+}
+
+func TestPoARequiresAuthorities(t *testing.T) {
+	if _, err := consensus.New(consensus.PoA, consensus.Config{EvHandler: noop}); !errors.Is(err, consensus.ErrNoAuthorities) {
+		t.Fatalf("expected ErrNoAuthorities, got %v", err)
+	}
+}
+
+// =============================================================================
+
+func newPoA(t *testing.T, authorities []database.AccountID, key *ecdsa.PrivateKey) consensus.Engine {
+	t.Helper()
+
+	engine, err := consensus.New(consensus.PoA, consensus.Config{
+		Authorities:    authorities,
+		BeneficiaryKey: key,
+		MiningThreads:  1,
+		EvHandler:      noop,
+	})
+	if err != nil {
+		t.Fatalf("constructing engine: %s", err)
+	}
+	return engine
+}
+
+// sealPoA seals a block on top of the previous block with the beneficiary.
+func sealPoA(t *testing.T, engine consensus.Engine, prevBlock database.Block, beneficiaryID database.AccountID) database.Block {
+	t.Helper()
+
+	block, err := database.NewBlock(database.BlockArgs{
+		BeneficiaryID: beneficiaryID,
+		Difficulty:    1,
+		PrevBlock:     prevBlock,
+	})
+	if err != nil {
+		t.Fatalf("constructing block: %s", err)
+	}
+
+	if err := engine.Seal(context.Background(), &block); err != nil {
+		t.Fatalf("sealing block: %s", err)
+	}
+	return block
+}
+
+func newKey(t *testing.T) *ecdsa.PrivateKey {
+	t.Helper()
+
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatalf("generating key: %s", err)
+	}
+	return key
+}
+
+func noop(v string, args ...any) {}
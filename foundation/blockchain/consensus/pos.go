@@ -0,0 +1,158 @@
+package consensus
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+	"time"
+
+	"github.com/ardanlabs/blockchain/foundation/blockchain/database"
+	"github.com/ardanlabs/blockchain/foundation/blockchain/signature"
+)
+
+// CORE NOTE: Under PoS time is divided into slots. For every slot a proposer is
+// picked at random weighted by stake. The random seed is the hash of the
+// previous block combined with the slot number, so every node can verify the
+// selection from data already in the chain. The slot a block belongs to is
+// derived from its timestamp which means at most one block per slot. The seal
+// is the signature of the proposer over the block hash.
+
+// SlotDuration is the length of a PoS slot.
+const SlotDuration = 5 * time.Second
+
+var ErrNoStake = errors.New("no stake locked to select a proposer")
+var ErrWrongProposer = errors.New("block beneficiary is not the slot proposer")
+
+type pos struct {
+	beneficiaryKey *ecdsa.PrivateKey
+	evHandler      func(v string, args ...any)
+}
+
+func newPoS(cfg Config) *pos {
+	return &pos{
+		beneficiaryKey: cfg.BeneficiaryKey,
+		evHandler:      cfg.EvHandler,
+	}
+}
+
+func (e *pos) Name() string {
+	return PoS
+}
+
+// PrepareHeader drops the difficulty to 1 and checks the beneficiary was
+// selected for the slot of the header timestamp.
+func (e *pos) PrepareHeader(db *database.Database, header *database.BlockHeader) error {
+	header.Difficulty = 1
+
+	slot := Slot(header.Timestamp)
+	proposer, err := SelectProposer(db, db.LatestBlock(), slot)
+	if err != nil {
+		return err
+	}
+
+	e.evHandler("consensus: pos: PrepareHeader: slot[%d]: SELECTED PROPOSER %s", slot, proposer)
+
+	if proposer != header.BeneficiaryID {
+		return ErrNotProposer
+	}
+
+	return nil
+}
+
+func (e *pos) Seal(ctx context.Context, block *database.Block) error {
+	if e.beneficiaryKey == nil {
+		return errors.New("no beneficiary key to sign the block")
+	}
+	return block.Sign(e.beneficiaryKey)
+}
+
+// VerifySeal checks the block was produced in a later slot than its parent,
+// by the proposer selected for that slot.
+func (e *pos) VerifySeal(db *database.Database, block database.Block, prevBlock database.Block) error {
+	slot := Slot(block.Header.Timestamp)
+
+	if prevBlock.Header.Number > 0 && slot <= Slot(prevBlock.Header.Timestamp) {
+		return fmt.Errorf("block slot %d is not after the previous block", slot)
+	}
+
+	if slot > Slot(uint64(time.Now().UTC().UnixMilli()))+1 {
+		return fmt.Errorf("block slot %d is in the future", slot)
+	}
+
+	proposer, err := SelectProposer(db, prevBlock, slot)
+	if err != nil {
+		return err
+	}
+
+	if block.Header.BeneficiaryID != proposer {
+		return ErrWrongProposer
+	}
+
+	return block.VerifySignature()
+}
+
+// VerifyHeader can't verify the proposer of a header since the selection
+// depends on the stakes at that height, and the seal is the signature of the
+// block which isn't part of the header.
+func (e *pos) VerifyHeader(header database.BlockHeader, parent database.BlockHeader) error {
+	return ErrHeaderNotVerifiable
+}
+
+// Schedule returns the start of the next slot.
+func (e *pos) Schedule(now time.Time) time.Time {
+	return now.Truncate(SlotDuration).Add(SlotDuration)
+}
+
+// =============================================================================
+
+// Slot returns the slot the timestamp in milliseconds belongs to.
+func Slot(timestamp uint64) uint64 {
+	return timestamp / uint64(SlotDuration.Milliseconds())
+}
+
+// SelectProposer returns the account selected to propose the block after the
+// specified block in the specified slot.
+func SelectProposer(db *database.Database, prevBlock database.Block, slot uint64) (database.AccountID, error) {
+	stakes := db.Stakes()
+
+	ids := make([]database.AccountID, 0, len(stakes))
+	total := new(big.Int)
+	for id, stake := range stakes {
+		ids = append(ids, id)
+		total.Add(total, new(big.Int).SetUint64(stake))
+	}
+
+	if total.Sign() == 0 {
+		return "", ErrNoStake
+	}
+
+	sort.Slice(ids, func(i, j int) bool {
+		return ids[i] < ids[j]
+	})
+
+	seed := struct {
+		PrevBlockHash string `json:"prev_block_hash"`
+		Slot          uint64 `json:"slot"`
+	}{
+		PrevBlockHash: prevBlock.Hash(),
+		Slot:          slot,
+	}
+
+	n, ok := new(big.Int).SetString(signature.Hash(seed)[2:], 16)
+	if !ok {
+		return "", errors.New("unable to compute proposer seed")
+	}
+	pick := n.Mod(n, total).Uint64()
+
+	for _, id := range ids {
+		if pick < stakes[id] {
+			return id, nil
+		}
+		pick -= stakes[id]
+	}
+
+	return ids[len(ids)-1], nil
+}
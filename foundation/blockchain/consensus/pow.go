@@ -0,0 +1,284 @@
+package consensus
+
+import (
+	"context"
+	"crypto/rand"
+	"math"
+	"math/big"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/ardanlabs/blockchain/foundation/blockchain/database"
+)
+
+// CORE NOTE: Under PoW a block is mined when a wallet transaction is received.
+// Mining looks for a nonce that makes the block hash start with as many zeros
+// as the difficulty asks for. The operation can be cancelled when a proposed
+// block from a peer is received and validated.
+
+type pow struct {
+	attempts   uint64 // Accessed atomically, kept first for alignment.
+	hashRate   uint64 // Accessed atomically.
+	threads    int
+	difficulty uint16
+	evHandler  func(v string, args ...any)
+}
+
+func newPoW(cfg Config) *pow {
+	return &pow{
+		threads:    miningThreads(cfg.MiningThreads),
+		difficulty: cfg.Difficulty,
+		evHandler:  cfg.EvHandler,
+	}
+}
+
+func (e *pow) Name() string {
+	return PoW
+}
+
+// PrepareHeader keeps the genesis difficulty set on the header.
+func (e *pow) PrepareHeader(db *database.Database, header *database.BlockHeader) error {
+	return nil
+}
+
+func (e *pow) Seal(ctx context.Context, block *database.Block) error {
+	return e.performPOW(ctx, block)
+}
+
+// VerifySeal checks the hash of the block solves its difficulty, which can't
+// be lower than the genesis difficulty. The block declares its own difficulty,
+// so without the floor a peer could seal blocks with no work at all.
+func (e *pow) VerifySeal(db *database.Database, block database.Block, prevBlock database.Block) error {
+	return verifyWork(block.Header, prevBlock.Header, e.difficulty)
+}
+
+// VerifyHeader checks the hash of the header solves its difficulty, which
+// can't be lower than the genesis difficulty or the difficulty of the parent.
+func (e *pow) VerifyHeader(header database.BlockHeader, parent database.BlockHeader) error {
+	return verifyWork(header, parent, e.difficulty)
+}
+
+// Schedule returns the zero time since PoW mines on demand.
+func (e *pow) Schedule(now time.Time) time.Time {
+	return time.Time{}
+}
+
+// =============================================================================
+
+// CORE NOTE: Mining is split across a number of goroutines. Each goroutine
+// searches its own range of the nonce space by incrementing the nonce, which
+// is much cheaper than asking crypto/rand for a new number every attempt. A
+// random starting point keeps miners on the network from searching the same
+// nonces. The first goroutine to solve the hash cancels the others.
+
+// hashRateInterval is how often the hashrate is calculated and reported.
+const hashRateInterval = 5 * time.Second
+
+// attemptsBatch is the number of attempts a mining goroutine performs before
+// adding them to the shared counter.
+const attemptsBatch = 10_000
+
+// MiningStats represents the current state of the PoW miner.
+type MiningStats struct {
+	Threads  int    `json:"threads"`
+	Attempts uint64 `json:"attempts"`
+	HashRate uint64 `json:"hashrate"`
+}
+
+// Stats returns the number of mining goroutines, the total attempts since the
+// node started and the latest hashrate in hashes per second.
+func (e *pow) Stats() MiningStats {
+	return MiningStats{
+		Threads:  e.threads,
+		Attempts: atomic.LoadUint64(&e.attempts),
+		HashRate: atomic.LoadUint64(&e.hashRate),
+	}
+}
+
+func (e *pow) performPOW(ctx context.Context, b *database.Block) error {
+	ev := e.evHandler
+
+	ev("consensus: performPOW: started: threads[%d]", e.threads)
+	defer ev("consensus: performPOW: completed")
+
+	// External miners only work with the header.
+	if b.MerkleTree != nil {
+		for _, tx := range b.MerkleTree.Values() {
+			ev("consensus: performPOW: tx [%s]", tx)
+		}
+	}
+
+	nBig, err := rand.Int(rand.Reader, big.NewInt(math.MaxInt64))
+	if err != nil {
+		return err
+	}
+	base := nBig.Uint64()
+
+	ev("consensus: performPOW: mining")
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var once sync.Once
+	var nonce uint64
+	var solved bool
+	var attempts uint64
+
+	var wg sync.WaitGroup
+	wg.Add(e.threads)
+
+	// Each goroutine starts at its own offset so the ranges never overlap.
+	rangeSize := math.MaxUint64 / uint64(e.threads)
+
+	for i := 0; i < e.threads; i++ {
+		go func(start uint64) {
+			defer wg.Done()
+
+			// Work on a copy of the block so the header isn't shared.
+			blk := database.Block{Header: b.Header}
+			blk.Header.Nonce = start
+
+			var local uint64
+			for {
+				if local == attemptsBatch {
+					atomic.AddUint64(&attempts, local)
+					atomic.AddUint64(&e.attempts, local)
+					local = 0
+
+					if ctx.Err() != nil {
+						return
+					}
+				}
+				local++
+
+				if !isHashSolved(blk.Header.Difficulty, blk.Hash()) {
+					blk.Header.Nonce++
+					continue
+				}
+
+				// We have a solved hash
+				atomic.AddUint64(&attempts, local)
+				atomic.AddUint64(&e.attempts, local)
+
+				once.Do(func() {
+					nonce = blk.Header.Nonce
+					solved = true
+					cancel()
+				})
+				return
+			}
+		}(base + uint64(i)*rangeSize)
+	}
+
+	// Report the hashrate while the mining goroutines are working.
+	reported := make(chan struct{})
+	go func() {
+		defer close(reported)
+
+		ticker := time.NewTicker(hashRateInterval)
+		defer ticker.Stop()
+
+		last := uint64(0)
+		for {
+			select {
+			case <-ticker.C:
+				total := atomic.LoadUint64(&attempts)
+				rate := (total - last) / uint64(hashRateInterval/time.Second)
+				last = total
+
+				atomic.StoreUint64(&e.hashRate, rate)
+				ev("consensus: performPOW: hashrate[%d H/s] attempts[%d]", rate, total)
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	t := time.Now()
+	wg.Wait()
+	cancel()
+	<-reported
+
+	total := atomic.LoadUint64(&attempts)
+	if secs := time.Since(t).Seconds(); secs > 0 {
+		atomic.StoreUint64(&e.hashRate, uint64(float64(total)/secs))
+	}
+
+	if !solved {
+		ev("consensus: performPOW: Mining cancelled: attempts[%d]", total)
+		return ctx.Err()
+	}
+
+	b.Header.Nonce = nonce
+
+	ev("consensus: performPOW: Solved prevBlk[%s], newBlk[%s]", b.Header.PrevBlockHash, b.Hash())
+	ev("consensus: performPOW: Attempts [%d] hashrate[%d H/s]", total, atomic.LoadUint64(&e.hashRate))
+
+	return nil
+}
+
+// Mine searches for the nonce that solves the block hash using the specified
+// number of goroutines. It's used by miners that run outside of a node.
+func Mine(ctx context.Context, block *database.Block, threads int, ev func(v string, args ...any)) error {
+	e := newPoW(Config{MiningThreads: threads, EvHandler: ev})
+	return e.performPOW(ctx, block)
+}
+
+// maxDifficulty is the number of hex digits of a hash, so no hash can solve a
+// higher difficulty.
+const maxDifficulty = 64
+
+// Target returns the largest hash value that solves a block at the specified
+// difficulty.
+func Target(difficulty uint16) string {
+	d := int(difficulty)
+	if d > maxDifficulty {
+		d = maxDifficulty
+	}
+
+	return "0x" + strings.Repeat("0", d) + strings.Repeat("f", maxDifficulty-d)
+}
+
+// verifyWork checks the hash of the header solves its difficulty, which can't
+// be lower than the minimum or the difficulty of the parent. The difficulty
+// comes from a peer, so it's bounded before the hash is checked.
+func verifyWork(header database.BlockHeader, parent database.BlockHeader, min uint16) error {
+	if header.Difficulty < min || header.Difficulty < parent.Difficulty || header.Difficulty > maxDifficulty {
+		return database.ErrInvalidDifficulty
+	}
+
+	block := database.Block{Header: header}
+	if !isHashSolved(header.Difficulty, block.Hash()) {
+		return database.ErrInvalidHash
+	}
+
+	return nil
+}
+
+// isHashSolved reports if the hash starts with as many zeros as the
+// difficulty.
+func isHashSolved(difficulty uint16, hash string) bool {
+	if len(hash) != 2+maxDifficulty || difficulty > maxDifficulty {
+		return false
+	}
+
+	for _, c := range hash[2 : 2+difficulty] {
+		if c != '0' {
+			return false
+		}
+	}
+
+	return true
+}
+
+// miningThreads returns the number of mining goroutines to use. Zero or less
+// means one per CPU.
+func miningThreads(n int) int {
+	if n <= 0 {
+		return runtime.NumCPU()
+	}
+	return n
+}
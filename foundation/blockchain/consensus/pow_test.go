@@ -0,0 +1,123 @@
+package consensus_test
+
+import (
+	"context"
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/ardanlabs/blockchain/foundation/blockchain/consensus"
+	"github.com/ardanlabs/blockchain/foundation/blockchain/database"
+)
+
+func TestPoWVerifyHeader(t *testing.T) {
+	const genesisDifficulty = 2
+
+	engine := newPoW(t, genesisDifficulty)
+	parent := database.BlockHeader{Number: 1, Difficulty: genesisDifficulty}
+
+	header := mine(t, parent, genesisDifficulty).Header
+	if err := engine.VerifyHeader(header, parent); err != nil {
+		t.Fatalf("verifying a solved header: %s", err)
+	}
+
+	tt := []struct {
+		name       string
+		difficulty uint16
+		parent     uint16
+		err        error
+	}{
+		{name: "below genesis", difficulty: genesisDifficulty - 1, parent: genesisDifficulty - 1, err: database.ErrInvalidDifficulty},
+		{name: "below parent", difficulty: genesisDifficulty, parent: genesisDifficulty + 1, err: database.ErrInvalidDifficulty},
+		{name: "unsolved", difficulty: 17, parent: genesisDifficulty, err: database.ErrInvalidHash},
+		{name: "longer than the match", difficulty: 18, parent: genesisDifficulty, err: database.ErrInvalidHash},
+		{name: "every digit", difficulty: 64, parent: genesisDifficulty, err: database.ErrInvalidHash},
+		{name: "beyond the hash", difficulty: 65, parent: genesisDifficulty, err: database.ErrInvalidDifficulty},
+		{name: "largest", difficulty: math.MaxUint16, parent: genesisDifficulty, err: database.ErrInvalidDifficulty},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			h := header
+			h.Difficulty = tc.difficulty
+
+			err := engine.VerifyHeader(h, database.BlockHeader{Number: 1, Difficulty: tc.parent})
+			if !errors.Is(err, tc.err) {
+				t.Fatalf("expected %v, got %v", tc.err, err)
+			}
+		})
+	}
+}
+
+func TestPoWVerifySeal(t *testing.T) {
+	const genesisDifficulty = 2
+
+	engine := newPoW(t, genesisDifficulty)
+	prevBlock := database.Block{Header: database.BlockHeader{Number: 1, Difficulty: genesisDifficulty}}
+
+	block := mine(t, prevBlock.Header, genesisDifficulty)
+	if err := engine.VerifySeal(nil, block, prevBlock); err != nil {
+		t.Fatalf("verifying a solved block: %s", err)
+	}
+
+	t.Run("below genesis", func(t *testing.T) {
+		easy := mine(t, database.BlockHeader{Number: 1}, 0)
+
+		if err := engine.VerifySeal(nil, easy, database.Block{Header: database.BlockHeader{Number: 1}}); !errors.Is(err, database.ErrInvalidDifficulty) {
+			t.Fatalf("expected a block below the genesis difficulty to be refused, got %v", err)
+		}
+	})
+
+	for _, difficulty := range []uint16{18, 65, math.MaxUint16} {
+		hard := block
+		hard.Header.Difficulty = difficulty
+
+		if err := engine.VerifySeal(nil, hard, prevBlock); err == nil {
+			t.Fatalf("expected a block claiming difficulty %d to be refused", difficulty)
+		}
+	}
+}
+
+func TestTarget(t *testing.T) {
+	if target := consensus.Target(2); target != "0x00ffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff" {
+		t.Fatalf("unexpected target %s", target)
+	}
+
+	if target := consensus.Target(math.MaxUint16); len(target) != 66 {
+		t.Fatalf("expected the target to be capped to the hash length, got %s", target)
+	}
+}
+
+// =============================================================================
+
+func newPoW(t *testing.T, difficulty uint16) consensus.Engine {
+	t.Helper()
+
+	engine, err := consensus.New(consensus.PoW, consensus.Config{
+		MiningThreads: 1,
+		Difficulty:    difficulty,
+		EvHandler:     noop,
+	})
+	if err != nil {
+		t.Fatalf("constructing engine: %s", err)
+	}
+	return engine
+}
+
+// mine solves a block on top of the parent at the difficulty.
+func mine(t *testing.T, parent database.BlockHeader, difficulty uint16) database.Block {
+	t.Helper()
+
+	block, err := database.NewBlock(database.BlockArgs{
+		Difficulty: difficulty,
+		PrevBlock:  database.Block{Header: parent},
+	})
+	if err != nil {
+		t.Fatalf("constructing block: %s", err)
+	}
+
+	if err := consensus.Mine(context.Background(), &block, 1, noop); err != nil {
+		t.Fatalf("mining block: %s", err)
+	}
+	return block
+}
@@ -14,6 +14,10 @@ type Account struct {
 	AccountID AccountID
 	Balance   uint64
 	Nonce     uint64
+	Stake     uint64   `json:",omitempty"` // Balance locked under PoS.
+	Unbonding uint64   `json:",omitempty"` // Stake released but still slashable.
+	UnbondAt  uint64   `json:",omitempty"` // Block the unbonding stake returns to the balance.
+	Slashed   []uint64 `json:",omitempty"` // Heights of the double signs already slashed.
 }
 
 func newAccount(accountID AccountID, balance uint64) Account {
@@ -23,6 +27,17 @@ func newAccount(accountID AccountID, balance uint64) Account {
 	}
 }
 
+// IsSlashed reports if the double sign of the account at the height has
+// already been slashed.
+func (a Account) IsSlashed(number uint64) bool {
+	for _, slashed := range a.Slashed {
+		if slashed == number {
+			return true
+		}
+	}
+	return false
+}
+
 func ToAccountID(hex string) (AccountID, error) {
 	a := AccountID(hex)
 	if !a.IsAccountID() {
@@ -31,6 +46,19 @@ func ToAccountID(hex string) (AccountID, error) {
 	return AccountID(hex), nil
 }
 
+// ToAccountIDs converts a list of hex strings into account ids.
+func ToAccountIDs(hexes []string) ([]AccountID, error) {
+	ids := make([]AccountID, len(hexes))
+	for i, hex := range hexes {
+		id, err := ToAccountID(hex)
+		if err != nil {
+			return nil, err
+		}
+		ids[i] = id
+	}
+	return ids, nil
+}
+
 func PublicKeyToAccountID(pk ecdsa.PublicKey) AccountID {
 	return AccountID(crypto.PubkeyToAddress(pk).String())
 }
@@ -45,7 +73,7 @@ func (a AccountID) IsAccountID() bool {
 }
 
 func (a AccountID) has0xPrefix() bool {
-	return a[:2] == "0x"
+	return len(a) >= 2 && a[:2] == "0x"
 }
 
 func (a AccountID) IsHex() bool {
@@ -1,11 +1,8 @@
 package database
 
 import (
-	"context"
-	"crypto/rand"
+	"crypto/ecdsa"
 	"errors"
-	"math"
-	"math/big"
 	"time"
 
 	"github.com/ardanlabs/blockchain/foundation/blockchain/merkle"
@@ -20,11 +17,16 @@ var ErrInvalidPrevBlockHash = errors.New("invalid previous block hash")
 var ErrInvalidBlockTimestamp = errors.New("invalid block timestamp")
 var ErrInvalidStateRoot = errors.New("invalid state root")
 var ErrInvalidTransRoot = errors.New("invalid transaction root")
+var ErrInvalidSignature = errors.New("invalid block signature")
+var ErrBlockNotFound = errors.New("block not found")
+var ErrBlockPruned = errors.New("block pruned, the node started from a snapshot after it")
 
 type BlockData struct {
-	Hash   string      `json:"hash"`
-	Header BlockHeader `json:"block"`
-	Trans  []BlockTx   `json:"tx"`
+	Hash   string        `json:"hash"`
+	Header BlockHeader   `json:"block"`
+	Trans  []BlockTx     `json:"tx"`
+	Sig    string        `json:"sig,omitempty"`
+	Cert   *FinalityCert `json:"cert,omitempty"`
 }
 
 func NewBlockData(block Block) BlockData {
@@ -32,6 +34,8 @@ func NewBlockData(block Block) BlockData {
 		Hash:   block.Hash(),
 		Header: block.Header,
 		Trans:  block.MerkleTree.Values(),
+		Sig:    block.Sig,
+		Cert:   block.Cert,
 	}
 
 	return blockData
@@ -46,6 +50,8 @@ func ToBlock(blockData BlockData) (Block, error) {
 	block := Block{
 		Header:     blockData.Header,
 		MerkleTree: tree,
+		Sig:        blockData.Sig,
+		Cert:       blockData.Cert,
 	}
 	return block, nil
 }
@@ -67,6 +73,8 @@ type BlockHeader struct {
 type Block struct {
 	Header     BlockHeader
 	MerkleTree *merkle.Tree[BlockTx]
+	Sig        string        // Producer signature of the hash under PoA and PoS, not part of the hash.
+	Cert       *FinalityCert // Set once the block has been finalized, not part of the hash.
 }
 
 func (b *Block) Hash() string {
@@ -88,17 +96,57 @@ func (b *Block) Hash() string {
 	return signature.Hash(b.Header)
 }
 
-type POWArgs struct {
+// Sign signs the block hash with the private key of the beneficiary.
+func (b *Block) Sign(privateKey *ecdsa.PrivateKey) error {
+	v, r, s, err := signature.Sign(b.Hash(), privateKey)
+	if err != nil {
+		return err
+	}
+
+	b.Sig = signature.SignatureString(v, r, s)
+	return nil
+}
+
+// VerifySignature checks the block hash was signed by the beneficiary.
+func (b *Block) VerifySignature() error {
+	const sigLength = 2 + 65*2
+	if len(b.Sig) != sigLength {
+		return ErrInvalidSignature
+	}
+
+	v, r, s, err := signature.ToVRSFromHexSignature(b.Sig)
+	if err != nil {
+		return err
+	}
+
+	if err := signature.VerifySignature(v, r, s); err != nil {
+		return err
+	}
+
+	address, err := signature.FromAddress(b.Hash(), v, r, s)
+	if err != nil {
+		return err
+	}
+
+	if address != string(b.Header.BeneficiaryID) {
+		return ErrInvalidSignature
+	}
+
+	return nil
+}
+
+type BlockArgs struct {
 	BeneficiaryID AccountID
 	Difficulty    uint16
 	MiningReward  uint64
 	PrevBlock     Block
 	StateRoot     string
 	Trans         []BlockTx
-	EvHandler     func(v string, args ...any)
 }
 
-func POW(ctx context.Context, args POWArgs) (Block, error) {
+// NewBlock constructs a block on top of the previous block. The block still
+// needs to be sealed by a consensus engine before it is valid.
+func NewBlock(args BlockArgs) (Block, error) {
 	// When mining the first block, the previous block hash is the zero hash.
 	prevBlockHash := signature.ZeroHash
 	if args.PrevBlock.Header.Number > 0 {
@@ -123,67 +171,16 @@ func POW(ctx context.Context, args POWArgs) (Block, error) {
 		TransRoot:     tree.RootHex(),
 		Nonce:         0,
 	}
+
 	// Create the block
 	block := Block{
 		Header:     header,
 		MerkleTree: tree,
 	}
 
-	if err := block.performPOW(ctx, args.EvHandler); err != nil {
-		return Block{}, err
-	}
-
 	return block, nil
 }
 
-func (b *Block) performPOW(ctx context.Context, ev func(v string, args ...any)) error {
-	ev("database:performPOW:started")
-	defer ev("database:performPOW:completed")
-
-	for _, tx := range b.MerkleTree.Values() {
-		ev("database:performPOW:tx [%s]", tx)
-	}
-
-	nBig, err := rand.Int(rand.Reader, big.NewInt(math.MaxInt64))
-	if err != nil {
-		return err
-	}
-	b.Header.Nonce = nBig.Uint64()
-
-	ev("database: PerformPOW ")
-
-	var attemps uint64
-	for {
-		attemps++
-		if attemps%1000000 == 0 {
-			ev("database: PerformPOW for Attemps [%d]", attemps)
-		}
-
-		if ctx.Err() != nil {
-			ev("database: PerformPOW: Mining cancelled")
-			return ctx.Err()
-		}
-
-		hash := b.Hash()
-		if !isHashSolved(b.Header.Difficulty, hash) {
-			nBig, err := rand.Int(rand.Reader, big.NewInt(math.MaxInt64))
-			if err != nil {
-				return err
-			}
-			b.Header.Nonce = nBig.Uint64()
-			// Regenerating a random number seems faster than incrementing a number
-			// b.Header.Nonce++
-			continue
-		}
-		// We have a solved hash
-
-		ev("database: PerformPOW: Solved prevBlk[%s], newBlk[%s]", b.Header.PrevBlockHash, hash)
-		ev("database: PerformPOW: Attempts [%d]", attemps)
-
-		return nil
-	}
-}
-
 func (b *Block) ValidateBlock(previousBlock Block, stateRoot string, evHandler func(v string, args ...any)) error {
 	evHandler("database: ValidateBlock: blk[%d]: check: chain is not forked", b.Header.Number)
 
@@ -201,13 +198,6 @@ func (b *Block) ValidateBlock(previousBlock Block, stateRoot string, evHandler f
 
 	evHandler("database: ValidateBlock: blk[%d]: check: block difficulty is correct", b.Header.Number)
 
-	hash := b.Hash()
-	if !isHashSolved(b.Header.Difficulty, hash) {
-		return ErrInvalidHash
-	}
-
-	evHandler("database: ValidateBlock: blk[%d]: check: block hash is correct", b.Header.Number)
-
 	if b.Header.Number != nextNumber {
 		return ErrInvalidBlockNumber
 	}
@@ -243,15 +233,3 @@ func (b *Block) ValidateBlock(previousBlock Block, stateRoot string, evHandler f
 
 	return nil
 }
-
-func isHashSolved(difficulty uint16, hash string) bool {
-	const match = "0x00000000000000000"
-
-	if len(hash) != 66 {
-		return false
-	}
-
-	difficulty += 2
-
-	return hash[:difficulty] == match[:difficulty]
-}
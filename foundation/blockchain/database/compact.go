@@ -0,0 +1,83 @@
+package database
+
+import "errors"
+
+// CORE NOTE: By the time a block is relayed, its peers usually have its
+// transactions in their mempool already. A compact block carries the header
+// and the hashes of the transactions so the receiver rebuilds the block from
+// its own mempool and only asks the sender for the transactions it's missing.
+// Only when the block still can't be rebuilt is the full block downloaded.
+
+// ErrMissingTxs is returned when a compact block can't be rebuilt because
+// transactions are missing.
+var ErrMissingTxs = errors.New("compact block is missing transactions")
+
+// CompactBlock represents a block with its transactions replaced by their
+// hashes.
+type CompactBlock struct {
+	Hash     string        `json:"hash"`
+	Header   BlockHeader   `json:"block"`
+	TxHashes []string      `json:"tx_hashes"`
+	Sig      string        `json:"sig,omitempty"`
+	Cert     *FinalityCert `json:"cert,omitempty"`
+}
+
+// NewCompactBlock constructs the compact form of the block.
+func NewCompactBlock(block Block) CompactBlock {
+	values := block.MerkleTree.Values()
+
+	hashes := make([]string, len(values))
+	for i, tx := range values {
+		hashes[i] = tx.HashString()
+	}
+
+	return CompactBlock{
+		Hash:     block.Hash(),
+		Header:   block.Header,
+		TxHashes: hashes,
+		Sig:      block.Sig,
+		Cert:     block.Cert,
+	}
+}
+
+// Missing returns the hashes of the transactions that aren't in the set.
+func (cb CompactBlock) Missing(txs map[string]BlockTx) []string {
+	var missing []string
+	for _, hash := range cb.TxHashes {
+		if _, exists := txs[hash]; !exists {
+			missing = append(missing, hash)
+		}
+	}
+
+	return missing
+}
+
+// ToBlock rebuilds the block from the transactions in the set, which is
+// keyed by transaction hash.
+func (cb CompactBlock) ToBlock(txs map[string]BlockTx) (Block, error) {
+	if len(cb.Missing(txs)) > 0 {
+		return Block{}, ErrMissingTxs
+	}
+
+	trans := make([]BlockTx, len(cb.TxHashes))
+	for i, hash := range cb.TxHashes {
+		trans[i] = txs[hash]
+	}
+
+	block, err := ToBlock(BlockData{
+		Hash:   cb.Hash,
+		Header: cb.Header,
+		Trans:  trans,
+		Sig:    cb.Sig,
+		Cert:   cb.Cert,
+	})
+	if err != nil {
+		return Block{}, err
+	}
+
+	if block.MerkleTree.RootHex() != cb.Header.TransRoot {
+		return Block{}, ErrInvalidTransRoot
+	}
+
+	return block, nil
+}
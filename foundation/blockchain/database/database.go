@@ -2,6 +2,7 @@ package database
 
 import (
 	"errors"
+	"fmt"
 	"sort"
 	"sync"
 
@@ -11,11 +12,19 @@ import (
 
 type Storage interface {
 	Write(block BlockData) error
+
+	// GetBlock returns ErrBlockNotFound when no stored block has the hash.
 	GetBlock(hash string) (BlockData, error)
 	GetBlockByNumber(number uint64) (BlockData, error)
 	ForEach() Iterator
 	Close() error
 	Reset() error
+
+	// A node that fast synced starts from a snapshot instead of genesis.
+	// ReadSnapshot returns ErrSnapshotNotFound when there is none and
+	// ForEach starts with the block after the snapshot.
+	WriteSnapshot(snapshot Snapshot) error
+	ReadSnapshot() (Snapshot, error)
 }
 
 type Iterator interface {
@@ -26,18 +35,57 @@ type Iterator interface {
 // ===========================
 
 type Database struct {
-	mu          sync.RWMutex
-	genesis     genesis.Genesis
-	latestBlock Block
-	accounts    map[AccountID]Account
-	storage     Storage
+	mu               sync.RWMutex
+	genesis          genesis.Genesis
+	latestBlock      Block
+	finalized        FinalityCert
+	authorities      []AccountID
+	staking          bool
+	sealVerifier     SealVerifier
+	snapshotInterval uint64
+	snapshots        []Snapshot
+	pruned           uint64
+	accounts         map[AccountID]Account
+	index            *index
+	storage          Storage
+}
+
+// SealVerifier is the behavior required to check the consensus seal of the
+// blocks read from storage.
+type SealVerifier interface {
+	VerifySeal(db *Database, block Block, prevBlock Block) error
+}
+
+// WithSealVerifier is used to check the consensus seal of every block read
+// from storage while the database is constructed.
+func WithSealVerifier(sv SealVerifier) func(db *Database) {
+	return func(db *Database) {
+		db.sealVerifier = sv
+	}
+}
+
+// WithStaking is used to enable the staking account under PoS. Without it
+// the stakes of the genesis are ignored and staking transactions rejected, so
+// the state of the other consensus modes is left untouched.
+func WithStaking() func(db *Database) {
+	return func(db *Database) {
+		db.staking = true
+	}
 }
 
-func New(genesis genesis.Genesis, storage Storage, evHandler func(v string, args ...any)) (*Database, error) {
+func New(genesis genesis.Genesis, storage Storage, evHandler func(v string, args ...any), options ...func(db *Database)) (*Database, error) {
+	authorities, err := ToAccountIDs(genesis.Authorities)
+	if err != nil {
+		return nil, err
+	}
+
 	db := Database{
-		genesis:  genesis,
-		accounts: make(map[AccountID]Account),
-		storage:  storage,
+		genesis:     genesis,
+		finalized:   FinalityCert{BlockHash: signature.ZeroHash},
+		authorities: authorities,
+		accounts:    make(map[AccountID]Account),
+		index:       newIndex(),
+		storage:     storage,
 	}
 
 	for accountStr, balance := range genesis.Balances {
@@ -49,6 +97,39 @@ func New(genesis genesis.Genesis, storage Storage, evHandler func(v string, args
 
 		evHandler("Account %s, Balance: %d", accountID, balance)
 	}
+
+	for _, option := range options {
+		option(&db)
+	}
+
+	if db.staking {
+		for accountStr, stake := range genesis.Stakes {
+			accountID, err := ToAccountID(accountStr)
+			if err != nil {
+				return nil, err
+			}
+			account, exists := db.accounts[accountID]
+			if !exists {
+				account = newAccount(accountID, 0)
+			}
+			account.Stake = stake
+			db.accounts[accountID] = account
+
+			evHandler("Account %s, Stake: %d", accountID, stake)
+		}
+	}
+
+	// A node that fast synced replaces the genesis state with its snapshot.
+	switch snapshot, err := storage.ReadSnapshot(); {
+	case err == nil:
+		if err := db.restoreSnapshot(snapshot); err != nil {
+			return nil, err
+		}
+		evHandler("Snapshot blk[%d], Accounts: %d", snapshot.Header.Number, len(snapshot.Accounts))
+	case !errors.Is(err, ErrSnapshotNotFound):
+		return nil, err
+	}
+
 	// Read all the blocks from the storage and validate them.
 	iter := db.ForEach()
 
@@ -57,18 +138,37 @@ func New(genesis genesis.Genesis, storage Storage, evHandler func(v string, args
 			return nil, err
 		}
 
+		if db.sealVerifier != nil {
+			if err := db.sealVerifier.VerifySeal(&db, block, db.latestBlock); err != nil {
+				return nil, err
+			}
+		}
+
 		if err := block.ValidateBlock(db.latestBlock, db.HashState(), evHandler); err != nil {
 			return nil, err
 		}
 
+		// The block is indexed first so the transactions that fail are
+		// recorded against it.
+		db.index.add(block)
+
 		// Update the database with the information from the block.
 		for _, tx := range block.MerkleTree.Values() {
 			db.ApplyTransaction(block, tx)
 		}
 
 		db.ApplyMiningReward(block)
+		db.ReleaseUnbonded(block)
 
 		db.latestBlock = block
+		db.CaptureSnapshot(block)
+
+		if block.Cert != nil {
+			if err := block.Cert.Validate(genesis.ChainID, authorities); err != nil {
+				return nil, err
+			}
+			db.finalized = *block.Cert
+		}
 
 	}
 	return &db, nil
@@ -145,6 +245,51 @@ func (db *Database) LatestBlock() Block {
 	return db.latestBlock
 }
 
+// Authorities returns the accounts allowed to vote on block finality.
+func (db *Database) Authorities() []AccountID {
+	return db.authorities
+}
+
+// Finalized returns the certificate of the latest finalized block. The zero
+// value represents the genesis block which is always final.
+func (db *Database) Finalized() FinalityCert {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	return db.finalized
+}
+
+// Finalize stores the certificate with its block and moves the finalized
+// height forward.
+func (db *Database) Finalize(cert FinalityCert) error {
+	blockData, err := db.storage.GetBlockByNumber(cert.Number)
+	if err != nil {
+		return err
+	}
+
+	if blockData.Hash != cert.BlockHash {
+		return errors.New("certificate does not match stored block")
+	}
+
+	blockData.Cert = &cert
+	if err := db.storage.Write(blockData); err != nil {
+		return err
+	}
+
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	if cert.Number > db.finalized.Number {
+		db.finalized = cert
+	}
+
+	if db.latestBlock.Header.Number == cert.Number {
+		db.latestBlock.Cert = &cert
+	}
+
+	return nil
+}
+
 func (db *Database) HashState() string {
 	accounts := make([]Account, 0, len(db.accounts))
 	db.mu.RLock()
@@ -171,10 +316,16 @@ func (db *Database) ApplyMiningReward(block Block) {
 	db.accounts[block.Header.BeneficiaryID] = account
 }
 
-func (db *Database) ApplyTransaction(block Block, tx BlockTx) error {
+func (db *Database) ApplyTransaction(block Block, tx BlockTx) (err error) {
 	db.mu.Lock()
 	defer db.mu.Unlock()
 
+	defer func() {
+		if err != nil {
+			db.index.fail(block, tx, err)
+		}
+	}()
+
 	from, exists := db.accounts[tx.FromID]
 	if !exists {
 		return errors.New("from account not found")
@@ -206,6 +357,16 @@ func (db *Database) ApplyTransaction(block Block, tx BlockTx) error {
 		if tx.Nonce != (from.Nonce + 1) {
 			return errors.New("invalid nonce")
 		}
+
+		// Staking transactions lock or release stake instead of moving
+		// value to another account.
+		if tx.ToID == StakingAccountID {
+			if !db.staking {
+				return ErrStakingDisabled
+			}
+			return db.applyStakeTx(block, tx, from)
+		}
+
 		if from.Balance == 0 || from.Balance < (tx.Value+tx.Tip) {
 			return errors.New("insufficient funds")
 		}
@@ -229,7 +390,14 @@ func (db *Database) ApplyTransaction(block Block, tx BlockTx) error {
 	return nil
 }
 
+// GetBlock returns the stored block with the specified number. A node that
+// restored a snapshot never stored the blocks up to the snapshot, so those
+// return ErrBlockPruned.
 func (db *Database) GetBlock(num uint64) (Block, error) {
+	if pruned := db.Pruned(); num > 0 && num <= pruned {
+		return Block{}, fmt.Errorf("blk[%d]: %w up to blk[%d]", num, ErrBlockPruned, pruned)
+	}
+
 	blockData, err := db.storage.GetBlockByNumber(num)
 	if err != nil {
 		return Block{}, err
@@ -238,6 +406,16 @@ func (db *Database) GetBlock(num uint64) (Block, error) {
 	return ToBlock(blockData)
 }
 
+// GetBlockByHash returns the stored block with the specified hash.
+func (db *Database) GetBlockByHash(hash string) (Block, error) {
+	blockData, err := db.storage.GetBlock(hash)
+	if err != nil {
+		return Block{}, err
+	}
+
+	return ToBlock(blockData)
+}
+
 type DatabaseIterator struct {
 	iterator Iterator
 }
@@ -256,5 +434,11 @@ func (di *DatabaseIterator) Done() bool {
 }
 
 func (d *Database) Write(block Block) error {
-	return d.storage.Write(NewBlockData(block))
+	if err := d.storage.Write(NewBlockData(block)); err != nil {
+		return err
+	}
+
+	d.index.add(block)
+
+	return nil
 }
@@ -0,0 +1,150 @@
+package database
+
+import (
+	"crypto/ecdsa"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/ardanlabs/blockchain/foundation/blockchain/signature"
+)
+
+// CORE NOTE: Under PoA a block is accepted by each node on its own, which means
+// two authorities can produce conflicting blocks at the same height. Finality
+// is layered on top with a Tendermint style vote. Every authority prevotes for
+// the first block it accepts at a height and precommits once more than 2/3 of
+// the authorities prevoted for the same block. More than 2/3 precommits make
+// the block final and form the certificate that is stored with the block.
+
+var ErrBelowFinalized = errors.New("block is at or below the finalized height")
+
+type VoteType string
+
+const (
+	VotePrevote   VoteType = "prevote"
+	VotePrecommit VoteType = "precommit"
+)
+
+type Vote struct {
+	ChainID   uint16    `json:"chain_id"`
+	Type      VoteType  `json:"type"`
+	Number    uint64    `json:"number"`
+	BlockHash string    `json:"block_hash"`
+	VoterID   AccountID `json:"voter"`
+}
+
+func NewVote(chainID uint16, voteType VoteType, block Block, voterID AccountID) Vote {
+	return Vote{
+		ChainID:   chainID,
+		Type:      voteType,
+		Number:    block.Header.Number,
+		BlockHash: block.Hash(),
+		VoterID:   voterID,
+	}
+}
+
+// ==============================
+
+type SignedVote struct {
+	Vote
+	V *big.Int `json:"v"`
+	R *big.Int `json:"r"`
+	S *big.Int `json:"s"`
+}
+
+func (v Vote) Sign(privateKey *ecdsa.PrivateKey) (SignedVote, error) {
+	sv, sr, ss, err := signature.Sign(v, privateKey)
+	if err != nil {
+		return SignedVote{}, err
+	}
+
+	return SignedVote{
+		Vote: v,
+		V:    sv,
+		R:    sr,
+		S:    ss,
+	}, nil
+}
+
+func (sv SignedVote) Validate(chainID uint16) error {
+	if sv.ChainID != chainID {
+		return errors.New("invalid chain id")
+	}
+
+	if sv.Type != VotePrevote && sv.Type != VotePrecommit {
+		return fmt.Errorf("invalid vote type %q", sv.Type)
+	}
+
+	if !sv.VoterID.IsAccountID() {
+		return errors.New("invalid voter account id")
+	}
+
+	if err := signature.VerifySignature(sv.V, sv.R, sv.S); err != nil {
+		return err
+	}
+
+	address, err := signature.FromAddress(sv.Vote, sv.V, sv.R, sv.S)
+	if err != nil {
+		return err
+	}
+
+	if address != string(sv.VoterID) {
+		return errors.New("signature does not match voter account id")
+	}
+
+	return nil
+}
+
+// String returns the vote as a string.
+func (sv SignedVote) String() string {
+	return fmt.Sprintf("%s:%s:%d", sv.VoterID, sv.Type, sv.Number)
+}
+
+// ==============================
+
+// FinalityCert proves more than 2/3 of the authorities precommitted to a block.
+type FinalityCert struct {
+	Number     uint64       `json:"number"`
+	BlockHash  string       `json:"block_hash"`
+	Precommits []SignedVote `json:"precommits"`
+}
+
+// Validate checks every precommit in the certificate is signed by a distinct
+// authority for this block and that together they form a quorum.
+func (c FinalityCert) Validate(chainID uint16, authorities []AccountID) error {
+	set := make(map[AccountID]struct{}, len(authorities))
+	for _, id := range authorities {
+		set[id] = struct{}{}
+	}
+
+	seen := make(map[AccountID]struct{})
+	for _, vote := range c.Precommits {
+		if err := vote.Validate(chainID); err != nil {
+			return err
+		}
+
+		if vote.Type != VotePrecommit || vote.Number != c.Number || vote.BlockHash != c.BlockHash {
+			return errors.New("certificate vote does not match block")
+		}
+
+		if _, exists := set[vote.VoterID]; !exists {
+			return fmt.Errorf("voter %s is not an authority", vote.VoterID)
+		}
+
+		if _, exists := seen[vote.VoterID]; exists {
+			return fmt.Errorf("voter %s counted twice", vote.VoterID)
+		}
+		seen[vote.VoterID] = struct{}{}
+	}
+
+	if !HasQuorum(len(seen), len(authorities)) {
+		return errors.New("certificate does not have a quorum")
+	}
+
+	return nil
+}
+
+// HasQuorum reports if the number of votes is more than 2/3 of the total.
+func HasQuorum(votes int, total int) bool {
+	return total > 0 && votes*3 > total*2
+}
@@ -0,0 +1,209 @@
+package database_test
+
+import (
+	"crypto/ecdsa"
+	"testing"
+
+	"github.com/ardanlabs/blockchain/foundation/blockchain/database"
+	"github.com/ardanlabs/blockchain/foundation/blockchain/genesis"
+	"github.com/ardanlabs/blockchain/foundation/blockchain/storage/disk"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+const chainID = 1
+
+func TestHasQuorum(t *testing.T) {
+	tt := []struct {
+		votes  int
+		total  int
+		quorum bool
+	}{
+		{votes: 0, total: 0, quorum: false},
+		{votes: 1, total: 1, quorum: true},
+		{votes: 2, total: 3, quorum: false},
+		{votes: 3, total: 3, quorum: true},
+		{votes: 2, total: 4, quorum: false},
+		{votes: 3, total: 4, quorum: true},
+		{votes: 4, total: 6, quorum: false},
+		{votes: 5, total: 6, quorum: true},
+		{votes: 7, total: 10, quorum: true},
+	}
+
+	for _, tc := range tt {
+		if got := database.HasQuorum(tc.votes, tc.total); got != tc.quorum {
+			t.Errorf("HasQuorum(%d, %d): expected %v, got %v", tc.votes, tc.total, tc.quorum, got)
+		}
+	}
+}
+
+func TestFinalityCertValidate(t *testing.T) {
+	keys, ids := authorities(t, 4)
+	outsider, _ := authorities(t, 1)
+
+	const number = 5
+	const hash = "0xa"
+
+	precommit := func(key *ecdsa.PrivateKey) database.SignedVote {
+		return signVote(t, key, database.VotePrecommit, number, hash)
+	}
+
+	tampered := precommit(keys[2])
+	tampered.BlockHash = "0xb"
+
+	tt := []struct {
+		name  string
+		votes []database.SignedVote
+		valid bool
+	}{
+		{
+			name:  "quorum",
+			votes: []database.SignedVote{precommit(keys[0]), precommit(keys[1]), precommit(keys[2])},
+			valid: true,
+		},
+		{
+			name:  "all authorities",
+			votes: []database.SignedVote{precommit(keys[0]), precommit(keys[1]), precommit(keys[2]), precommit(keys[3])},
+			valid: true,
+		},
+		{
+			name:  "no quorum",
+			votes: []database.SignedVote{precommit(keys[0]), precommit(keys[1])},
+		},
+		{
+			name:  "voter counted twice",
+			votes: []database.SignedVote{precommit(keys[0]), precommit(keys[1]), precommit(keys[1])},
+		},
+		{
+			name:  "voter not an authority",
+			votes: []database.SignedVote{precommit(keys[0]), precommit(keys[1]), precommit(outsider[0])},
+		},
+		{
+			name:  "prevote",
+			votes: []database.SignedVote{precommit(keys[0]), precommit(keys[1]), signVote(t, keys[2], database.VotePrevote, number, hash)},
+		},
+		{
+			name:  "vote for another block",
+			votes: []database.SignedVote{precommit(keys[0]), precommit(keys[1]), signVote(t, keys[2], database.VotePrecommit, number, "0xb")},
+		},
+		{
+			name:  "vote at another height",
+			votes: []database.SignedVote{precommit(keys[0]), precommit(keys[1]), signVote(t, keys[2], database.VotePrecommit, number+1, hash)},
+		},
+		{
+			name:  "vote changed after signing",
+			votes: []database.SignedVote{precommit(keys[0]), precommit(keys[1]), tampered},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			cert := database.FinalityCert{
+				Number:     number,
+				BlockHash:  hash,
+				Precommits: tc.votes,
+			}
+
+			err := cert.Validate(chainID, ids)
+			if tc.valid && err != nil {
+				t.Fatalf("expected the certificate to be valid: %s", err)
+			}
+			if !tc.valid && err == nil {
+				t.Fatal("expected the certificate to be invalid")
+			}
+		})
+	}
+
+	t.Run("another chain", func(t *testing.T) {
+		cert := database.FinalityCert{
+			Number:     number,
+			BlockHash:  hash,
+			Precommits: []database.SignedVote{precommit(keys[0]), precommit(keys[1]), precommit(keys[2])},
+		}
+
+		if err := cert.Validate(chainID+1, ids); err == nil {
+			t.Fatal("expected the certificate of another chain to be invalid")
+		}
+	})
+}
+
+func TestFinalize(t *testing.T) {
+	storage, err := disk.New(t.TempDir() + "/")
+	if err != nil {
+		t.Fatalf("creating storage: %s", err)
+	}
+
+	db, err := database.New(genesis.Genesis{ChainID: chainID}, storage, func(v string, args ...any) {})
+	if err != nil {
+		t.Fatalf("creating database: %s", err)
+	}
+	defer db.Close()
+
+	block, err := database.NewBlock(database.BlockArgs{PrevBlock: db.LatestBlock()})
+	if err != nil {
+		t.Fatalf("creating block: %s", err)
+	}
+	if err := storage.Write(database.NewBlockData(block)); err != nil {
+		t.Fatalf("writing block: %s", err)
+	}
+
+	if err := db.Finalize(database.FinalityCert{Number: 1, BlockHash: "0xb"}); err == nil {
+		t.Fatal("expected a certificate for another block at the height to be refused")
+	}
+	if db.Finalized().Number != 0 {
+		t.Fatalf("expected the finalized height not to move, got %d", db.Finalized().Number)
+	}
+
+	cert := database.FinalityCert{Number: 1, BlockHash: block.Hash()}
+	if err := db.Finalize(cert); err != nil {
+		t.Fatalf("finalizing: %s", err)
+	}
+
+	if finalized := db.Finalized(); finalized.Number != 1 || finalized.BlockHash != block.Hash() {
+		t.Fatalf("expected blk[1] %s to be final, got blk[%d] %s", block.Hash(), finalized.Number, finalized.BlockHash)
+	}
+
+	stored, err := db.GetBlock(1)
+	if err != nil {
+		t.Fatalf("reading block: %s", err)
+	}
+	if stored.Cert == nil || stored.Cert.BlockHash != block.Hash() {
+		t.Fatal("expected the certificate to be stored with the block")
+	}
+}
+
+// =============================================================================
+
+func authorities(t *testing.T, n int) ([]*ecdsa.PrivateKey, []database.AccountID) {
+	t.Helper()
+
+	keys := make([]*ecdsa.PrivateKey, n)
+	ids := make([]database.AccountID, n)
+	for i := range keys {
+		key, err := crypto.GenerateKey()
+		if err != nil {
+			t.Fatalf("generating key: %s", err)
+		}
+		keys[i] = key
+		ids[i] = database.PublicKeyToAccountID(key.PublicKey)
+	}
+
+	return keys, ids
+}
+
+func signVote(t *testing.T, key *ecdsa.PrivateKey, voteType database.VoteType, number uint64, hash string) database.SignedVote {
+	t.Helper()
+
+	vote := database.Vote{
+		ChainID:   chainID,
+		Type:      voteType,
+		Number:    number,
+		BlockHash: hash,
+		VoterID:   database.PublicKeyToAccountID(key.PublicKey),
+	}
+
+	sv, err := vote.Sign(key)
+	if err != nil {
+		t.Fatalf("signing vote: %s", err)
+	}
+	return sv
+}
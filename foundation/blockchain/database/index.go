@@ -0,0 +1,135 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// ErrTxNotFound is returned when no stored block has the transaction.
+var ErrTxNotFound = errors.New("transaction not found")
+
+// TxLocation is the position of a transaction in the chain.
+type TxLocation struct {
+	BlockNumber uint64
+	Index       int
+}
+
+// index keeps the location of every transaction in the stored blocks so they
+// can be found by hash, account or nonce without reading the chain, along with
+// the reason the ones that failed were not applied. It's rebuilt from storage
+// when the node starts and covers the blocks after a snapshot.
+type index struct {
+	mu       sync.RWMutex
+	txs      map[string]TxLocation
+	accounts map[AccountID][]string
+	nonces   map[string]string
+	failed   map[string]string
+}
+
+func newIndex() *index {
+	return &index{
+		txs:      make(map[string]TxLocation),
+		accounts: make(map[AccountID][]string),
+		nonces:   make(map[string]string),
+		failed:   make(map[string]string),
+	}
+}
+
+// add records the transactions of the block.
+func (idx *index) add(block Block) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	for i, tx := range block.MerkleTree.Values() {
+		hash := tx.HashString()
+		if _, exists := idx.txs[hash]; exists {
+			continue
+		}
+
+		idx.txs[hash] = TxLocation{BlockNumber: block.Header.Number, Index: i}
+
+		// A failed transaction doesn't use up its nonce, so the one that
+		// does later takes its place.
+		key := nonceKey(tx.FromID, tx.Nonce)
+		if _, exists := idx.nonces[key]; !exists || idx.failed[idx.nonces[key]] != "" {
+			idx.nonces[key] = hash
+		}
+
+		idx.accounts[tx.FromID] = append(idx.accounts[tx.FromID], hash)
+		if tx.ToID != tx.FromID {
+			idx.accounts[tx.ToID] = append(idx.accounts[tx.ToID], hash)
+		}
+	}
+}
+
+// fail records the reason the transaction in the block was not applied. A
+// transaction indexed in another block is left alone.
+func (idx *index) fail(block Block, tx BlockTx, err error) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	hash := tx.HashString()
+	if loc, exists := idx.txs[hash]; !exists || loc.BlockNumber != block.Header.Number {
+		return
+	}
+
+	idx.failed[hash] = err.Error()
+}
+
+func nonceKey(accountID AccountID, nonce uint64) string {
+	return fmt.Sprintf("%s:%d", accountID, nonce)
+}
+
+// =============================================================================
+
+// GetTxLocation returns the block and position of the transaction with the
+// specified hash.
+func (db *Database) GetTxLocation(hash string) (TxLocation, error) {
+	db.index.mu.RLock()
+	defer db.index.mu.RUnlock()
+
+	loc, exists := db.index.txs[hash]
+	if !exists {
+		return TxLocation{}, ErrTxNotFound
+	}
+
+	return loc, nil
+}
+
+// GetAccountTxs returns the hashes of the stored transactions sent or received
+// by the account, oldest first.
+func (db *Database) GetAccountTxs(accountID AccountID) []string {
+	db.index.mu.RLock()
+	defer db.index.mu.RUnlock()
+
+	hashes := make([]string, len(db.index.accounts[accountID]))
+	copy(hashes, db.index.accounts[accountID])
+
+	return hashes
+}
+
+// GetTxHashByNonce returns the hash of the stored transaction sent by the
+// account with the specified nonce. A transaction that applied is preferred
+// over one that failed.
+func (db *Database) GetTxHashByNonce(accountID AccountID, nonce uint64) (string, error) {
+	db.index.mu.RLock()
+	defer db.index.mu.RUnlock()
+
+	hash, exists := db.index.nonces[nonceKey(accountID, nonce)]
+	if !exists {
+		return "", ErrTxNotFound
+	}
+
+	return hash, nil
+}
+
+// GetTxFailure returns the reason the stored transaction was not applied, or
+// false when it was applied.
+func (db *Database) GetTxFailure(hash string) (string, bool) {
+	db.index.mu.RLock()
+	defer db.index.mu.RUnlock()
+
+	reason, exists := db.index.failed[hash]
+	return reason, exists
+}
@@ -0,0 +1,24 @@
+package database
+
+// Sandbox returns a database holding a copy of the accounts that transactions
+// can be applied to without changing this one. It has no storage, so it can't
+// read or write blocks.
+func (db *Database) Sandbox() *Database {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	accounts := make(map[AccountID]Account, len(db.accounts))
+	for accountID, account := range db.accounts {
+		accounts[accountID] = account
+	}
+
+	return &Database{
+		genesis:     db.genesis,
+		latestBlock: db.latestBlock,
+		finalized:   db.finalized,
+		authorities: db.authorities,
+		staking:     db.staking,
+		accounts:    accounts,
+		index:       newIndex(),
+	}
+}
@@ -0,0 +1,201 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/ardanlabs/blockchain/foundation/blockchain/signature"
+)
+
+// CORE NOTE: A snapshot is the full account state right after a block was
+// applied. The StateRoot of the next block header is the hash of that state,
+// so a node that trusts the header chain can verify a snapshot received from
+// a peer without executing the blocks before it. Nodes capture a snapshot in
+// memory every snapshot interval blocks to serve to joining nodes. A node that
+// fast synced stores the snapshot instead of the blocks before it.
+
+// SnapshotChunkSize is the number of accounts served per snapshot chunk.
+const SnapshotChunkSize = 1000
+
+// ErrSnapshotNotFound is returned when a snapshot isn't available.
+var ErrSnapshotNotFound = errors.New("snapshot not found")
+
+// Snapshot represents the account state after the block in the header was
+// applied.
+type Snapshot struct {
+	Header   BlockHeader `json:"header"`
+	Accounts []Account   `json:"accounts"`
+}
+
+// SnapshotInfo describes a snapshot a peer can serve.
+type SnapshotInfo struct {
+	Number uint64 `json:"number"`
+	Hash   string `json:"hash"`
+	Chunks int    `json:"chunks"`
+}
+
+// SnapshotChunk is one page of the accounts of a snapshot.
+type SnapshotChunk struct {
+	Number   uint64      `json:"number"`
+	Chunk    int         `json:"chunk"`
+	Header   BlockHeader `json:"header"`
+	Accounts []Account   `json:"accounts"`
+}
+
+// Hash returns the hash of the accounts the same way the state root of a
+// block header is calculated.
+func (s Snapshot) Hash() string {
+	accounts := make([]Account, len(s.Accounts))
+	copy(accounts, s.Accounts)
+	sort.Sort(byAccount(accounts))
+
+	return signature.Hash(accounts)
+}
+
+// Info returns the description of the snapshot.
+func (s Snapshot) Info() SnapshotInfo {
+	return SnapshotInfo{
+		Number: s.Header.Number,
+		Hash:   s.Hash(),
+		Chunks: (len(s.Accounts) + SnapshotChunkSize - 1) / SnapshotChunkSize,
+	}
+}
+
+// Chunk returns the specified page of the accounts.
+func (s Snapshot) Chunk(chunk int) (SnapshotChunk, error) {
+	start := chunk * SnapshotChunkSize
+	if chunk < 0 || start >= len(s.Accounts) {
+		return SnapshotChunk{}, fmt.Errorf("chunk %d out of range", chunk)
+	}
+
+	end := start + SnapshotChunkSize
+	if end > len(s.Accounts) {
+		end = len(s.Accounts)
+	}
+
+	return SnapshotChunk{
+		Number:   s.Header.Number,
+		Chunk:    chunk,
+		Header:   s.Header,
+		Accounts: s.Accounts[start:end],
+	}, nil
+}
+
+// Verify checks the snapshot is the state the next header was built on.
+func (s Snapshot) Verify(next BlockHeader) error {
+	if next.Number != s.Header.Number+1 {
+		return fmt.Errorf("header %d doesn't follow snapshot %d", next.Number, s.Header.Number)
+	}
+
+	block := Block{Header: s.Header}
+	if next.PrevBlockHash != block.Hash() {
+		return errors.New("snapshot header is not the parent of the header")
+	}
+
+	if s.Hash() != next.StateRoot {
+		return ErrInvalidStateRoot
+	}
+
+	return nil
+}
+
+// =============================================================================
+
+// WithSnapshotInterval is used to capture a snapshot of the account state
+// every interval blocks. Zero disables snapshots.
+func WithSnapshotInterval(interval uint64) func(db *Database) {
+	return func(db *Database) {
+		db.snapshotInterval = interval
+	}
+}
+
+// CaptureSnapshot keeps a copy of the account state if the block is at a
+// snapshot interval. It must be called once the block has been fully applied.
+func (db *Database) CaptureSnapshot(block Block) {
+	if db.snapshotInterval == 0 || block.Header.Number%db.snapshotInterval != 0 {
+		return
+	}
+
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	accounts := make([]Account, 0, len(db.accounts))
+	for _, account := range db.accounts {
+		accounts = append(accounts, account)
+	}
+	sort.Sort(byAccount(accounts))
+
+	// The previous snapshot is kept so a download in progress can complete.
+	db.snapshots = append(db.snapshots, Snapshot{Header: block.Header, Accounts: accounts})
+	if len(db.snapshots) > 2 {
+		db.snapshots = db.snapshots[1:]
+	}
+}
+
+// Snapshot returns the captured snapshot at the specified height.
+func (db *Database) Snapshot(number uint64) (Snapshot, error) {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	for _, snapshot := range db.snapshots {
+		if snapshot.Header.Number == number {
+			return snapshot, nil
+		}
+	}
+
+	return Snapshot{}, ErrSnapshotNotFound
+}
+
+// LatestSnapshot returns the most recent captured snapshot.
+func (db *Database) LatestSnapshot() (Snapshot, error) {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	if len(db.snapshots) == 0 {
+		return Snapshot{}, ErrSnapshotNotFound
+	}
+
+	return db.snapshots[len(db.snapshots)-1], nil
+}
+
+// RestoreSnapshot replaces the account state with a verified snapshot and
+// makes the snapshot block the latest block. The snapshot is stored so the
+// state can be rebuilt on restart without the blocks before it.
+func (db *Database) RestoreSnapshot(snapshot Snapshot) error {
+	if err := db.storage.WriteSnapshot(snapshot); err != nil {
+		return err
+	}
+
+	return db.restoreSnapshot(snapshot)
+}
+
+// restoreSnapshot loads the snapshot into memory.
+func (db *Database) restoreSnapshot(snapshot Snapshot) error {
+	block, err := ToBlock(BlockData{Header: snapshot.Header})
+	if err != nil {
+		return err
+	}
+
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	db.accounts = make(map[AccountID]Account, len(snapshot.Accounts))
+	for _, account := range snapshot.Accounts {
+		db.accounts[account.AccountID] = account
+	}
+
+	db.latestBlock = block
+	db.pruned = snapshot.Header.Number
+
+	return nil
+}
+
+// Pruned returns the number of the snapshot block the node was restored
+// from. The blocks up to it are not stored. Zero means every block is stored.
+func (db *Database) Pruned() uint64 {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	return db.pruned
+}
@@ -0,0 +1,51 @@
+package database_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/ardanlabs/blockchain/foundation/blockchain/database"
+	"github.com/ardanlabs/blockchain/foundation/blockchain/genesis"
+	"github.com/ardanlabs/blockchain/foundation/blockchain/storage/disk"
+)
+
+func TestRestoreSnapshotPrunes(t *testing.T) {
+	storage, err := disk.New(t.TempDir() + "/")
+	if err != nil {
+		t.Fatalf("creating storage: %s", err)
+	}
+
+	gen := genesis.Genesis{ChainID: chainID}
+	ev := func(v string, args ...any) {}
+
+	db, err := database.New(gen, storage, ev)
+	if err != nil {
+		t.Fatalf("creating database: %s", err)
+	}
+
+	if err := db.RestoreSnapshot(database.Snapshot{Header: database.BlockHeader{Number: 3}}); err != nil {
+		t.Fatalf("restoring snapshot: %s", err)
+	}
+	db.Close()
+
+	// The pruned height must survive a restart.
+	db, err = database.New(gen, storage, ev)
+	if err != nil {
+		t.Fatalf("reopening database: %s", err)
+	}
+	defer db.Close()
+
+	if pruned := db.Pruned(); pruned != 3 {
+		t.Fatalf("expected the blocks up to blk[3] to be pruned, got %d", pruned)
+	}
+
+	for _, number := range []uint64{1, 3} {
+		if _, err := db.GetBlock(number); !errors.Is(err, database.ErrBlockPruned) {
+			t.Fatalf("blk[%d]: expected ErrBlockPruned, got %v", number, err)
+		}
+	}
+
+	if _, err := db.GetBlock(4); errors.Is(err, database.ErrBlockPruned) {
+		t.Fatal("expected the block after the snapshot not to be reported as pruned")
+	}
+}
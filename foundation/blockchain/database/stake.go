@@ -0,0 +1,222 @@
+package database
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// CORE NOTE: Under PoS an account locks part of its balance as stake by sending
+// a transaction to the staking account. The staking account never holds a
+// balance, the transaction data tells the database what to do with the value.
+// A proposer that signs two different blocks at the same height can be
+// reported with a slash transaction carrying both signed headers as evidence.
+// The evidence is verified by every node when the transaction is applied and
+// the offender loses its full stake. The stake only earns the right to propose
+// blocks: the proposer selected for a slot is paid the mining reward and the
+// tips of its block like a miner, and nothing is shared with the other stakers.
+//
+// Unstaking doesn't return the stake right away. It stays unbonding for a
+// number of blocks during which it no longer selects the account as proposer
+// but can still be slashed, so a proposer can't withdraw before the evidence
+// of its double sign is included. The height of every double sign slashed is
+// recorded in the account of the offender so the same evidence can't be
+// replayed to wipe stake bonded later.
+
+// StakingAccountID is the well known account staking transactions are sent to.
+const StakingAccountID AccountID = "0x0000000000000000000000000000005374616b65"
+
+// UnbondingBlocks is the number of blocks unstaked value stays slashable
+// before it returns to the balance.
+const UnbondingBlocks = 50
+
+// ErrStakingDisabled is returned for staking transactions outside of PoS.
+var ErrStakingDisabled = errors.New("staking is only supported under PoS")
+
+// ErrEvidenceProcessed is returned when the double sign of the evidence has
+// already been slashed.
+var ErrEvidenceProcessed = errors.New("double sign evidence already processed")
+
+type StakeOp string
+
+const (
+	StakeOpStake   StakeOp = "stake"
+	StakeOpUnstake StakeOp = "unstake"
+	StakeOpSlash   StakeOp = "slash"
+)
+
+// StakeData is carried in the data field of a staking transaction.
+type StakeData struct {
+	Op       StakeOp             `json:"op"`
+	Evidence *DoubleSignEvidence `json:"evidence,omitempty"`
+}
+
+// Encode marshals the stake data for use as transaction data.
+func (sd StakeData) Encode() ([]byte, error) {
+	return json.Marshal(sd)
+}
+
+// DoubleSignEvidence holds two signed block headers for the same height by the
+// same proposer.
+type DoubleSignEvidence struct {
+	HeaderA BlockHeader `json:"header_a"`
+	SigA    string      `json:"sig_a"`
+	HeaderB BlockHeader `json:"header_b"`
+	SigB    string      `json:"sig_b"`
+}
+
+// NewDoubleSignEvidence constructs evidence from two conflicting blocks.
+func NewDoubleSignEvidence(a Block, b Block) DoubleSignEvidence {
+	return DoubleSignEvidence{
+		HeaderA: a.Header,
+		SigA:    a.Sig,
+		HeaderB: b.Header,
+		SigB:    b.Sig,
+	}
+}
+
+// Offender validates the evidence and returns the account that double signed.
+func (e DoubleSignEvidence) Offender() (AccountID, error) {
+	if e.HeaderA.Number != e.HeaderB.Number {
+		return "", errors.New("evidence headers are at different heights")
+	}
+
+	if e.HeaderA.BeneficiaryID != e.HeaderB.BeneficiaryID {
+		return "", errors.New("evidence headers have different proposers")
+	}
+
+	blockA := Block{Header: e.HeaderA, Sig: e.SigA}
+	blockB := Block{Header: e.HeaderB, Sig: e.SigB}
+	if blockA.Hash() == blockB.Hash() {
+		return "", errors.New("evidence headers are the same block")
+	}
+
+	for _, block := range []Block{blockA, blockB} {
+		if err := block.VerifySignature(); err != nil {
+			return "", err
+		}
+	}
+
+	return e.HeaderA.BeneficiaryID, nil
+}
+
+// =============================================================================
+
+// applyStakeTx performs the staking operation described by the transaction
+// data once the gas has been charged. The database lock must be held by the
+// caller.
+func (db *Database) applyStakeTx(block Block, tx BlockTx, from Account) error {
+	var sd StakeData
+	if err := json.Unmarshal(tx.Data, &sd); err != nil {
+		return fmt.Errorf("invalid stake data: %w", err)
+	}
+
+	if from.Balance < tx.Tip {
+		return errors.New("insufficient funds")
+	}
+
+	switch sd.Op {
+	case StakeOpStake:
+		if from.Balance < tx.Value+tx.Tip {
+			return errors.New("insufficient funds")
+		}
+		from.Balance -= tx.Value
+		from.Stake += tx.Value
+
+	case StakeOpUnstake:
+		if from.Stake < tx.Value {
+			return errors.New("insufficient stake")
+		}
+		from.Stake -= tx.Value
+		from.Unbonding += tx.Value
+		from.UnbondAt = block.Header.Number + UnbondingBlocks
+
+	case StakeOpSlash:
+		if sd.Evidence == nil {
+			return errors.New("slash requires evidence")
+		}
+
+		offenderID, err := sd.Evidence.Offender()
+		if err != nil {
+			return err
+		}
+
+		offender := from
+		if offenderID != from.AccountID {
+			var exists bool
+			if offender, exists = db.accounts[offenderID]; !exists {
+				offender = newAccount(offenderID, 0)
+			}
+		}
+
+		number := sd.Evidence.HeaderA.Number
+		if offender.IsSlashed(number) {
+			return ErrEvidenceProcessed
+		}
+
+		// The slashed stake is burned, including the stake still unbonding.
+		offender.Stake = 0
+		offender.Unbonding = 0
+		offender.UnbondAt = 0
+		offender.Slashed = append(append([]uint64{}, offender.Slashed...), number)
+
+		if offenderID == from.AccountID {
+			from = offender
+			break
+		}
+		db.accounts[offenderID] = offender
+
+	default:
+		return fmt.Errorf("unknown stake op %q", sd.Op)
+	}
+
+	from.Balance -= tx.Tip
+	from.Nonce = tx.Nonce
+	db.accounts[tx.FromID] = from
+
+	// The beneficiary is read back since it can be the sender or the offender.
+	bnfc, exists := db.accounts[block.Header.BeneficiaryID]
+	if !exists {
+		bnfc = newAccount(block.Header.BeneficiaryID, 0)
+	}
+	bnfc.Balance += tx.Tip
+	db.accounts[block.Header.BeneficiaryID] = bnfc
+
+	return nil
+}
+
+// ReleaseUnbonded returns the unbonding stake that is no longer slashable to
+// the balance of its account once the block is applied.
+func (db *Database) ReleaseUnbonded(block Block) {
+	if !db.staking {
+		return
+	}
+
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	for accountID, account := range db.accounts {
+		if account.Unbonding == 0 || block.Header.Number < account.UnbondAt {
+			continue
+		}
+
+		account.Balance += account.Unbonding
+		account.Unbonding = 0
+		account.UnbondAt = 0
+		db.accounts[accountID] = account
+	}
+}
+
+// Stakes returns the accounts that currently have stake locked.
+func (db *Database) Stakes() map[AccountID]uint64 {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	stakes := make(map[AccountID]uint64)
+	for accountID, account := range db.accounts {
+		if account.Stake > 0 {
+			stakes[accountID] = account.Stake
+		}
+	}
+	return stakes
+}
@@ -0,0 +1,183 @@
+package database_test
+
+import (
+	"crypto/ecdsa"
+	"errors"
+	"testing"
+
+	"github.com/ardanlabs/blockchain/foundation/blockchain/database"
+	"github.com/ardanlabs/blockchain/foundation/blockchain/genesis"
+	"github.com/ardanlabs/blockchain/foundation/blockchain/storage/disk"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+func TestSlashReplay(t *testing.T) {
+	proposer, reporter := newKey(t), newKey(t)
+	db := newStakingDB(t, proposer, reporter)
+	offenderID := database.PublicKeyToAccountID(proposer.PublicKey)
+
+	evidence := doubleSign(t, proposer, 3)
+
+	applyStakeTx(t, db, reporter, 1, 0, database.StakeData{Op: database.StakeOpSlash, Evidence: &evidence})
+
+	offender, _ := db.GetAccount(offenderID)
+	if offender.Stake != 0 || !offender.IsSlashed(3) {
+		t.Fatalf("expected the offender to be slashed at blk[3], got %+v", offender)
+	}
+
+	// The offender bonds new stake and the old evidence is sent again.
+	applyStakeTx(t, db, proposer, 1, 500, database.StakeData{Op: database.StakeOpStake})
+
+	err := applyTx(t, db, reporter, 2, 0, database.StakeData{Op: database.StakeOpSlash, Evidence: &evidence})
+	if !errors.Is(err, database.ErrEvidenceProcessed) {
+		t.Fatalf("expected the replayed evidence to be refused, got %v", err)
+	}
+
+	if offender, _ := db.GetAccount(offenderID); offender.Stake != 500 {
+		t.Fatalf("expected the new stake to be kept, got %d", offender.Stake)
+	}
+}
+
+func TestUnbondingSlashable(t *testing.T) {
+	proposer, reporter := newKey(t), newKey(t)
+	db := newStakingDB(t, proposer, reporter)
+	offenderID := database.PublicKeyToAccountID(proposer.PublicKey)
+
+	before, _ := db.GetAccount(offenderID)
+
+	applyStakeTx(t, db, proposer, 1, 400, database.StakeData{Op: database.StakeOpUnstake})
+
+	account, _ := db.GetAccount(offenderID)
+	if account.Stake != 600 || account.Unbonding != 400 {
+		t.Fatalf("expected 600 staked and 400 unbonding, got %+v", account)
+	}
+	if account.Balance != before.Balance {
+		t.Fatalf("expected the unstaked value not to return to the balance yet, got %d", account.Balance)
+	}
+
+	// The double sign is reported before the stake is released.
+	evidence := doubleSign(t, proposer, 1)
+	applyStakeTx(t, db, reporter, 1, 0, database.StakeData{Op: database.StakeOpSlash, Evidence: &evidence})
+
+	db.ReleaseUnbonded(blockAt(database.UnbondingBlocks + 1))
+
+	account, _ = db.GetAccount(offenderID)
+	if account.Stake != 0 || account.Unbonding != 0 || account.Balance != before.Balance {
+		t.Fatalf("expected the unbonding stake to be slashed, got %+v", account)
+	}
+}
+
+func TestUnbondingReleased(t *testing.T) {
+	proposer, reporter := newKey(t), newKey(t)
+	db := newStakingDB(t, proposer, reporter)
+	accountID := database.PublicKeyToAccountID(proposer.PublicKey)
+
+	before, _ := db.GetAccount(accountID)
+
+	applyStakeTx(t, db, proposer, 1, 400, database.StakeData{Op: database.StakeOpUnstake})
+
+	db.ReleaseUnbonded(blockAt(database.UnbondingBlocks))
+	if account, _ := db.GetAccount(accountID); account.Unbonding != 400 {
+		t.Fatalf("expected the stake to be unbonding until blk[%d], got %+v", database.UnbondingBlocks+1, account)
+	}
+
+	db.ReleaseUnbonded(blockAt(database.UnbondingBlocks + 1))
+
+	account, _ := db.GetAccount(accountID)
+	if account.Unbonding != 0 || account.Balance != before.Balance+400 {
+		t.Fatalf("expected the unbonded stake to return to the balance, got %+v", account)
+	}
+}
+
+// =============================================================================
+
+func newStakingDB(t *testing.T, proposer *ecdsa.PrivateKey, reporter *ecdsa.PrivateKey) *database.Database {
+	t.Helper()
+
+	storage, err := disk.New(t.TempDir() + "/")
+	if err != nil {
+		t.Fatalf("creating storage: %s", err)
+	}
+
+	proposerID := string(database.PublicKeyToAccountID(proposer.PublicKey))
+	reporterID := string(database.PublicKeyToAccountID(reporter.PublicKey))
+
+	gen := genesis.Genesis{
+		ChainID:  chainID,
+		Balances: map[string]uint64{proposerID: 1_000, reporterID: 1_000},
+		Stakes:   map[string]uint64{proposerID: 1_000},
+	}
+
+	db, err := database.New(gen, storage, func(v string, args ...any) {}, database.WithStaking())
+	if err != nil {
+		t.Fatalf("creating database: %s", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	return db
+}
+
+// doubleSign returns the evidence of two blocks signed by the key at the
+// same height.
+func doubleSign(t *testing.T, key *ecdsa.PrivateKey, number uint64) database.DoubleSignEvidence {
+	t.Helper()
+
+	blocks := make([]database.Block, 2)
+	for i := range blocks {
+		blocks[i] = blockAt(number)
+		blocks[i].Header.BeneficiaryID = database.PublicKeyToAccountID(key.PublicKey)
+		blocks[i].Header.Nonce = uint64(i)
+
+		if err := blocks[i].Sign(key); err != nil {
+			t.Fatalf("signing block: %s", err)
+		}
+	}
+
+	return database.NewDoubleSignEvidence(blocks[0], blocks[1])
+}
+
+func blockAt(number uint64) database.Block {
+	return database.Block{Header: database.BlockHeader{Number: number}}
+}
+
+// applyTx applies a staking transaction from the key in a block at height 1.
+// The transaction is free of gas so only the staking changes the balances.
+func applyTx(t *testing.T, db *database.Database, key *ecdsa.PrivateKey, nonce uint64, value uint64, sd database.StakeData) error {
+	t.Helper()
+
+	data, err := sd.Encode()
+	if err != nil {
+		t.Fatalf("encoding stake data: %s", err)
+	}
+
+	fromID := database.PublicKeyToAccountID(key.PublicKey)
+	tx, err := database.NewTx(chainID, nonce, fromID, database.StakingAccountID, value, 0, data)
+	if err != nil {
+		t.Fatalf("constructing tx: %s", err)
+	}
+
+	signedTx, err := tx.Sign(key)
+	if err != nil {
+		t.Fatalf("signing tx: %s", err)
+	}
+
+	return db.ApplyTransaction(blockAt(1), database.NewBlockTx(signedTx, 0, 0))
+}
+
+func applyStakeTx(t *testing.T, db *database.Database, key *ecdsa.PrivateKey, nonce uint64, value uint64, sd database.StakeData) {
+	t.Helper()
+
+	if err := applyTx(t, db, key, nonce, value, sd); err != nil {
+		t.Fatalf("applying %s tx: %s", sd.Op, err)
+	}
+}
+
+func newKey(t *testing.T) *ecdsa.PrivateKey {
+	t.Helper()
+
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatalf("generating key: %s", err)
+	}
+	return key
+}
@@ -133,6 +133,11 @@ func (tx BlockTx) Hash() ([]byte, error) {
 	return hex.DecodeString(str[2:])
 }
 
+// HashString returns the hash identifying the transaction on the network.
+func (tx BlockTx) HashString() string {
+	return signature.Hash(tx)
+}
+
 // Equals returns true if the two transactions are equal. If the nonce and the signatures
 // are equal, then the transactions are equal.
 func (tx BlockTx) Equals(otherTx BlockTx) bool {
@@ -0,0 +1,122 @@
+// Package finality collects the prevotes and precommits cast by the authority
+// set and reports when a block has reached a quorum.
+package finality
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/ardanlabs/blockchain/foundation/blockchain/database"
+)
+
+// ErrEquivocation is returned when an authority votes for two different blocks
+// at the same height and step.
+var ErrEquivocation = errors.New("authority voted for conflicting blocks")
+
+// step identifies the set of votes of one type at one height.
+type step struct {
+	number   uint64
+	voteType database.VoteType
+}
+
+// Votes maintains the votes received per height and vote type.
+type Votes struct {
+	mu          sync.RWMutex
+	authorities map[database.AccountID]struct{}
+	votes       map[step]map[database.AccountID]database.SignedVote
+}
+
+// New constructs a vote set for the specified authorities.
+func New(authorities []database.AccountID) *Votes {
+	set := make(map[database.AccountID]struct{}, len(authorities))
+	for _, id := range authorities {
+		set[id] = struct{}{}
+	}
+
+	return &Votes{
+		authorities: set,
+		votes:       make(map[step]map[database.AccountID]database.SignedVote),
+	}
+}
+
+// Enabled reports if there is an authority set to vote.
+func (v *Votes) Enabled() bool {
+	return len(v.authorities) > 0
+}
+
+// IsAuthority reports if the account is part of the authority set.
+func (v *Votes) IsAuthority(accountID database.AccountID) bool {
+	_, exists := v.authorities[accountID]
+	return exists
+}
+
+// Add records the vote. It returns false if the vote was already known.
+func (v *Votes) Add(vote database.SignedVote) (bool, error) {
+	if !v.IsAuthority(vote.VoterID) {
+		return false, fmt.Errorf("voter %s is not an authority", vote.VoterID)
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	key := step{number: vote.Number, voteType: vote.Type}
+	voters, exists := v.votes[key]
+	if !exists {
+		voters = make(map[database.AccountID]database.SignedVote)
+		v.votes[key] = voters
+	}
+
+	if existing, exists := voters[vote.VoterID]; exists {
+		if existing.BlockHash != vote.BlockHash {
+			return false, ErrEquivocation
+		}
+		return false, nil
+	}
+
+	voters[vote.VoterID] = vote
+
+	return true, nil
+}
+
+// HasVoted reports if the authority already cast a vote of this type at the
+// specified height.
+func (v *Votes) HasVoted(accountID database.AccountID, number uint64, voteType database.VoteType) bool {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+
+	_, exists := v.votes[step{number: number, voteType: voteType}][accountID]
+	return exists
+}
+
+// Quorum returns the block hash and the votes backing it when more than 2/3
+// of the authorities cast the same vote at the specified height.
+func (v *Votes) Quorum(number uint64, voteType database.VoteType) (string, []database.SignedVote, bool) {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+
+	byHash := make(map[string][]database.SignedVote)
+	for _, vote := range v.votes[step{number: number, voteType: voteType}] {
+		byHash[vote.BlockHash] = append(byHash[vote.BlockHash], vote)
+	}
+
+	for hash, votes := range byHash {
+		if database.HasQuorum(len(votes), len(v.authorities)) {
+			return hash, votes, true
+		}
+	}
+
+	return "", nil, false
+}
+
+// Prune removes the votes at or below the specified height.
+func (v *Votes) Prune(number uint64) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	for key := range v.votes {
+		if key.number <= number {
+			delete(v.votes, key)
+		}
+	}
+}
@@ -0,0 +1,159 @@
+package finality_test
+
+import (
+	"crypto/ecdsa"
+	"errors"
+	"testing"
+
+	"github.com/ardanlabs/blockchain/foundation/blockchain/database"
+	"github.com/ardanlabs/blockchain/foundation/blockchain/finality"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+const chainID = 1
+
+func TestQuorum(t *testing.T) {
+	keys, ids := authorities(t, 4)
+	votes := finality.New(ids)
+
+	// With 4 authorities a quorum needs 3 votes for the same block.
+	add(t, votes, sign(t, keys[0], database.VotePrecommit, 1, "0xa"))
+	add(t, votes, sign(t, keys[1], database.VotePrecommit, 1, "0xa"))
+	add(t, votes, sign(t, keys[2], database.VotePrecommit, 1, "0xb"))
+
+	if _, _, ok := votes.Quorum(1, database.VotePrecommit); ok {
+		t.Fatal("expected no quorum with votes split between two blocks")
+	}
+
+	add(t, votes, sign(t, keys[3], database.VotePrecommit, 1, "0xa"))
+
+	hash, backing, ok := votes.Quorum(1, database.VotePrecommit)
+	if !ok {
+		t.Fatal("expected a quorum with 3 of 4 votes")
+	}
+	if hash != "0xa" || len(backing) != 3 {
+		t.Fatalf("expected 3 votes for 0xa, got %d votes for %s", len(backing), hash)
+	}
+
+	if _, _, ok := votes.Quorum(1, database.VotePrevote); ok {
+		t.Fatal("expected the precommits not to count as prevotes")
+	}
+	if _, _, ok := votes.Quorum(2, database.VotePrecommit); ok {
+		t.Fatal("expected the votes not to count at another height")
+	}
+}
+
+func TestDuplicateVote(t *testing.T) {
+	keys, ids := authorities(t, 4)
+	votes := finality.New(ids)
+
+	vote := sign(t, keys[0], database.VotePrevote, 1, "0xa")
+	add(t, votes, vote)
+
+	added, err := votes.Add(vote)
+	if err != nil {
+		t.Fatalf("adding the vote again: %s", err)
+	}
+	if added {
+		t.Fatal("expected the same vote not to be added twice")
+	}
+
+	if _, _, ok := votes.Quorum(1, database.VotePrevote); ok {
+		t.Fatal("expected a repeated vote not to count towards the quorum")
+	}
+}
+
+func TestEquivocation(t *testing.T) {
+	keys, ids := authorities(t, 4)
+	votes := finality.New(ids)
+
+	add(t, votes, sign(t, keys[0], database.VotePrevote, 1, "0xa"))
+
+	added, err := votes.Add(sign(t, keys[0], database.VotePrevote, 1, "0xb"))
+	if !errors.Is(err, finality.ErrEquivocation) {
+		t.Fatalf("expected an equivocation, got %v", err)
+	}
+	if added {
+		t.Fatal("expected the conflicting vote not to be added")
+	}
+
+	// A vote for another block at another step is not an equivocation.
+	add(t, votes, sign(t, keys[0], database.VotePrecommit, 1, "0xb"))
+	add(t, votes, sign(t, keys[0], database.VotePrevote, 2, "0xb"))
+}
+
+func TestNotAuthority(t *testing.T) {
+	_, ids := authorities(t, 4)
+	votes := finality.New(ids)
+
+	key, _ := authorities(t, 1)
+	if _, err := votes.Add(sign(t, key[0], database.VotePrevote, 1, "0xa")); err == nil {
+		t.Fatal("expected a vote from an account outside the authority set to be rejected")
+	}
+}
+
+func TestPrune(t *testing.T) {
+	keys, ids := authorities(t, 1)
+	votes := finality.New(ids)
+
+	add(t, votes, sign(t, keys[0], database.VotePrevote, 1, "0xa"))
+	add(t, votes, sign(t, keys[0], database.VotePrevote, 2, "0xb"))
+
+	votes.Prune(1)
+
+	if votes.HasVoted(ids[0], 1, database.VotePrevote) {
+		t.Fatal("expected the votes at the pruned height to be removed")
+	}
+	if !votes.HasVoted(ids[0], 2, database.VotePrevote) {
+		t.Fatal("expected the votes above the pruned height to be kept")
+	}
+}
+
+// =============================================================================
+
+func authorities(t *testing.T, n int) ([]*ecdsa.PrivateKey, []database.AccountID) {
+	t.Helper()
+
+	keys := make([]*ecdsa.PrivateKey, n)
+	ids := make([]database.AccountID, n)
+	for i := range keys {
+		key, err := crypto.GenerateKey()
+		if err != nil {
+			t.Fatalf("generating key: %s", err)
+		}
+		keys[i] = key
+		ids[i] = database.PublicKeyToAccountID(key.PublicKey)
+	}
+
+	return keys, ids
+}
+
+func sign(t *testing.T, key *ecdsa.PrivateKey, voteType database.VoteType, number uint64, hash string) database.SignedVote {
+	t.Helper()
+
+	vote := database.Vote{
+		ChainID:   chainID,
+		Type:      voteType,
+		Number:    number,
+		BlockHash: hash,
+		VoterID:   database.PublicKeyToAccountID(key.PublicKey),
+	}
+
+	sv, err := vote.Sign(key)
+	if err != nil {
+		t.Fatalf("signing vote: %s", err)
+	}
+	return sv
+}
+
+func add(t *testing.T, votes *finality.Votes, vote database.SignedVote) {
+	t.Helper()
+
+	added, err := votes.Add(vote)
+	if err != nil {
+		t.Fatalf("adding vote %s: %s", vote, err)
+	}
+	if !added {
+		t.Fatalf("expected vote %s to be added", vote)
+	}
+}
@@ -9,11 +9,14 @@ import (
 type Genesis struct {
 	Date          time.Time         `json:"date"`
 	ChainID       uint16            `json:"chain_id"`
+	Consensus     string            `json:"consensus"` // PoW, PoA or PoS, defaults to PoW.
 	TransPerBlock uint16            `json:"trans_per_block"`
 	Difficulty    uint16            `json:"difficulty"`
 	MiningReward  uint64            `json:"mining_reward"`
 	GasPrice      uint16            `json:"gas_price"`
 	Balances      map[string]uint64 `json:"balances"`
+	Stakes        map[string]uint64 `json:"stakes"`      // Stake locked by accounts at genesis under PoS.
+	Authorities   []string          `json:"authorities"` // Accounts allowed to produce blocks and vote on their finality under PoA.
 }
 
 func Load() (Genesis, error) {
@@ -0,0 +1,104 @@
+// Package gossip tracks the transactions and blocks recently seen by the node
+// so they are relayed to each peer once and never bounce back and forth.
+package gossip
+
+import (
+	"container/list"
+	"sync"
+)
+
+// CORE NOTE: Nodes relay the transactions and blocks they accept to their own
+// peers, so the network doesn't need every node connected to every other node.
+// Without a memory of what was already handled, two nodes would keep sending
+// the same block to each other forever. The cache remembers the hash of the
+// recent messages and which peers are known to have them. It's bounded, the
+// least recently seen hash is dropped when it's full.
+
+// Seen is a bounded LRU of the hashes of recently seen messages.
+type Seen struct {
+	mu    sync.Mutex
+	size  int
+	order *list.List
+	items map[string]*list.Element
+}
+
+type entry struct {
+	hash  string
+	hosts map[string]struct{}
+}
+
+// New constructs a cache holding up to size hashes.
+func New(size int) *Seen {
+	if size <= 0 {
+		size = 1
+	}
+
+	return &Seen{
+		size:  size,
+		order: list.New(),
+		items: make(map[string]*list.Element),
+	}
+}
+
+// Add records the message is known by the host. An empty host stands for
+// this node. It returns true if the hash wasn't seen before.
+func (s *Seen) Add(hash string, host string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if elem, exists := s.items[hash]; exists {
+		s.order.MoveToFront(elem)
+		if host != "" {
+			elem.Value.(*entry).hosts[host] = struct{}{}
+		}
+		return false
+	}
+
+	e := entry{
+		hash:  hash,
+		hosts: make(map[string]struct{}),
+	}
+	if host != "" {
+		e.hosts[host] = struct{}{}
+	}
+	s.items[hash] = s.order.PushFront(&e)
+
+	if s.order.Len() > s.size {
+		oldest := s.order.Back()
+		s.order.Remove(oldest)
+		delete(s.items, oldest.Value.(*entry).hash)
+	}
+
+	return true
+}
+
+// Has reports if the hash was seen.
+func (s *Seen) Has(hash string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, exists := s.items[hash]
+	return exists
+}
+
+// KnownBy reports if the host is known to have the message.
+func (s *Seen) KnownBy(hash string, host string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	elem, exists := s.items[hash]
+	if !exists {
+		return false
+	}
+
+	_, known := elem.Value.(*entry).hosts[host]
+	return known
+}
+
+// Len returns the number of hashes in the cache.
+func (s *Seen) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.order.Len()
+}
@@ -0,0 +1,100 @@
+package gossip_test
+
+import (
+	"testing"
+
+	"github.com/ardanlabs/blockchain/foundation/blockchain/gossip"
+)
+
+func TestAdd(t *testing.T) {
+	seen := gossip.New(2)
+
+	if !seen.Add("0xa", "") {
+		t.Fatal("expected a new hash to be reported as new")
+	}
+	if seen.Add("0xa", "") {
+		t.Fatal("expected a hash seen before not to be reported as new")
+	}
+
+	if !seen.Has("0xa") || seen.Has("0xb") {
+		t.Fatal("expected only the added hash to be seen")
+	}
+	if seen.Len() != 1 {
+		t.Fatalf("expected one hash, got %d", seen.Len())
+	}
+}
+
+func TestKnownBy(t *testing.T) {
+	seen := gossip.New(10)
+
+	seen.Add("0xa", "")
+	if seen.KnownBy("0xa", "") {
+		t.Fatal("expected this node not to be recorded as a peer")
+	}
+
+	seen.Add("0xa", "peer1")
+	seen.Add("0xa", "peer2")
+
+	tt := []struct {
+		hash  string
+		host  string
+		known bool
+	}{
+		{hash: "0xa", host: "peer1", known: true},
+		{hash: "0xa", host: "peer2", known: true},
+		{hash: "0xa", host: "peer3", known: false},
+		{hash: "0xb", host: "peer1", known: false},
+	}
+
+	for _, tc := range tt {
+		if got := seen.KnownBy(tc.hash, tc.host); got != tc.known {
+			t.Errorf("KnownBy(%s, %s): expected %v, got %v", tc.hash, tc.host, tc.known, got)
+		}
+	}
+}
+
+func TestEviction(t *testing.T) {
+	seen := gossip.New(3)
+
+	seen.Add("0xa", "peer1")
+	seen.Add("0xb", "peer1")
+	seen.Add("0xc", "peer1")
+
+	// Seeing the oldest hash again makes 0xb the least recently seen.
+	seen.Add("0xa", "peer2")
+	seen.Add("0xd", "peer1")
+
+	if seen.Len() != 3 {
+		t.Fatalf("expected the cache to stay at 3 hashes, got %d", seen.Len())
+	}
+
+	if seen.Has("0xb") || seen.KnownBy("0xb", "peer1") {
+		t.Fatal("expected the least recently seen hash to be evicted")
+	}
+
+	for _, hash := range []string{"0xa", "0xc", "0xd"} {
+		if !seen.Has(hash) {
+			t.Fatalf("expected %s to be kept", hash)
+		}
+	}
+
+	if !seen.KnownBy("0xa", "peer1") || !seen.KnownBy("0xa", "peer2") {
+		t.Fatal("expected the peers of a kept hash to be kept")
+	}
+
+	// An evicted hash is new again.
+	if !seen.Add("0xb", "peer1") {
+		t.Fatal("expected an evicted hash to be reported as new")
+	}
+}
+
+func TestMinimumSize(t *testing.T) {
+	seen := gossip.New(0)
+
+	seen.Add("0xa", "")
+	seen.Add("0xb", "")
+
+	if seen.Len() != 1 || !seen.Has("0xb") {
+		t.Fatal("expected a cache without a size to hold the latest hash")
+	}
+}
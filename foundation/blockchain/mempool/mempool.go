@@ -13,6 +13,7 @@ import (
 type Mempool struct {
 	mu       sync.RWMutex
 	pool     map[string]database.BlockTx
+	strategy string
 	selectFn selector.Func
 }
 
@@ -29,12 +30,37 @@ func NewWithStrategy(strategy string) (*Mempool, error) {
 
 	mp := Mempool{
 		pool:     make(map[string]database.BlockTx),
+		strategy: strategy,
 		selectFn: selectFn,
 	}
 
 	return &mp, nil
 }
 
+// Strategy returns the name of the strategy selecting the transactions.
+func (mp *Mempool) Strategy() string {
+	mp.mu.RLock()
+	defer mp.mu.RUnlock()
+
+	return mp.strategy
+}
+
+// SetStrategy changes the strategy selecting the transactions.
+func (mp *Mempool) SetStrategy(strategy string) error {
+	selectFn, err := selector.Retrieve(strategy)
+	if err != nil {
+		return err
+	}
+
+	mp.mu.Lock()
+	defer mp.mu.Unlock()
+
+	mp.strategy = strategy
+	mp.selectFn = selectFn
+
+	return nil
+}
+
 func (mp *Mempool) Count() int {
 	mp.mu.RLock()
 	defer mp.mu.RUnlock()
@@ -42,7 +68,9 @@ func (mp *Mempool) Count() int {
 	return len(mp.pool)
 }
 
-func (mp *Mempool) Upsert(tx database.BlockTx) error {
+// Upsert adds the transaction to the mempool and returns the transaction from
+// the same account with the same nonce it replaced, if any.
+func (mp *Mempool) Upsert(tx database.BlockTx) (database.BlockTx, bool, error) {
 	mp.mu.Lock()
 	defer mp.mu.Unlock()
 
@@ -54,7 +82,7 @@ func (mp *Mempool) Upsert(tx database.BlockTx) error {
 
 	key, err := mapKey(tx)
 	if err != nil {
-		return err
+		return database.BlockTx{}, false, err
 	}
 
 	// Ethereum uses a nonce to prevent replay attacks. If the nonce is not the next one in the sequence
@@ -66,15 +94,16 @@ func (mp *Mempool) Upsert(tx database.BlockTx) error {
 	// Ethereum requires a bump of at least 10% for the gas price
 	// to replace a transaction in the mempool.
 
-	if etx, exists := mp.pool[key]; exists {
+	etx, exists := mp.pool[key]
+	if exists {
 		if tx.Tip < uint64(math.Round(float64(etx.Tip)*1.10)) {
-			return fmt.Errorf("replacing a transaction requires a 10%% bump in the tip")
+			return database.BlockTx{}, false, fmt.Errorf("replacing a transaction requires a 10%% bump in the tip")
 		}
 	}
 
 	mp.pool[key] = tx
 
-	return nil
+	return etx, exists, nil
 }
 
 func (mp *Mempool) Delete(tx database.BlockTx) error {
@@ -91,6 +120,44 @@ func (mp *Mempool) Delete(tx database.BlockTx) error {
 	return nil
 }
 
+// Remove deletes the transaction from the same account with the same nonce
+// and returns the one that was in the mempool.
+func (mp *Mempool) Remove(tx database.BlockTx) (database.BlockTx, bool) {
+	mp.mu.Lock()
+	defer mp.mu.Unlock()
+
+	key, err := mapKey(tx)
+	if err != nil {
+		return database.BlockTx{}, false
+	}
+
+	etx, exists := mp.pool[key]
+	if !exists {
+		return database.BlockTx{}, false
+	}
+
+	delete(mp.pool, key)
+
+	return etx, true
+}
+
+// RemoveIf deletes the transactions the function reports true for and returns
+// them.
+func (mp *Mempool) RemoveIf(fn func(tx database.BlockTx) bool) []database.BlockTx {
+	mp.mu.Lock()
+	defer mp.mu.Unlock()
+
+	var removed []database.BlockTx
+	for key, tx := range mp.pool {
+		if fn(tx) {
+			delete(mp.pool, key)
+			removed = append(removed, tx)
+		}
+	}
+
+	return removed
+}
+
 func (mp *Mempool) Truncate() error {
 	mp.mu.Lock()
 	defer mp.mu.Unlock()
@@ -100,6 +167,28 @@ func (mp *Mempool) Truncate() error {
 	return nil
 }
 
+// Lookup returns the transactions in the mempool with the specified hashes,
+// keyed by hash.
+func (mp *Mempool) Lookup(hashes []string) map[string]database.BlockTx {
+	want := make(map[string]struct{}, len(hashes))
+	for _, hash := range hashes {
+		want[hash] = struct{}{}
+	}
+
+	mp.mu.RLock()
+	defer mp.mu.RUnlock()
+
+	txs := make(map[string]database.BlockTx)
+	for _, tx := range mp.pool {
+		hash := tx.HashString()
+		if _, exists := want[hash]; exists {
+			txs[hash] = tx
+		}
+	}
+
+	return txs
+}
+
 func (mp *Mempool) PickBest(howMany ...uint16) []database.BlockTx {
 	number := 0
 	if len(howMany) > 0 {
@@ -107,8 +196,11 @@ func (mp *Mempool) PickBest(howMany ...uint16) []database.BlockTx {
 	}
 
 	m := make(map[database.AccountID][]database.BlockTx)
+	var selectFn selector.Func
 	mp.mu.RLock()
 	{
+		selectFn = mp.selectFn
+
 		if number == 0 {
 			number = len(mp.pool)
 		}
@@ -120,7 +212,7 @@ func (mp *Mempool) PickBest(howMany ...uint16) []database.BlockTx {
 	}
 	mp.mu.RUnlock()
 
-	return mp.selectFn(m, number)
+	return selectFn(m, number)
 
 }
 
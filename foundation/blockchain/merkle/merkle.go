@@ -65,10 +65,14 @@ func NewTree[T Hashable[T]](values []T, options ...func(t *Tree[T])) (*Tree[T],
 
 // Generate constructs the leafs and nodes of the tree from the specified
 // data. If the tree has been generated previously, the tree is re-generated
-// from scratch.
+// from scratch. A tree with no data has no nodes and the merkle root is the
+// hash of no content.
 func (t *Tree[T]) Generate(values []T) error {
 	if len(values) == 0 {
-		return errors.New("cannot construct tree with no content")
+		t.Root = nil
+		t.Leafs = nil
+		t.MerkleRoot = t.hashStrategy().Sum(nil)
+		return nil
 	}
 
 	var leafs []*Node[T]
@@ -110,14 +114,10 @@ func (t *Tree[T]) Generate(values []T) error {
 }
 
 // Rebuild is a helper function that will rebuild the tree reusing only the
-// data that it currently holds in the leaves.
+// data that it currently holds in the leaves. The leaf duplicated to even out
+// the tree is not data and is left out.
 func (t *Tree[T]) Rebuild() error {
-	var data []T
-	for _, node := range t.Leafs {
-		data = append(data, node.Value)
-	}
-
-	if err := t.Generate(data); err != nil {
+	if err := t.Generate(t.Values()); err != nil {
 		return err
 	}
 
@@ -186,6 +186,13 @@ func (t *Tree[T]) Proof(data T) ([][]byte, []int64, error) {
 // Verify validates the hashes at each level of the tree and returns true
 // if the resulting hash at the root of the tree matches the resulting root hash.
 func (t *Tree[T]) Verify() error {
+	if t.Root == nil {
+		if !bytes.Equal(t.MerkleRoot, t.hashStrategy().Sum(nil)) {
+			return errors.New("root hashe invalid")
+		}
+		return nil
+	}
+
 	calculatedMerkleRoot, err := t.Root.verify()
 	if err != nil {
 		return err
@@ -242,13 +249,11 @@ func (t *Tree[T]) VerifyData(data T) error {
 // Values returns a slice of unique values stores in the tree.
 func (t *Tree[T]) Values() []T {
 	var values []T
-	for _, tx := range t.Leafs {
-		values = append(values, tx.Value)
-	}
-
-	l := len(t.Leafs)
-	if bytes.Equal(t.Leafs[l-1].Hash, t.Leafs[l-2].Hash) {
-		return values[:l-1]
+	for _, node := range t.Leafs {
+		if node.dup {
+			continue
+		}
+		values = append(values, node.Value)
 	}
 
 	return values
@@ -0,0 +1,142 @@
+package merkle_test
+
+import (
+	"crypto/sha256"
+	"testing"
+
+	"github.com/ardanlabs/blockchain/foundation/blockchain/merkle"
+)
+
+// data is a value that can be stored in the tree.
+type data string
+
+func (d data) Hash() ([]byte, error) {
+	h := sha256.Sum256([]byte(d))
+	return h[:], nil
+}
+
+func (d data) Equals(other data) bool {
+	return d == other
+}
+
+func TestEmptyTree(t *testing.T) {
+	tree, err := merkle.NewTree[data](nil)
+	if err != nil {
+		t.Fatalf("constructing an empty tree: %s", err)
+	}
+
+	if tree.Root != nil || len(tree.Leafs) != 0 {
+		t.Fatal("expected an empty tree to have no nodes")
+	}
+
+	if values := tree.Values(); len(values) != 0 {
+		t.Fatalf("expected no values, got %v", values)
+	}
+
+	empty := sha256.Sum256(nil)
+	if string(tree.MerkleRoot) != string(empty[:]) {
+		t.Fatalf("expected the root to be the hash of no content, got %s", tree.RootHex())
+	}
+
+	if err := tree.Verify(); err != nil {
+		t.Fatalf("verifying the empty tree: %s", err)
+	}
+
+	if _, _, err := tree.Proof("a"); err == nil {
+		t.Fatal("expected no proof for data that isn't in the tree")
+	}
+	if err := tree.VerifyData("a"); err == nil {
+		t.Fatal("expected data that isn't in the tree to fail verification")
+	}
+
+	if err := tree.Rebuild(); err != nil {
+		t.Fatalf("rebuilding the empty tree: %s", err)
+	}
+	if string(tree.MerkleRoot) != string(empty[:]) {
+		t.Fatal("expected the rebuilt tree to keep the empty root")
+	}
+
+	tree.MerkleRoot = []byte("other root")
+	if err := tree.Verify(); err == nil {
+		t.Fatal("expected an empty tree with a different root to fail verification")
+	}
+}
+
+func TestEmptyTreeAfterValues(t *testing.T) {
+	tree, err := merkle.NewTree([]data{"a", "b"})
+	if err != nil {
+		t.Fatalf("constructing tree: %s", err)
+	}
+
+	if err := tree.Generate(nil); err != nil {
+		t.Fatalf("generating an empty tree: %s", err)
+	}
+
+	empty, err := merkle.NewTree[data](nil)
+	if err != nil {
+		t.Fatalf("constructing an empty tree: %s", err)
+	}
+
+	if tree.RootHex() != empty.RootHex() || len(tree.Values()) != 0 {
+		t.Fatal("expected a regenerated tree with no values to match an empty tree")
+	}
+}
+
+func TestValues(t *testing.T) {
+	tt := []struct {
+		name   string
+		values []data
+	}{
+		{name: "one", values: []data{"a"}},
+		{name: "even", values: []data{"a", "b", "c", "d"}},
+		{name: "odd", values: []data{"a", "b", "c"}},
+		{name: "same last two", values: []data{"a", "b", "b"}},
+		{name: "same values", values: []data{"a", "a"}},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			tree, err := merkle.NewTree(tc.values)
+			if err != nil {
+				t.Fatalf("constructing tree: %s", err)
+			}
+
+			equal(t, tree.Values(), tc.values)
+
+			if err := tree.Verify(); err != nil {
+				t.Fatalf("verifying tree: %s", err)
+			}
+
+			for _, value := range tc.values {
+				if err := tree.VerifyData(value); err != nil {
+					t.Fatalf("verifying %s: %s", value, err)
+				}
+			}
+
+			root := tree.RootHex()
+			if err := tree.Rebuild(); err != nil {
+				t.Fatalf("rebuilding tree: %s", err)
+			}
+
+			equal(t, tree.Values(), tc.values)
+			if tree.RootHex() != root {
+				t.Fatalf("expected the rebuilt tree to keep root %s, got %s", root, tree.RootHex())
+			}
+		})
+	}
+}
+
+// =============================================================================
+
+func equal(t *testing.T, got []data, exp []data) {
+	t.Helper()
+
+	if len(got) != len(exp) {
+		t.Fatalf("expected values %v, got %v", exp, got)
+	}
+	for i := range exp {
+		if got[i] != exp[i] {
+			t.Fatalf("expected values %v, got %v", exp, got)
+		}
+	}
+}
@@ -0,0 +1,198 @@
+package p2p
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"net"
+	"sync"
+	"time"
+)
+
+// Set of timeouts applied to a connection.
+const (
+	sendTimeout  = 5 * time.Second
+	writeTimeout = 10 * time.Second
+)
+
+// sendQueueSize is the number of messages that can wait to be written to a
+// peer before senders are blocked.
+const sendQueueSize = 128
+
+// Set of errors returned by a connection.
+var (
+	ErrClosed       = errors.New("connection closed")
+	ErrBackpressure = errors.New("peer is not keeping up")
+)
+
+// Handler processes a message received from a peer. When the message is a
+// request, the returned message is sent back as the reply. A handler that
+// can't answer right away returns Deferred and calls Reply later.
+type Handler func(c *Conn, msg Message) Message
+
+// Deferred is returned by a handler that replies to the request later.
+var Deferred = Message{Type: msgDeferred}
+
+// Conn is a long lived connection with a peer. Writes go through a queue so a
+// slow peer pushes back on the senders instead of piling up goroutines.
+type Conn struct {
+	host      string
+	id        string
+	dialed    bool
+	conn      net.Conn
+	rd        *bufio.Reader
+	queue     chan Message
+	closed    chan struct{}
+	closeOnce sync.Once
+
+	mu      sync.Mutex
+	nextID  uint64
+	pending map[uint64]chan Message
+}
+
+func newConn(host string, id string, dialed bool, conn net.Conn, rd *bufio.Reader) *Conn {
+	return &Conn{
+		host:    host,
+		id:      id,
+		dialed:  dialed,
+		conn:    conn,
+		rd:      rd,
+		queue:   make(chan Message, sendQueueSize),
+		closed:  make(chan struct{}),
+		pending: make(map[uint64]chan Message),
+	}
+}
+
+// Host returns the host of the peer on the other side of the connection.
+func (c *Conn) Host() string {
+	return c.host
+}
+
+// ID returns the verified node id of the peer.
+func (c *Conn) ID() string {
+	return c.id
+}
+
+// Done returns a channel that's closed when the connection is closed.
+func (c *Conn) Done() <-chan struct{} {
+	return c.closed
+}
+
+// Close shuts down the connection. Pending requests fail with ErrClosed.
+func (c *Conn) Close() error {
+	var err error
+	c.closeOnce.Do(func() {
+		close(c.closed)
+		err = c.conn.Close()
+	})
+	return err
+}
+
+// Send queues the message to be written to the peer. If the queue stays full
+// for the send timeout, ErrBackpressure is returned.
+func (c *Conn) Send(msg Message) error {
+	select {
+	case <-c.closed:
+		return ErrClosed
+	default:
+	}
+
+	timer := time.NewTimer(sendTimeout)
+	defer timer.Stop()
+
+	select {
+	case c.queue <- msg:
+		return nil
+	case <-c.closed:
+		return ErrClosed
+	case <-timer.C:
+		return ErrBackpressure
+	}
+}
+
+// Request sends the message and waits for the reply from the peer.
+func (c *Conn) Request(ctx context.Context, msg Message) (Message, error) {
+	reply := make(chan Message, 1)
+
+	c.mu.Lock()
+	c.nextID++
+	msg.ID = c.nextID
+	c.pending[msg.ID] = reply
+	c.mu.Unlock()
+
+	defer func() {
+		c.mu.Lock()
+		delete(c.pending, msg.ID)
+		c.mu.Unlock()
+	}()
+
+	if err := c.Send(msg); err != nil {
+		return Message{}, err
+	}
+
+	select {
+	case resp := <-reply:
+		return resp, nil
+	case <-c.closed:
+		return Message{}, ErrClosed
+	case <-ctx.Done():
+		return Message{}, ctx.Err()
+	}
+}
+
+// Reply sends the reply to the request.
+func (c *Conn) Reply(req Message, resp Message) error {
+	resp.ReplyTo = req.ID
+	return c.Send(resp)
+}
+
+// =============================================================================
+
+// run starts the writer and reads messages until the connection fails.
+func (c *Conn) run(handler Handler) error {
+	go c.writeLoop()
+	defer c.Close()
+
+	for {
+		msg, err := ReadMessage(c.rd)
+		if err != nil {
+			return err
+		}
+
+		if msg.ReplyTo != 0 {
+			c.mu.Lock()
+			reply, exists := c.pending[msg.ReplyTo]
+			c.mu.Unlock()
+
+			if exists {
+				reply <- msg
+			}
+			continue
+		}
+
+		resp := handler(c, msg)
+		if msg.ID == 0 || resp.Type == msgDeferred {
+			continue
+		}
+
+		if err := c.Reply(msg, resp); err != nil {
+			return err
+		}
+	}
+}
+
+// writeLoop writes the queued messages to the peer.
+func (c *Conn) writeLoop() {
+	for {
+		select {
+		case msg := <-c.queue:
+			c.conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+			if err := WriteMessage(c.conn, msg); err != nil {
+				c.Close()
+				return
+			}
+		case <-c.closed:
+			return
+		}
+	}
+}
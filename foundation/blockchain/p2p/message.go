@@ -0,0 +1,166 @@
+// Package p2p provides long lived, bidirectional connections between nodes.
+// Messages are framed with a length prefix and carry a JSON payload.
+package p2p
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// MaxMessageSize is the largest frame accepted from a peer.
+const MaxMessageSize = 32 << 20
+
+// ErrMessageTooLarge is returned when a frame is bigger than MaxMessageSize.
+var ErrMessageTooLarge = errors.New("message too large")
+
+// MsgType identifies the content of a message.
+type MsgType string
+
+// Set of message types exchanged between nodes.
+const (
+	MsgHandshake MsgType = "handshake"
+	MsgStatus    MsgType = "status"
+	MsgTx        MsgType = "tx"
+	MsgBlock     MsgType = "block"
+	MsgVote      MsgType = "vote"
+	MsgPeers     MsgType = "peers"
+	MsgGetBlocks MsgType = "get-blocks"
+	MsgBlocks    MsgType = "blocks"
+	MsgError     MsgType = "error"
+
+	MsgInv          MsgType = "inv"
+	MsgGetTxs       MsgType = "get-txs"
+	MsgTxs          MsgType = "txs"
+	MsgGetBlock     MsgType = "get-block"
+	MsgCompactBlock MsgType = "compact-block"
+	MsgAck          MsgType = "ack"
+
+	// msgDeferred marks a reply the handler sends itself. It's never sent.
+	msgDeferred MsgType = "deferred"
+)
+
+// Message is the unit of communication between two nodes. A message with an
+// ID is a request and the peer answers with a message carrying the same value
+// in ReplyTo.
+type Message struct {
+	Type    MsgType         `json:"type"`
+	ID      uint64          `json:"id,omitempty"`
+	ReplyTo uint64          `json:"reply_to,omitempty"`
+	Payload json.RawMessage `json:"payload,omitempty"`
+}
+
+// NewMessage constructs a message with the payload encoded as JSON.
+func NewMessage(msgType MsgType, payload any) (Message, error) {
+	msg := Message{
+		Type: msgType,
+	}
+
+	if payload != nil {
+		data, err := json.Marshal(payload)
+		if err != nil {
+			return Message{}, err
+		}
+		msg.Payload = data
+	}
+
+	return msg, nil
+}
+
+// ErrorMessage constructs the reply for a request that failed.
+func ErrorMessage(err error) Message {
+	msg, _ := NewMessage(MsgError, err.Error())
+	return msg
+}
+
+// Decode unmarshals the payload into the specified value. An error message
+// is returned as an error.
+func (m Message) Decode(v any) error {
+	if m.Type == MsgError {
+		var text string
+		if err := json.Unmarshal(m.Payload, &text); err != nil {
+			return err
+		}
+		return errors.New(text)
+	}
+
+	if len(m.Payload) == 0 {
+		return nil
+	}
+
+	return json.Unmarshal(m.Payload, v)
+}
+
+// GetBlocks is the payload of a get-blocks request.
+type GetBlocks struct {
+	From uint64 `json:"from"`
+	To   uint64 `json:"to"`
+}
+
+// Inv is the payload of an inventory announcement. It carries the hashes of
+// the transactions and blocks the sender has.
+type Inv struct {
+	Txs    []string `json:"txs,omitempty"`
+	Blocks []string `json:"blocks,omitempty"`
+}
+
+// GetTxs is the payload of a get-txs request.
+type GetTxs struct {
+	Hashes []string `json:"hashes"`
+}
+
+// GetBlock is the payload of a get-block request. A compact block is sent
+// back when Compact is set.
+type GetBlock struct {
+	Hash    string `json:"hash"`
+	Compact bool   `json:"compact"`
+}
+
+// =============================================================================
+
+// WriteMessage writes the message as a frame: a 4 byte big endian length
+// followed by the JSON encoded message.
+func WriteMessage(w io.Writer, msg Message) error {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+
+	if len(data) > MaxMessageSize {
+		return ErrMessageTooLarge
+	}
+
+	frame := make([]byte, 4+len(data))
+	binary.BigEndian.PutUint32(frame, uint32(len(data)))
+	copy(frame[4:], data)
+
+	_, err = w.Write(frame)
+	return err
+}
+
+// ReadMessage reads the next frame and decodes the message.
+func ReadMessage(r io.Reader) (Message, error) {
+	var prefix [4]byte
+	if _, err := io.ReadFull(r, prefix[:]); err != nil {
+		return Message{}, err
+	}
+
+	size := binary.BigEndian.Uint32(prefix[:])
+	if size > MaxMessageSize {
+		return Message{}, ErrMessageTooLarge
+	}
+
+	data := make([]byte, size)
+	if _, err := io.ReadFull(r, data); err != nil {
+		return Message{}, err
+	}
+
+	var msg Message
+	if err := json.Unmarshal(data, &msg); err != nil {
+		return Message{}, fmt.Errorf("decoding message: %w", err)
+	}
+
+	return msg, nil
+}
@@ -0,0 +1,345 @@
+package p2p
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// CORE NOTE: A stream starts as an HTTP request on the private api of the peer
+// asking to upgrade the connection. Once the peer answers with 101 Switching
+// Protocols, the TCP connection is taken over and both nodes exchange length
+// prefixed messages in both directions until one side closes it. This way the
+// stream shares the address already used to identify a peer. Before any other
+// message, the dialing node sends its signed handshake and the peer answers
+// with its own, so both sides know who they are talking to. The host in a
+// handshake is only proven for the node that dialed the host, a node dialing
+// in can claim any host, so only the dialing side binds the identity of the
+// peer to its host. Only one stream is kept per peer. When both nodes dial each other at the same time, both
+// keep the stream dialed by the node with the lowest host.
+
+// Protocol is the value of the Upgrade header used to open a stream.
+const Protocol = "ardan-p2p/1"
+
+// dialTimeout is how long the upgrade and handshake of a stream can take.
+const dialTimeout = 5 * time.Second
+
+// Identity produces and verifies the handshakes exchanged when a stream opens.
+// Bind is called once a dialed peer proved it's the node at the host.
+type Identity interface {
+	Handshake() (Message, error)
+	VerifyHandshake(msg Message) (host string, id string, err error)
+	Bind(host string, id string) error
+}
+
+// Dialer opens the connections the streams are carried on.
+type Dialer interface {
+	Dial(host string) (net.Conn, error)
+}
+
+// Authorizer is implemented by the dialers carrying the credentials the peers
+// require to accept a stream.
+type Authorizer interface {
+	Authorization() string
+}
+
+// Streams maintains the set of open streams with peers.
+type Streams struct {
+	host      string
+	path      string
+	handler   Handler
+	identity  Identity
+	dialer    Dialer
+	evHandler func(v string, args ...any)
+
+	mu    sync.RWMutex
+	conns map[string]*Conn
+}
+
+// New constructs the set of streams for the node at the specified host. The
+// path is the url path peers accept streams on.
+func New(host string, path string, handler Handler, identity Identity, dialer Dialer, evHandler func(v string, args ...any)) *Streams {
+	return &Streams{
+		host:      host,
+		path:      path,
+		handler:   handler,
+		identity:  identity,
+		dialer:    dialer,
+		evHandler: evHandler,
+		conns:     make(map[string]*Conn),
+	}
+}
+
+// Conn returns the open stream with the peer.
+func (s *Streams) Conn(host string) (*Conn, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	c, exists := s.conns[host]
+	return c, exists
+}
+
+// Hosts returns the hosts of the peers with an open stream.
+func (s *Streams) Hosts() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	hosts := make([]string, 0, len(s.conns))
+	for host := range s.conns {
+		hosts = append(hosts, host)
+	}
+	return hosts
+}
+
+// Send sends the message over the open stream with the peer.
+func (s *Streams) Send(host string, msg Message) error {
+	c, exists := s.Conn(host)
+	if !exists {
+		return fmt.Errorf("no stream with %s", host)
+	}
+
+	return c.Send(msg)
+}
+
+// Request sends the request over the open stream with the peer and waits for
+// the reply.
+func (s *Streams) Request(ctx context.Context, host string, msg Message) (Message, error) {
+	c, exists := s.Conn(host)
+	if !exists {
+		return Message{}, fmt.Errorf("no stream with %s", host)
+	}
+
+	return c.Request(ctx, msg)
+}
+
+// Dial opens a stream with the peer unless one is already open.
+func (s *Streams) Dial(host string) (*Conn, error) {
+	if c, exists := s.Conn(host); exists {
+		return c, nil
+	}
+
+	conn, err := s.dialer.Dial(host)
+	if err != nil {
+		return nil, err
+	}
+
+	conn.SetDeadline(time.Now().Add(dialTimeout))
+
+	req, err := http.NewRequest(http.MethodGet, "http://"+host+s.path, nil)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Upgrade", Protocol)
+	if a, ok := s.dialer.(Authorizer); ok {
+		if auth := a.Authorization(); auth != "" {
+			req.Header.Set("Authorization", auth)
+		}
+	}
+
+	if err := req.Write(conn); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	rd := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(rd, req)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		conn.Close()
+		return nil, fmt.Errorf("peer refused stream: %s", resp.Status)
+	}
+
+	// The peer must prove it's the node expected at the host.
+	if err := s.sendHandshake(conn); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	peerHost, id, err := s.readHandshake(rd)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	if peerHost != host {
+		conn.Close()
+		return nil, fmt.Errorf("peer at %s claims host %s", host, peerHost)
+	}
+
+	if err := s.identity.Bind(host, id); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	conn.SetDeadline(time.Time{})
+
+	c := newConn(host, id, true, conn, rd)
+	if !s.add(c) {
+		conn.Close()
+		if existing, exists := s.Conn(host); exists {
+			return existing, nil
+		}
+		return nil, ErrClosed
+	}
+
+	go s.serve(c)
+
+	return c, nil
+}
+
+// Accept takes over the connection of an upgrade request from a peer.
+func (s *Streams) Accept(w http.ResponseWriter, r *http.Request) error {
+	if r.Header.Get("Upgrade") != Protocol {
+		return fmt.Errorf("upgrade to %s required", Protocol)
+	}
+
+	hj, ok := w.(http.Hijacker)
+	if !ok {
+		return fmt.Errorf("connection can't be upgraded")
+	}
+
+	conn, rw, err := hj.Hijack()
+	if err != nil {
+		return err
+	}
+
+	// The deadlines set by the http server don't apply to a stream.
+	conn.SetDeadline(time.Now().Add(dialTimeout))
+
+	resp := "HTTP/1.1 101 Switching Protocols\r\nConnection: Upgrade\r\nUpgrade: " + Protocol + "\r\n\r\n"
+	if _, err := conn.Write([]byte(resp)); err != nil {
+		conn.Close()
+		return nil
+	}
+
+	// The response is already sent so a failed handshake only closes the
+	// connection.
+	host, id, err := s.readHandshake(rw.Reader)
+	if err != nil {
+		s.evHandler("p2p: Accept: handshake from %s: ERROR %s", r.RemoteAddr, err)
+		conn.Close()
+		return nil
+	}
+
+	if host == s.host {
+		conn.Close()
+		return nil
+	}
+
+	if err := s.sendHandshake(conn); err != nil {
+		conn.Close()
+		return nil
+	}
+
+	conn.SetDeadline(time.Time{})
+
+	c := newConn(host, id, false, conn, rw.Reader)
+	if !s.add(c) {
+		conn.Close()
+		return nil
+	}
+
+	go s.serve(c)
+
+	return nil
+}
+
+// Disconnect closes the stream with the peer.
+func (s *Streams) Disconnect(host string) {
+	s.mu.Lock()
+	c, exists := s.conns[host]
+	delete(s.conns, host)
+	s.mu.Unlock()
+
+	if exists {
+		c.Close()
+	}
+}
+
+// Close closes all the open streams.
+func (s *Streams) Close() {
+	s.mu.Lock()
+	conns := s.conns
+	s.conns = make(map[string]*Conn)
+	s.mu.Unlock()
+
+	for _, c := range conns {
+		c.Close()
+	}
+}
+
+// =============================================================================
+
+// add registers the stream. It returns false if the stream lost against an
+// existing stream with the same peer.
+func (s *Streams) add(c *Conn) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if existing, exists := s.conns[c.host]; exists {
+		keepNew := c.dialed == (s.host < c.host)
+		if !keepNew {
+			return false
+		}
+		existing.Close()
+	}
+
+	s.conns[c.host] = c
+	return true
+}
+
+// remove unregisters the stream if it's still the one in use for the peer.
+func (s *Streams) remove(c *Conn) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.conns[c.host] == c {
+		delete(s.conns, c.host)
+	}
+}
+
+// sendHandshake writes the handshake of this node.
+func (s *Streams) sendHandshake(w io.Writer) error {
+	msg, err := s.identity.Handshake()
+	if err != nil {
+		return err
+	}
+
+	return WriteMessage(w, msg)
+}
+
+// readHandshake reads and verifies the handshake of the peer.
+func (s *Streams) readHandshake(r io.Reader) (host string, id string, err error) {
+	msg, err := ReadMessage(r)
+	if err != nil {
+		return "", "", err
+	}
+
+	if msg.Type != MsgHandshake {
+		return "", "", fmt.Errorf("expected handshake, got %q", msg.Type)
+	}
+
+	return s.identity.VerifyHandshake(msg)
+}
+
+// serve processes the messages from the peer until the stream closes.
+func (s *Streams) serve(c *Conn) {
+	s.evHandler("p2p: serve: stream opened with %s id[%s] dialed[%t]", c.host, c.id, c.dialed)
+
+	err := c.run(s.handler)
+
+	s.remove(c)
+	s.evHandler("p2p: serve: stream closed with %s: %v", c.host, err)
+}
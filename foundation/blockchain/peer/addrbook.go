@@ -0,0 +1,273 @@
+package peer
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CORE NOTE: Every peer the node hears about is recorded in the address book
+// along with when it was last seen and how often it answered. The book is
+// written to the data directory so a restarted node doesn't depend on its
+// origin peers alone. Only a limited number of peers are active at a time.
+// They are picked from the book preferring peers that answered recently, while
+// spreading the picks over different networks so a single operator can't
+// surround the node.
+
+// staleAge is how long a peer that never answered stays in the book.
+const staleAge = 14 * 24 * time.Hour
+
+// Address represents a peer recorded in the address book.
+type Address struct {
+	Host        string    `json:"host"`
+	ID          string    `json:"id,omitempty"`
+	FirstSeen   time.Time `json:"first_seen"`
+	LastSeen    time.Time `json:"last_seen"`
+	LastSuccess time.Time `json:"last_success"`
+	Successes   int       `json:"successes"`
+	Failures    int       `json:"failures"`
+}
+
+// AddrBook maintains the peers known to the node across restarts.
+type AddrBook struct {
+	mu    sync.Mutex
+	path  string
+	addrs map[string]*Address
+}
+
+// LoadAddrBook loads the address book stored at the path. An empty book is
+// returned when the file doesn't exist yet. An empty path keeps the book in
+// memory only.
+func LoadAddrBook(path string) (*AddrBook, error) {
+	ab := AddrBook{
+		path:  path,
+		addrs: make(map[string]*Address),
+	}
+
+	if path == "" {
+		return &ab, nil
+	}
+
+	data, err := os.ReadFile(path)
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		return &ab, nil
+	case err != nil:
+		return nil, err
+	}
+
+	var addrs []Address
+	if err := json.Unmarshal(data, &addrs); err != nil {
+		return nil, err
+	}
+
+	for i := range addrs {
+		ab.addrs[addrs[i].Host] = &addrs[i]
+	}
+
+	return &ab, nil
+}
+
+// Save writes the address book to its file.
+func (ab *AddrBook) Save() error {
+	if ab.path == "" {
+		return nil
+	}
+
+	data, err := json.MarshalIndent(ab.Copy(), "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(ab.path), 0755); err != nil {
+		return err
+	}
+
+	// The file is replaced at once so a crash never leaves half a book.
+	tmp := ab.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, ab.path)
+}
+
+// Seen records the peer was heard of.
+func (ab *AddrBook) Seen(peer Peer) {
+	ab.mu.Lock()
+	defer ab.mu.Unlock()
+
+	addr := ab.get(peer.Host)
+	addr.LastSeen = time.Now()
+	if peer.ID != "" {
+		addr.ID = peer.ID
+	}
+}
+
+// Success records the peer answered a request.
+func (ab *AddrBook) Success(host string) {
+	ab.mu.Lock()
+	defer ab.mu.Unlock()
+
+	addr := ab.get(host)
+	addr.LastSeen = time.Now()
+	addr.LastSuccess = addr.LastSeen
+	addr.Successes++
+}
+
+// Failure records the peer didn't answer a request.
+func (ab *AddrBook) Failure(host string) {
+	ab.mu.Lock()
+	defer ab.mu.Unlock()
+
+	ab.get(host).Failures++
+}
+
+// Prune removes the peers that haven't answered within the stale age.
+func (ab *AddrBook) Prune() int {
+	ab.mu.Lock()
+	defer ab.mu.Unlock()
+
+	var pruned int
+	cutoff := time.Now().Add(-staleAge)
+	for host, addr := range ab.addrs {
+		last := addr.LastSuccess
+		if last.IsZero() {
+			last = addr.FirstSeen
+		}
+		if last.Before(cutoff) {
+			delete(ab.addrs, host)
+			pruned++
+		}
+	}
+
+	return pruned
+}
+
+// Copy returns the addresses in the book sorted by host.
+func (ab *AddrBook) Copy() []Address {
+	ab.mu.Lock()
+	defer ab.mu.Unlock()
+
+	addrs := make([]Address, 0, len(ab.addrs))
+	for _, addr := range ab.addrs {
+		addrs = append(addrs, *addr)
+	}
+
+	sort.Slice(addrs, func(i, j int) bool {
+		return addrs[i].Host < addrs[j].Host
+	})
+
+	return addrs
+}
+
+// Select picks up to n peers for which skip returns false. Fresher peers are
+// preferred and the picks rotate over the network groups of the peers so no
+// group takes all the slots.
+func (ab *AddrBook) Select(n int, skip func(host string) bool) []Peer {
+	var candidates []Address
+	for _, addr := range ab.Copy() {
+		if !skip(addr.Host) {
+			candidates = append(candidates, addr)
+		}
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return fresher(candidates[i], candidates[j])
+	})
+
+	// The groups keep the order of their freshest peer.
+	var order []string
+	groups := make(map[string][]Address)
+	for _, addr := range candidates {
+		group := netGroup(addr.Host)
+		if _, exists := groups[group]; !exists {
+			order = append(order, group)
+		}
+		groups[group] = append(groups[group], addr)
+	}
+
+	var peers []Peer
+	for len(peers) < n {
+		picked := false
+		for _, group := range order {
+			if len(peers) == n {
+				break
+			}
+			if len(groups[group]) == 0 {
+				continue
+			}
+			peers = append(peers, New(groups[group][0].Host))
+			groups[group] = groups[group][1:]
+			picked = true
+		}
+		if !picked {
+			break
+		}
+	}
+
+	return peers
+}
+
+// get returns the address of the peer, creating it when needed. The lock must
+// be held by the caller.
+func (ab *AddrBook) get(host string) *Address {
+	addr, exists := ab.addrs[host]
+	if !exists {
+		now := time.Now()
+		addr = &Address{
+			Host:      host,
+			FirstSeen: now,
+			LastSeen:  now,
+		}
+		ab.addrs[host] = addr
+	}
+	return addr
+}
+
+// =============================================================================
+
+// fresher reports if address a should be preferred over address b. Peers that
+// answered more recently come first, then peers with a better success ratio.
+func fresher(a, b Address) bool {
+	if !a.LastSuccess.Equal(b.LastSuccess) {
+		return a.LastSuccess.After(b.LastSuccess)
+	}
+
+	ra := float64(a.Successes+1) / float64(a.Successes+a.Failures+2)
+	rb := float64(b.Successes+1) / float64(b.Successes+b.Failures+2)
+	if ra != rb {
+		return ra > rb
+	}
+
+	return a.LastSeen.After(b.LastSeen)
+}
+
+// netGroup returns the network the host belongs to. IPv4 addresses are grouped
+// by /16 and IPv6 addresses by /32. Names are grouped by their parent domain.
+func netGroup(host string) string {
+	h, _, err := net.SplitHostPort(host)
+	if err != nil {
+		h = host
+	}
+
+	if ip := net.ParseIP(h); ip != nil {
+		if ip4 := ip.To4(); ip4 != nil {
+			return ip4.Mask(net.CIDRMask(16, 32)).String()
+		}
+		return ip.Mask(net.CIDRMask(32, 128)).String()
+	}
+
+	labels := strings.Split(h, ".")
+	if len(labels) > 2 {
+		labels = labels[len(labels)-2:]
+	}
+	return strings.Join(labels, ".")
+}
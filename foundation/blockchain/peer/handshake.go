@@ -0,0 +1,129 @@
+package peer
+
+import (
+	"crypto/ecdsa"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ardanlabs/blockchain/foundation/blockchain/signature"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// CORE NOTE: Every node has an identity keypair that is separate from the key
+// of its beneficiary. The node id is the address of the identity public key.
+// A connection between nodes starts with both sides sending a signed handshake.
+// The signature proves the node holds the key behind its id, and the chain id,
+// genesis hash and protocol version make sure both nodes are on the same
+// network. The timestamp limits how long a captured handshake can be replayed.
+
+// ProtocolVersion is the version of the node to node protocol.
+const ProtocolVersion = 1
+
+// maxHandshakeAge is how old or far in the future a handshake can be.
+const maxHandshakeAge = 5 * time.Minute
+
+// Set of errors returned when a handshake is verified.
+var (
+	ErrHandshakeSignature = errors.New("handshake signature doesn't match the node id")
+	ErrHandshakeNetwork   = errors.New("handshake is for a different network")
+	ErrHandshakeExpired   = errors.New("handshake is too old")
+)
+
+// Handshake is the information a node sends when a connection with a peer
+// starts.
+type Handshake struct {
+	NodeID      string `json:"node_id"`
+	Host        string `json:"host"`
+	ChainID     uint16 `json:"chain_id"`
+	GenesisHash string `json:"genesis_hash"`
+	Version     uint32 `json:"version"`
+	BestHeight  uint64 `json:"best_height"`
+	Timestamp   int64  `json:"timestamp"`
+}
+
+// SignedHandshake is a handshake signed with the identity key of the node.
+type SignedHandshake struct {
+	Handshake
+	Sig string `json:"sig"`
+}
+
+// NodeID returns the id of the node with the specified identity key.
+func NodeID(pk *ecdsa.PrivateKey) string {
+	return crypto.PubkeyToAddress(pk.PublicKey).String()
+}
+
+// NewHandshake constructs the handshake for the node with the identity key.
+func NewHandshake(pk *ecdsa.PrivateKey, host string, chainID uint16, genesisHash string, bestHeight uint64) Handshake {
+	return Handshake{
+		NodeID:      NodeID(pk),
+		Host:        host,
+		ChainID:     chainID,
+		GenesisHash: genesisHash,
+		Version:     ProtocolVersion,
+		BestHeight:  bestHeight,
+		Timestamp:   time.Now().Unix(),
+	}
+}
+
+// Sign signs the handshake with the identity key of the node.
+func (hs Handshake) Sign(pk *ecdsa.PrivateKey) (SignedHandshake, error) {
+	v, r, s, err := signature.Sign(hs, pk)
+	if err != nil {
+		return SignedHandshake{}, err
+	}
+
+	return SignedHandshake{
+		Handshake: hs,
+		Sig:       signature.SignatureString(v, r, s),
+	}, nil
+}
+
+// Validate checks the handshake was signed by the node it claims to be from
+// and that the node is on the same network.
+func (shs SignedHandshake) Validate(chainID uint16, genesisHash string) error {
+	if shs.ChainID != chainID || shs.GenesisHash != genesisHash {
+		return ErrHandshakeNetwork
+	}
+
+	if shs.Version != ProtocolVersion {
+		return fmt.Errorf("unsupported protocol version %d", shs.Version)
+	}
+
+	age := time.Since(time.Unix(shs.Timestamp, 0))
+	if age > maxHandshakeAge || age < -maxHandshakeAge {
+		return ErrHandshakeExpired
+	}
+
+	if len(shs.Sig) < 4 {
+		return ErrHandshakeSignature
+	}
+
+	v, r, s, err := signature.ToVRSFromHexSignature(shs.Sig)
+	if err != nil {
+		return err
+	}
+
+	if err := signature.VerifySignature(v, r, s); err != nil {
+		return err
+	}
+
+	address, err := signature.FromAddress(shs.Handshake, v, r, s)
+	if err != nil {
+		return err
+	}
+
+	if address != shs.NodeID {
+		return ErrHandshakeSignature
+	}
+
+	return nil
+}
+
+// Peer returns the verified peer described by the handshake.
+func (shs SignedHandshake) Peer() Peer {
+	return Peer{
+		Host: shs.Host,
+		ID:   shs.NodeID,
+	}
+}
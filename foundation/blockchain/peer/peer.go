@@ -1,22 +1,36 @@
 package peer
 
 import (
+	"errors"
 	"fmt"
 	"sync"
 )
 
 const (
-	BaseUrl        = "http://%s/v1/node"
+	BasePath       = "/v1/node"
+	BaseUrl        = "http://%s" + BasePath
 	StatusUri      = "/status"
 	MempoolUri     = "/tx/list"
 	BlocksUri      = "/block/list/%s/%s"
+	HeadersUri     = "/block/headers/%s/%s"
+	TxByHashUri    = "/tx/hash/%s"
+	BlockByHashUri = "/block/hash/%s"
 	PeerUri        = "/peers"
+	HandshakeUri   = "/handshake"
 	TxSubmitUri    = "/tx/submit"
 	BlockSubmitUri = "/block/propose"
+	VoteSubmitUri  = "/vote/submit"
+
+	StreamUri        = "/stream"
+	SnapshotUri      = "/snapshot/latest"
+	SnapshotChunkUri = "/snapshot/%s/%s"
 )
 
+// Peer represents a node in the network. The ID is only set once the node
+// proved its identity with a signed handshake.
 type Peer struct {
 	Host string
+	ID   string `json:",omitempty"`
 }
 
 func New(host string) Peer {
@@ -29,20 +43,33 @@ func (p Peer) Match(host string) bool {
 	return p.Host == host
 }
 
+// Verified reports if the peer proved its identity.
+func (p Peer) Verified() bool {
+	return p.ID != ""
+}
+
 type PeerStatus struct {
+	NodeID          string `json:"node_id,omitempty"`
 	LatestBlockHash string `json:"latest_block_hash"`
 	LatestBlockNum  uint64 `json:"latest_block_num"`
 	KnownPeers      []Peer `json:"known_peers"`
 }
 
+// ErrIdentityMismatch is returned when a host is claimed by a node with a
+// different identity than the one already verified for it.
+var ErrIdentityMismatch = errors.New("host belongs to a different node")
+
+// PeerSet maintains the known peers. Verified peers are tracked by node id so
+// a node that moves to a new host keeps its identity. Peers learned from other
+// nodes are tracked by host until a handshake verifies them.
 type PeerSet struct {
 	mu  sync.RWMutex
-	set map[Peer]struct{}
+	set map[string]Peer
 }
 
 func NewPeerSet() *PeerSet {
 	return &PeerSet{
-		set: make(map[Peer]struct{}),
+		set: make(map[string]Peer),
 	}
 }
 
@@ -50,41 +77,105 @@ func (p Peer) Url() string {
 	return fmt.Sprintf(BaseUrl, p.Host)
 }
 
+// Add adds a peer that hasn't been verified. Any id claimed for it is ignored
+// and nothing changes when the host is already known.
 func (ps *PeerSet) Add(peer Peer) bool {
 	ps.mu.Lock()
 	defer ps.mu.Unlock()
 
-	_, exists := ps.set[peer]
-	if !exists {
-		ps.set[peer] = struct{}{}
-		return true
+	if _, exists := ps.byHost(peer.Host); exists {
+		return false
 	}
 
-	return false
+	ps.set[hostKey(peer.Host)] = Peer{Host: peer.Host}
+	return true
+}
+
+// AddVerified adds a peer whose identity was verified with a handshake. It
+// replaces the unverified entry for the host and updates the host of a known
+// node id. It fails if the host is verified for a different node.
+func (ps *PeerSet) AddVerified(peer Peer) (bool, error) {
+	if !peer.Verified() {
+		return false, errors.New("peer is not verified")
+	}
+
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+
+	if existing, exists := ps.byHost(peer.Host); exists && existing.Verified() && existing.ID != peer.ID {
+		return false, ErrIdentityMismatch
+	}
+
+	if existing, exists := ps.set[peer.ID]; exists && existing == peer {
+		return false, nil
+	}
+
+	delete(ps.set, hostKey(peer.Host))
+	ps.set[peer.ID] = peer
+	return true, nil
 }
 
+// Remove removes the peer at the host.
 func (ps *PeerSet) Remove(peer Peer) bool {
 	ps.mu.Lock()
 	defer ps.mu.Unlock()
 
-	_, exists := ps.set[peer]
-	if exists {
-		delete(ps.set, peer)
-		return true
+	for key, existing := range ps.set {
+		if existing.Host == peer.Host {
+			delete(ps.set, key)
+			return true
+		}
 	}
 
 	return false
 }
 
+// Get returns the peer at the host.
+func (ps *PeerSet) Get(host string) (Peer, bool) {
+	ps.mu.RLock()
+	defer ps.mu.RUnlock()
+
+	return ps.byHost(host)
+}
+
 func (ps *PeerSet) Copy(host string) []Peer {
 	ps.mu.RLock()
 	defer ps.mu.RUnlock()
 
-	peers := make([]Peer, 0, len(ps.set)-1)
-	for peer := range ps.set {
+	peers := make([]Peer, 0, len(ps.set))
+	for _, peer := range ps.set {
 		if peer.Host != host { // Removing self
 			peers = append(peers, peer)
 		}
 	}
 	return peers
 }
+
+// Len returns the number of peers in the set.
+func (ps *PeerSet) Len() int {
+	ps.mu.RLock()
+	defer ps.mu.RUnlock()
+
+	return len(ps.set)
+}
+
+// byHost finds the peer at the host. The lock must be held by the caller.
+func (ps *PeerSet) byHost(host string) (Peer, bool) {
+	if peer, exists := ps.set[hostKey(host)]; exists {
+		return peer, true
+	}
+
+	for _, peer := range ps.set {
+		if peer.Host == host {
+			return peer, true
+		}
+	}
+
+	return Peer{}, false
+}
+
+// hostKey is the key of a peer that isn't verified. Node ids are hex
+// addresses so they can't collide with it.
+func hostKey(host string) string {
+	return "host:" + host
+}
@@ -0,0 +1,278 @@
+package peer
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// CORE NOTE: Every peer has a health record. A peer that doesn't answer is
+// retried after a backoff that doubles with every consecutive failure instead
+// of being dropped and re-added over and over. A peer that sends invalid data
+// collects penalty points. Once the points reach the ban threshold the peer is
+// banned for a while, and a peer banned too many times is banned for good.
+// Records are kept by host so they survive the peer being forgotten. Like the
+// address book, records idle for long are pruned, and the number of records
+// is capped so addresses that come and go can't grow it without bound.
+
+// Set of penalties given for misbehavior.
+const (
+	PenaltyInvalidBlock = 50
+	PenaltyInvalidTx    = 10
+	PenaltyBadResponse  = 25
+)
+
+// Set of limits used to back off and ban peers.
+const (
+	BanThreshold   = 100
+	TempBanPeriod  = time.Hour
+	MaxTempBans    = 3
+	backoffBase    = 15 * time.Second
+	backoffMax     = 30 * time.Minute
+	scoreDecayTime = 10 * time.Minute
+	healthIdleAge  = 24 * time.Hour
+	maxHealth      = 10_000
+)
+
+// Health represents the health record of a peer.
+type Health struct {
+	Host        string    `json:"host"`
+	Score       int       `json:"score"`
+	Failures    int       `json:"failures"`
+	LastFailure time.Time `json:"last_failure"`
+	NextAttempt time.Time `json:"next_attempt"`
+	TempBans    int       `json:"temp_bans"`
+	BannedUntil time.Time `json:"banned_until"`
+	Permanent   bool      `json:"permanent"`
+	Reason      string    `json:"reason,omitempty"`
+	lastPenalty time.Time
+	lastUpdate  time.Time
+}
+
+// Banned reports if the peer is banned at the specified time.
+func (h Health) Banned(now time.Time) bool {
+	return h.Permanent || now.Before(h.BannedUntil)
+}
+
+// Scores maintains the health records of the peers.
+type Scores struct {
+	mu     sync.Mutex
+	health map[string]*Health
+}
+
+// NewScores constructs an empty set of health records.
+func NewScores() *Scores {
+	return &Scores{
+		health: make(map[string]*Health),
+	}
+}
+
+// Success records the peer answered. Its backoff is reset.
+func (s *Scores) Success(host string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	h := s.get(host)
+	h.Failures = 0
+	h.NextAttempt = time.Time{}
+}
+
+// Failure records the peer didn't answer and returns how long to wait before
+// contacting it again.
+func (s *Scores) Failure(host string) time.Duration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	h := s.get(host)
+	h.Failures++
+	h.LastFailure = time.Now()
+
+	backoff := backoffBase << (h.Failures - 1)
+	if backoff > backoffMax || backoff <= 0 {
+		backoff = backoffMax
+	}
+	h.NextAttempt = h.LastFailure.Add(backoff)
+
+	return backoff
+}
+
+// Penalize adds penalty points for misbehavior. It returns true when the
+// points got the peer banned.
+func (s *Scores) Penalize(host string, points int, reason string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	h := s.get(host)
+
+	// Points from long ago are forgiven so honest peers with the odd bad
+	// message never end up banned.
+	if now.Sub(h.lastPenalty) > scoreDecayTime {
+		h.Score = 0
+	}
+	h.lastPenalty = now
+	h.Score += points
+
+	if h.Score < BanThreshold || h.Banned(now) {
+		return false
+	}
+
+	h.Score = 0
+	h.TempBans++
+	h.Reason = reason
+	if h.TempBans >= MaxTempBans {
+		h.Permanent = true
+		return true
+	}
+	h.BannedUntil = now.Add(TempBanPeriod)
+
+	return true
+}
+
+// Ban bans the peer for the duration. A zero duration bans the peer for good.
+func (s *Scores) Ban(host string, d time.Duration, reason string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	h := s.get(host)
+	h.Reason = reason
+	if d == 0 {
+		h.Permanent = true
+		return
+	}
+	h.BannedUntil = time.Now().Add(d)
+}
+
+// Unban lifts the ban of the peer and clears its penalty points.
+func (s *Scores) Unban(host string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	h := s.get(host)
+	h.Score = 0
+	h.TempBans = 0
+	h.BannedUntil = time.Time{}
+	h.Permanent = false
+	h.Reason = ""
+}
+
+// Banned reports if the peer is banned.
+func (s *Scores) Banned(host string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	h, exists := s.health[host]
+	return exists && h.Banned(time.Now())
+}
+
+// Ready reports if the peer can be contacted: it isn't banned and isn't
+// waiting out a backoff.
+func (s *Scores) Ready(host string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	h, exists := s.health[host]
+	if !exists {
+		return true
+	}
+
+	now := time.Now()
+	return !h.Banned(now) && !now.Before(h.NextAttempt)
+}
+
+// Failures returns the number of consecutive failures of the peer.
+func (s *Scores) Failures(host string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if h, exists := s.health[host]; exists {
+		return h.Failures
+	}
+	return 0
+}
+
+// Prune removes the records not updated for a day that aren't banned, and
+// returns how many were removed. Their backoff and points already expired.
+func (s *Scores) Prune() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+
+	var pruned int
+	for host, h := range s.health {
+		if !h.Banned(now) && now.Sub(h.lastUpdate) > healthIdleAge {
+			delete(s.health, host)
+			pruned++
+		}
+	}
+
+	return pruned
+}
+
+// Len returns the number of health records.
+func (s *Scores) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return len(s.health)
+}
+
+// Copy returns the health records sorted by host.
+func (s *Scores) Copy() []Health {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	health := make([]Health, 0, len(s.health))
+	for _, h := range s.health {
+		health = append(health, *h)
+	}
+
+	sort.Slice(health, func(i, j int) bool {
+		return health[i].Host < health[j].Host
+	})
+
+	return health
+}
+
+// get returns the record of the peer, creating it when needed. When the
+// records are full, the least recently updated record that isn't banned makes
+// room for it. The lock must be held by the caller.
+func (s *Scores) get(host string) *Health {
+	now := time.Now()
+
+	h, exists := s.health[host]
+	if !exists {
+		if len(s.health) >= maxHealth {
+			s.evict(now)
+		}
+		h = &Health{Host: host}
+
+		// With every record banned, the new record is used once and
+		// dropped so the bans are kept.
+		if len(s.health) < maxHealth {
+			s.health[host] = h
+		}
+	}
+	h.lastUpdate = now
+
+	return h
+}
+
+// evict removes the least recently updated record that isn't banned. The
+// lock must be held by the caller.
+func (s *Scores) evict(now time.Time) {
+	var oldest *Health
+	for _, h := range s.health {
+		if h.Banned(now) {
+			continue
+		}
+		if oldest == nil || h.lastUpdate.Before(oldest.lastUpdate) {
+			oldest = h
+		}
+	}
+
+	if oldest != nil {
+		delete(s.health, oldest.Host)
+	}
+}
@@ -0,0 +1,51 @@
+package peer
+
+import (
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestScoresCapped(t *testing.T) {
+	scores := NewScores()
+
+	scores.Ban("banned", 0, "test")
+	for i := 0; i < maxHealth+10; i++ {
+		scores.Failure("host" + strconv.Itoa(i))
+	}
+
+	if n := scores.Len(); n != maxHealth {
+		t.Fatalf("expected the records to be capped to %d, got %d", maxHealth, n)
+	}
+
+	if !scores.Banned("banned") {
+		t.Fatal("expected the ban to survive the eviction")
+	}
+
+	if scores.Failures("host0") != 0 || scores.Failures("host"+strconv.Itoa(maxHealth+9)) != 1 {
+		t.Fatal("expected the least recently updated records to be evicted")
+	}
+}
+
+func TestScoresPrune(t *testing.T) {
+	scores := NewScores()
+
+	scores.Failure("idle")
+	scores.Failure("recent")
+	scores.Ban("banned", 0, "test")
+
+	// Every record but the recent one goes idle.
+	for _, h := range scores.health {
+		if h.Host != "recent" {
+			h.lastUpdate = time.Now().Add(-healthIdleAge - time.Minute)
+		}
+	}
+
+	if pruned := scores.Prune(); pruned != 1 {
+		t.Fatalf("expected 1 record pruned, got %d", pruned)
+	}
+
+	if scores.Failures("idle") != 0 || scores.Failures("recent") != 1 || !scores.Banned("banned") {
+		t.Fatal("expected only the idle record that isn't banned to be pruned")
+	}
+}
@@ -0,0 +1,72 @@
+package state
+
+import (
+	"time"
+
+	"github.com/ardanlabs/blockchain/foundation/blockchain/database"
+	"github.com/ardanlabs/blockchain/foundation/blockchain/peer"
+	"github.com/ardanlabs/blockchain/foundation/events"
+)
+
+// CORE NOTE: These functions let an operator change a running node. They work
+// the same as what the node does on its own: a banned peer is treated like one
+// banned for misbehavior and a flushed transaction like one dropped from the
+// mempool, so the events and the transaction history tell what happened.
+
+// RemovePeer removes the peer from the active peers and closes its stream.
+// The peer can come back when another node shares it.
+func (s *State) RemovePeer(host string) bool {
+	s.streams.Disconnect(host)
+	return s.removePeer(peer.New(host), "removed by operator")
+}
+
+// BanPeer bans the peer for the duration and removes it. A zero duration bans
+// the peer for good.
+func (s *State) BanPeer(host string, d time.Duration, reason string) {
+	s.evHandler("state: BanPeer: peer %s: BANNED for %v: %s", host, d, reason)
+
+	s.scores.Ban(host, d, reason)
+	s.streams.Disconnect(host)
+	s.removePeer(peer.New(host), "banned: "+reason)
+}
+
+// UnbanPeer lifts the ban of the peer. It's added back once another node
+// shares it or it's picked from the address book.
+func (s *State) UnbanPeer(host string) {
+	s.evHandler("state: UnbanPeer: peer %s", host)
+
+	s.scores.Unban(host)
+}
+
+// FlushMempool removes every transaction from the mempool and returns the
+// number removed.
+func (s *State) FlushMempool() int {
+	txs := s.mempool.RemoveIf(func(tx database.BlockTx) bool { return true })
+
+	for _, tx := range txs {
+		s.recordChange(tx, TxDropped, "", "mempool flushed by operator")
+		s.events.Publish(events.TxEvicted{Tx: toEventTx(tx), Reason: events.EvictDropped})
+	}
+
+	s.evHandler("state: FlushMempool: removed %d transactions", len(txs))
+
+	return len(txs)
+}
+
+// SelectStrategy returns the name of the strategy selecting the transactions
+// of a new block.
+func (s *State) SelectStrategy() string {
+	return s.mempool.Strategy()
+}
+
+// SetSelectStrategy changes the strategy selecting the transactions of a new
+// block.
+func (s *State) SetSelectStrategy(strategy string) error {
+	if err := s.mempool.SetStrategy(strategy); err != nil {
+		return err
+	}
+
+	s.evHandler("state: SetSelectStrategy: strategy is now %s", strategy)
+
+	return nil
+}
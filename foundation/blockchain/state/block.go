@@ -3,7 +3,9 @@ package state
 import (
 	"context"
 	"errors"
+	"time"
 
+	"github.com/ardanlabs/blockchain/foundation/blockchain/consensus"
 	"github.com/ardanlabs/blockchain/foundation/blockchain/database"
 )
 
@@ -14,57 +16,117 @@ func (s *State) MineNewBlock(ctx context.Context) (database.Block, error) {
 
 	s.evHandler("viewer: MineNewBlock: MINING checking mempool")
 
-	if s.mempool.Count() == 0 {
+	// An empty block is only produced as a heartbeat.
+	if s.mempool.Count() == 0 && !s.HeartbeatDue() {
 		return database.Block{}, ErrNoTransactions
 	}
 
 	trans := s.mempool.PickBest(s.genesis.TransPerBlock)
 
-	// If PoA is being used drop diffulty to 1.
-	difficulty := s.genesis.Difficulty
-	if s.Consensus() == ConsensusPoA {
-		difficulty = 1
-	}
-
 	s.evHandler("viewer: MineNewBlock: MINING creating new block")
 
-	block, err := database.POW(ctx, database.POWArgs{
+	block, err := database.NewBlock(database.BlockArgs{
 		BeneficiaryID: s.beneficiaryID,
-		Difficulty:    difficulty,
+		Difficulty:    s.genesis.Difficulty,
 		MiningReward:  s.genesis.MiningReward,
 		PrevBlock:     s.db.LatestBlock(),
 		StateRoot:     s.db.HashState(),
 		Trans:         trans,
-		EvHandler:     s.evHandler,
 	})
 	if err != nil {
 		return database.Block{}, err
 	}
 
+	// The engine decides if this node can produce the block and sets the
+	// consensus fields of the header before it gets sealed.
+	if err := s.engine.PrepareHeader(s.db, &block.Header); err != nil {
+		return database.Block{}, err
+	}
+
+	start := time.Now()
+
+	if err := s.engine.Seal(ctx, &block); err != nil {
+		return database.Block{}, err
+	}
+
 	if ctx.Err() != nil {
 		return database.Block{}, ctx.Err()
 	}
+
 	s.evHandler("viewer: MineNewBlock: MINING adding new block to database")
 
 	if err := s.validateUpdateDatabase(block); err != nil {
 		return database.Block{}, err
 	}
+
+	s.publishBlockMined(block, time.Since(start))
+
 	return block, nil
 }
 
-func (s *State) ProcessProposedBlock(block database.Block) error {
+// NextBlockTime returns the earliest time a block can be mined on demand based
+// on the minimum block interval.
+func (s *State) NextBlockTime() time.Time {
+	return s.latestBlockTime().Add(s.minBlockInterval)
+}
+
+// NextHeartbeat returns the time an empty block needs to be produced if no
+// other block is added before. The zero time is returned when heartbeat blocks
+// are disabled.
+func (s *State) NextHeartbeat() time.Time {
+	if s.heartbeatInterval <= 0 {
+		return time.Time{}
+	}
+	return s.latestBlockTime().Add(s.heartbeatInterval)
+}
+
+// HeartbeatDue reports if an empty block should be produced.
+func (s *State) HeartbeatDue() bool {
+	next := s.NextHeartbeat()
+	return !next.IsZero() && !time.Now().Before(next)
+}
+
+// ProcessProposedBlock accepts a block proposed by another node. The from is
+// the address the block came from, the sender is penalized by it when the
+// block is invalid.
+func (s *State) ProcessProposedBlock(from string, block database.Block) error {
+	return s.processProposedBlock(from, block)
+}
+
+// processBlock validates the block and adds it to the chain.
+func (s *State) processBlock(block database.Block) error {
 	s.evHandler("state: ProcessProposedBlock: started: prevBlk[%d]: newBlk[%d]: numTx in block[%d]", s.db.LatestBlock().Header.Number, block.Header.Number, len(block.MerkleTree.Values()))
 	defer s.evHandler("state: ProcessProposedBlock: completed: newBlock[%d] added", block.Header.Number)
 
+	// A block received during a sync may carry its finality certificate. It is
+	// only stored once the certificate itself has been verified.
+	cert := block.Cert
+	block.Cert = nil
+
 	if err := s.validateUpdateDatabase(block); err != nil {
+		if s.engine.Name() == consensus.PoS {
+			s.checkDoubleSign(block)
+		}
 		return err
 	}
 
+	if cert != nil {
+		if err := s.processCert(*cert); err != nil {
+			s.evHandler("state: ProcessProposedBlock: WARNING invalid certificate for blk[%d]: %s", block.Header.Number, err)
+		}
+	}
+
 	s.Worker.SignalCancelMining()
 	return nil
 }
 
 // =============================================================================
+
+// latestBlockTime returns the time the latest block was mined.
+func (s *State) latestBlockTime() time.Time {
+	return time.UnixMilli(int64(s.db.LatestBlock().Header.Timestamp))
+}
+
 func (s *State) validateUpdateDatabase(block database.Block) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
@@ -75,6 +137,16 @@ func (s *State) validateUpdateDatabase(block database.Block) error {
 	// If it was mined by this, even if a peer beat me to this function for the same block
 	// number, I could replace the peer block with my own.
 
+	// Finalized blocks can never be replaced, so anything at or below that
+	// height is refused before any other validation.
+	if block.Header.Number <= s.db.Finalized().Number {
+		return database.ErrBelowFinalized
+	}
+
+	if err := s.engine.VerifySeal(s.db, block, s.db.LatestBlock()); err != nil {
+		return err
+	}
+
 	if err := block.ValidateBlock(s.db.LatestBlock(), s.db.HashState(), s.evHandler); err != nil {
 		return err
 	}
@@ -84,6 +156,10 @@ func (s *State) validateUpdateDatabase(block database.Block) error {
 		return err
 	}
 
+	// The accounts are compared once the block is applied to publish the
+	// balances that changed.
+	before := s.touchedAccounts(block)
+
 	// Update the state with the new block.
 	s.db.UpdateLatestBlock(block)
 
@@ -94,7 +170,7 @@ func (s *State) validateUpdateDatabase(block database.Block) error {
 	for _, tx := range block.MerkleTree.Values() {
 		s.evHandler("state: validateUpdateDatabase: UPDATING state with tx [%s]", tx)
 
-		s.mempool.Delete(tx)
+		s.evictMempool(tx)
 
 		if err := s.db.ApplyTransaction(block, tx); err != nil {
 			s.evHandler("state: validateUpdateDatabase: ERROR [%s]", err)
@@ -105,6 +181,15 @@ func (s *State) validateUpdateDatabase(block database.Block) error {
 	s.evHandler("state: validateUpdateDatabase: applying Mining Reward")
 
 	s.db.ApplyMiningReward(block)
+	s.db.ReleaseUnbonded(block)
+
+	s.dropStaleMempool()
+
+	s.db.CaptureSnapshot(block)
+
+	s.publishBlockAccepted(block, before)
+
+	s.prevote(block)
 
 	return nil
 }
@@ -0,0 +1,181 @@
+package state
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/ardanlabs/blockchain/foundation/blockchain/database"
+	"github.com/ardanlabs/blockchain/foundation/blockchain/p2p"
+	"github.com/ardanlabs/blockchain/foundation/blockchain/peer"
+)
+
+// CORE NOTE: A new block is sent to every peer at the same time so a slow or
+// dead peer doesn't hold up the others. Each peer gets its own timeout and
+// the outcome is recorded per peer. When most peers reject the block, this
+// node is likely behind or on a fork of its own, so it asks the worker to sync
+// with the network again.
+
+// blockSendTimeout is how long a peer has to accept a block.
+const blockSendTimeout = 10 * time.Second
+
+// Set of outcomes of sending a block to a peer.
+const (
+	BroadcastAccepted    = "accepted"
+	BroadcastRejected    = "rejected"
+	BroadcastUnreachable = "unreachable"
+)
+
+// BroadcastResult is the outcome of sending a block to a peer.
+type BroadcastResult struct {
+	Host     string        `json:"host"`
+	Status   string        `json:"status"`
+	Reason   string        `json:"reason,omitempty"`
+	Duration time.Duration `json:"duration"`
+}
+
+// BroadcastStats are the totals of the block broadcasts made by the node.
+type BroadcastStats struct {
+	Broadcasts  uint64            `json:"broadcasts"`
+	Accepted    uint64            `json:"accepted"`
+	Rejected    uint64            `json:"rejected"`
+	Unreachable uint64            `json:"unreachable"`
+	Resyncs     uint64            `json:"resyncs"`
+	Last        []BroadcastResult `json:"last"`
+}
+
+// NetSendBlockToPeers sends the block to the peers that aren't known to have
+// it and returns the outcome for each peer.
+func (s *State) NetSendBlockToPeers(block database.Block) []BroadcastResult {
+	s.evHandler("state: NetSendBlockToPeers: started")
+	defer s.evHandler("state: NetSendBlockToPeers: completed")
+
+	// A block mined by this node is marked as seen so it isn't accepted again
+	// when a peer relays it back.
+	blockData := database.NewBlockData(block)
+	s.seen.Add(blockData.Hash, "")
+
+	var peers []peer.Peer
+	for _, pr := range s.KnowExternalPeers() {
+		if !s.seen.KnownBy(blockData.Hash, pr.Host) {
+			peers = append(peers, pr)
+		}
+	}
+
+	results := make([]BroadcastResult, len(peers))
+
+	var wg sync.WaitGroup
+	wg.Add(len(peers))
+	for i, pr := range peers {
+		go func(i int, pr peer.Peer) {
+			defer wg.Done()
+
+			s.evHandler("state: NetSendBlockToPeers: sending block[%s] to peer %s", blockData.Hash, pr.Host)
+			results[i] = s.netSendBlock(pr, blockData)
+		}(i, pr)
+	}
+	wg.Wait()
+
+	var rejected int
+	for _, result := range results {
+		switch result.Status {
+		case BroadcastAccepted:
+			s.seen.Add(blockData.Hash, result.Host)
+			s.evHandler("state: NetSendBlockToPeers: peer %s: block[%d] accepted in %v", result.Host, block.Header.Number, result.Duration)
+		case BroadcastRejected:
+			rejected++
+			s.seen.Add(blockData.Hash, result.Host)
+			s.evHandler("state: NetSendBlockToPeers: peer %s: block[%d] REJECTED: %s", result.Host, block.Header.Number, result.Reason)
+		default:
+			s.evHandler("state: NetSendBlockToPeers: peer %s: block[%d] UNREACHABLE: %s", result.Host, block.Header.Number, result.Reason)
+		}
+	}
+
+	resync := len(results) > 0 && rejected*2 > len(results)
+	s.recordBroadcast(results, resync)
+
+	if resync {
+		s.evHandler("state: NetSendBlockToPeers: block[%d] rejected by %d of %d peers, resync", block.Header.Number, rejected, len(results))
+		s.Worker.SignalResync()
+	}
+
+	return results
+}
+
+// BroadcastStats returns the totals of the block broadcasts.
+func (s *State) BroadcastStats() BroadcastStats {
+	s.bmu.Lock()
+	defer s.bmu.Unlock()
+
+	stats := s.broadcast
+	stats.Last = append([]BroadcastResult(nil), s.broadcast.Last...)
+	return stats
+}
+
+// =============================================================================
+
+// netSendBlock sends the block to the peer. Over a stream the block is
+// announced and the peer replies once it processed the block.
+func (s *State) netSendBlock(pr peer.Peer, blockData database.BlockData) BroadcastResult {
+	ctx, cancel := context.WithTimeout(context.Background(), blockSendTimeout)
+	defer cancel()
+
+	start := time.Now()
+	result := BroadcastResult{
+		Host: pr.Host,
+	}
+
+	var err error
+	if _, exists := s.streams.Conn(pr.Host); exists {
+		var msg, resp p2p.Message
+		msg, err = p2p.NewMessage(p2p.MsgInv, p2p.Inv{Blocks: []string{blockData.Hash}})
+		if err == nil {
+			resp, err = s.streams.Request(ctx, pr.Host, msg)
+		}
+		if err == nil && resp.Type == p2p.MsgError {
+			err = &ResponseError{Message: resp.Decode(nil).Error()}
+		}
+	} else {
+		err = s.sendContext(ctx, http.MethodPost, pr.Url()+peer.BlockSubmitUri, blockData, nil)
+	}
+
+	result.Duration = time.Since(start)
+
+	var re *ResponseError
+	switch {
+	case err == nil:
+		result.Status = BroadcastAccepted
+	case errors.As(err, &re):
+		result.Status = BroadcastRejected
+		result.Reason = re.Message
+	default:
+		result.Status = BroadcastUnreachable
+		result.Reason = err.Error()
+	}
+
+	return result
+}
+
+// recordBroadcast adds the results to the broadcast totals.
+func (s *State) recordBroadcast(results []BroadcastResult, resync bool) {
+	s.bmu.Lock()
+	defer s.bmu.Unlock()
+
+	s.broadcast.Broadcasts++
+	for _, result := range results {
+		switch result.Status {
+		case BroadcastAccepted:
+			s.broadcast.Accepted++
+		case BroadcastRejected:
+			s.broadcast.Rejected++
+		default:
+			s.broadcast.Unreachable++
+		}
+	}
+	if resync {
+		s.broadcast.Resyncs++
+	}
+	s.broadcast.Last = results
+}
@@ -0,0 +1,193 @@
+package state
+
+import (
+	"time"
+
+	"github.com/ardanlabs/blockchain/foundation/blockchain/consensus"
+	"github.com/ardanlabs/blockchain/foundation/blockchain/database"
+	"github.com/ardanlabs/blockchain/foundation/blockchain/peer"
+	"github.com/ardanlabs/blockchain/foundation/events"
+)
+
+// CORE NOTE: Everything the node does is published on the event bus. The typed
+// events describe the changes to the chain, the mempool and the peers so the
+// logger, the metrics and the API streams can react to them. The rest of the
+// activity is published as trace messages through the event handler of each
+// package. The balances are compared only for the accounts a block touched.
+
+// Events returns the bus the state publishes its events on.
+func (s *State) Events() *events.Events {
+	return s.events
+}
+
+// =============================================================================
+
+// publishBlockMined publishes a block produced by this node. The duration is
+// the time spent sealing the block, zero when it's unknown.
+func (s *State) publishBlockMined(block database.Block, duration time.Duration) {
+	s.events.Publish(events.BlockMined{
+		Number:   block.Header.Number,
+		Hash:     block.Hash(),
+		TxCount:  len(block.MerkleTree.Values()),
+		Duration: duration,
+	})
+}
+
+// publishBlockAccepted publishes the block added to the chain and the changes
+// it made to the accounts it touched.
+func (s *State) publishBlockAccepted(block database.Block, before map[database.AccountID]database.Account) {
+	values := block.MerkleTree.Values()
+
+	txs := make([]events.Tx, len(values))
+	for i, tx := range values {
+		txs[i] = toEventTx(tx)
+	}
+
+	s.events.Publish(events.BlockAccepted{
+		Number:        block.Header.Number,
+		Hash:          block.Hash(),
+		PrevBlockHash: block.Header.PrevBlockHash,
+		Timestamp:     block.Header.Timestamp,
+		Beneficiary:   string(block.Header.BeneficiaryID),
+		Txs:           txs,
+	})
+
+	for accountID, prev := range before {
+		account, _ := s.db.GetAccount(accountID)
+		if account.Balance == prev.Balance && account.Stake == prev.Stake && account.Nonce == prev.Nonce {
+			continue
+		}
+
+		s.events.Publish(events.BalanceChanged{
+			Account:     string(accountID),
+			Balance:     account.Balance,
+			Stake:       account.Stake,
+			Nonce:       account.Nonce,
+			BlockNumber: block.Header.Number,
+		})
+	}
+}
+
+// publishBlockRejected publishes a block from the peer at the host that
+// wasn't added to the chain.
+func (s *State) publishBlockRejected(from string, block database.Block, err error) {
+	s.events.Publish(events.BlockRejected{
+		Number: block.Header.Number,
+		Hash:   block.Hash(),
+		Peer:   from,
+		Reason: err.Error(),
+	})
+}
+
+// touchedAccounts returns the current state of the accounts the block can
+// change. Under PoS a slash transaction changes the stake of the offender and
+// any block can return unbonding stake to the balance of its account.
+func (s *State) touchedAccounts(block database.Block) map[database.AccountID]database.Account {
+	ids := []database.AccountID{block.Header.BeneficiaryID}
+	for _, tx := range block.MerkleTree.Values() {
+		ids = append(ids, tx.FromID, tx.ToID)
+	}
+
+	if s.engine.Name() == consensus.PoS {
+		for _, account := range s.db.GetAccounts() {
+			if account.Stake > 0 || account.Unbonding > 0 {
+				ids = append(ids, account.AccountID)
+			}
+		}
+	}
+
+	accounts := make(map[database.AccountID]database.Account, len(ids))
+	for _, accountID := range ids {
+		account, _ := s.db.GetAccount(accountID)
+		accounts[accountID] = account
+	}
+
+	return accounts
+}
+
+// upsertMempool adds the transaction to the mempool and publishes it along
+// with the transaction it replaced.
+func (s *State) upsertMempool(tx database.BlockTx) error {
+	if tx.ToID == database.StakingAccountID && s.engine.Name() != consensus.PoS {
+		return database.ErrStakingDisabled
+	}
+
+	etx, replaced, err := s.mempool.Upsert(tx)
+	if err != nil {
+		return err
+	}
+
+	if replaced && etx.HashString() != tx.HashString() {
+		s.recordChange(etx, TxReplaced, tx.HashString(), "replaced by a transaction with a higher tip")
+		s.events.Publish(events.TxEvicted{Tx: toEventTx(etx), Reason: events.EvictReplaced})
+	}
+
+	s.events.Publish(events.TxAdded{Tx: toEventTx(tx)})
+
+	return nil
+}
+
+// evictMempool removes the transaction from the same account with the same
+// nonce as the committed transaction and publishes it.
+func (s *State) evictMempool(tx database.BlockTx) {
+	etx, exists := s.mempool.Remove(tx)
+	if !exists {
+		return
+	}
+
+	reason := events.EvictCommitted
+	if etx.HashString() != tx.HashString() {
+		reason = events.EvictReplaced
+		s.recordChange(etx, TxReplaced, tx.HashString(), "another transaction with the same nonce was mined")
+	}
+
+	s.events.Publish(events.TxEvicted{Tx: toEventTx(etx), Reason: reason})
+}
+
+// dropStaleMempool removes the transactions with a nonce the sender already
+// used in the chain, since they can never be applied, and publishes them.
+func (s *State) dropStaleMempool() {
+	stale := s.mempool.RemoveIf(func(tx database.BlockTx) bool {
+		account, err := s.db.Query(tx.FromID)
+		return err == nil && tx.Nonce <= account.Nonce
+	})
+
+	for _, tx := range stale {
+		s.recordChange(tx, TxDropped, "", "nonce already used")
+		s.events.Publish(events.TxEvicted{Tx: toEventTx(tx), Reason: events.EvictDropped})
+	}
+}
+
+// addPeer adds the peer to the active peers and publishes it when new.
+func (s *State) addPeer(pr peer.Peer) bool {
+	if !s.knownPeers.Add(pr) {
+		return false
+	}
+
+	s.events.Publish(events.PeerAdded{Host: pr.Host, NodeID: pr.ID})
+
+	return true
+}
+
+// removePeer removes the peer from the active peers and publishes it when it
+// was there.
+func (s *State) removePeer(pr peer.Peer, reason string) bool {
+	if !s.knownPeers.Remove(pr) {
+		return false
+	}
+
+	s.events.Publish(events.PeerRemoved{Host: pr.Host, Reason: reason})
+
+	return true
+}
+
+func toEventTx(tx database.BlockTx) events.Tx {
+	return events.Tx{
+		Hash:  tx.HashString(),
+		From:  string(tx.FromID),
+		To:    string(tx.ToID),
+		Nonce: tx.Nonce,
+		Value: tx.Value,
+		Tip:   tx.Tip,
+	}
+}
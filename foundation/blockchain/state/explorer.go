@@ -0,0 +1,137 @@
+package state
+
+import (
+	"errors"
+
+	"github.com/ardanlabs/blockchain/foundation/blockchain/database"
+)
+
+// CORE NOTE: The explorer queries read the committed chain for the public API.
+// Transactions are found through the index the database keeps of the stored
+// blocks, so a lookup by hash or account doesn't walk the chain. A transaction
+// still in the mempool is reported as pending with no confirmations.
+
+// TxRecord is a transaction with its position in the chain.
+type TxRecord struct {
+	Tx            database.BlockTx
+	BlockNumber   uint64
+	BlockHash     string
+	Index         int
+	Confirmations uint64
+	Pending       bool
+}
+
+// QueryRecentBlocks returns a page of blocks, newest first, along with the
+// total number of blocks. Pages start at 1. The blocks pruned by a snapshot
+// restore are not part of the pages.
+func (s *State) QueryRecentBlocks(page int, rows int) ([]database.Block, uint64, error) {
+	if page < 1 || rows < 1 {
+		return nil, 0, errors.New("page and rows must be greater than zero")
+	}
+
+	latest := s.db.LatestBlock().Header.Number
+	pruned := s.db.Pruned()
+	total := latest - pruned
+
+	skip := uint64(page-1) * uint64(rows)
+	if skip >= total {
+		return []database.Block{}, total, nil
+	}
+
+	to := latest - skip
+	from := pruned + 1
+	if to-pruned > uint64(rows) {
+		from = to - uint64(rows) + 1
+	}
+
+	blocks, err := s.QueryBlocksByNumber(from, to)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	for i, j := 0, len(blocks)-1; i < j; i, j = i+1, j-1 {
+		blocks[i], blocks[j] = blocks[j], blocks[i]
+	}
+
+	return blocks, total, nil
+}
+
+// QueryTx returns the transaction with the specified hash from the chain or,
+// when not committed yet, from the mempool.
+func (s *State) QueryTx(hash string) (TxRecord, error) {
+	loc, err := s.db.GetTxLocation(hash)
+	if err != nil {
+		if txs := s.QueryMempoolTxs([]string{hash}); len(txs) == 1 {
+			return TxRecord{Tx: txs[0], Pending: true}, nil
+		}
+		return TxRecord{}, err
+	}
+
+	block, err := s.db.GetBlock(loc.BlockNumber)
+	if err != nil {
+		return TxRecord{}, err
+	}
+
+	return s.txRecord(block, loc.Index), nil
+}
+
+// QueryAccountTxs returns a page of the committed transactions sent or
+// received by the account, newest first, along with the total number of them.
+func (s *State) QueryAccountTxs(accountID database.AccountID, page int, rows int) ([]TxRecord, int, error) {
+	if page < 1 || rows < 1 {
+		return nil, 0, errors.New("page and rows must be greater than zero")
+	}
+
+	hashes := s.db.GetAccountTxs(accountID)
+	total := len(hashes)
+
+	skip := (page - 1) * rows
+	if skip >= total {
+		return []TxRecord{}, total, nil
+	}
+
+	end := total - skip
+	start := end - rows
+	if start < 0 {
+		start = 0
+	}
+
+	// The blocks are read once even when they hold several of the
+	// transactions on the page.
+	blocks := make(map[uint64]database.Block)
+
+	records := make([]TxRecord, 0, end-start)
+	for i := end - 1; i >= start; i-- {
+		loc, err := s.db.GetTxLocation(hashes[i])
+		if err != nil {
+			return nil, 0, err
+		}
+
+		block, exists := blocks[loc.BlockNumber]
+		if !exists {
+			if block, err = s.db.GetBlock(loc.BlockNumber); err != nil {
+				return nil, 0, err
+			}
+			blocks[loc.BlockNumber] = block
+		}
+
+		records = append(records, s.txRecord(block, loc.Index))
+	}
+
+	return records, total, nil
+}
+
+// =============================================================================
+
+// txRecord builds the record for the transaction at the index in the block.
+func (s *State) txRecord(block database.Block, index int) TxRecord {
+	latest := s.db.LatestBlock().Header.Number
+
+	return TxRecord{
+		Tx:            block.MerkleTree.Values()[index],
+		BlockNumber:   block.Header.Number,
+		BlockHash:     block.Hash(),
+		Index:         index,
+		Confirmations: latest - block.Header.Number + 1,
+	}
+}
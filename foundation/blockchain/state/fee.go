@@ -0,0 +1,107 @@
+package state
+
+import (
+	"sort"
+)
+
+// CORE NOTE: When a block is full the selector picks the transactions with the
+// highest tips, so the tip needed to get into a block depends on the demand.
+// The estimate looks at two things. The mempool tells how many transactions
+// are already waiting: to be mined within N blocks a transaction has to
+// outbid the ones beyond the first N blocks worth. The recent blocks tell what
+// the lowest tip in a full block has been, since that's what it took to get
+// in. The estimate is the higher of the two.
+
+// feeBlocks is the number of recent blocks the estimate looks at.
+const feeBlocks = 20
+
+// Set of confirmation targets a tip is recommended for, in blocks.
+const (
+	TargetNextBlock = 1
+	TargetFast      = 3
+	TargetSlow      = 10
+)
+
+// feePercentile is the share of the recent full blocks a tip must have been
+// high enough for, per target. A closer target asks for more certainty.
+var feePercentile = map[int]int{
+	TargetNextBlock: 90,
+	TargetFast:      60,
+	TargetSlow:      25,
+}
+
+// FeeEstimate is the tip recommended to get a transaction mined within a
+// number of blocks.
+type FeeEstimate struct {
+	NextBlock     uint64
+	Fast          uint64
+	Slow          uint64
+	MempoolDepth  int
+	TransPerBlock uint16
+	BlocksSampled int
+}
+
+// EstimateTip recommends the tips for the confirmation targets based on the
+// mempool and the recent blocks.
+func (s *State) EstimateTip() (FeeEstimate, error) {
+	perBlock := int(s.genesis.TransPerBlock)
+
+	mempool := s.mempool.PickBest()
+	tips := make([]uint64, len(mempool))
+	for i, tx := range mempool {
+		tips[i] = tx.Tip
+	}
+	sort.Slice(tips, func(i, j int) bool { return tips[i] > tips[j] })
+
+	blocks, _, err := s.QueryRecentBlocks(1, feeBlocks)
+	if err != nil {
+		return FeeEstimate{}, err
+	}
+
+	// A block that wasn't full took any tip, so its price of entry is zero.
+	minTips := make([]uint64, len(blocks))
+	for i, block := range blocks {
+		values := block.MerkleTree.Values()
+		if len(values) < perBlock {
+			continue
+		}
+
+		minTip := values[0].Tip
+		for _, tx := range values[1:] {
+			if tx.Tip < minTip {
+				minTip = tx.Tip
+			}
+		}
+		minTips[i] = minTip
+	}
+	sort.Slice(minTips, func(i, j int) bool { return minTips[i] < minTips[j] })
+
+	estimate := func(target int) uint64 {
+		var tip uint64
+
+		// Outbid the transactions that don't fit in the target blocks.
+		if capacity := target * perBlock; capacity > 0 && len(tips) >= capacity {
+			tip = tips[capacity-1] + 1
+		}
+
+		if len(minTips) > 0 {
+			i := (len(minTips)*feePercentile[target] + 99) / 100
+			if recent := minTips[i-1]; recent > tip {
+				tip = recent
+			}
+		}
+
+		return tip
+	}
+
+	fe := FeeEstimate{
+		NextBlock:     estimate(TargetNextBlock),
+		Fast:          estimate(TargetFast),
+		Slow:          estimate(TargetSlow),
+		MempoolDepth:  len(tips),
+		TransPerBlock: s.genesis.TransPerBlock,
+		BlocksSampled: len(blocks),
+	}
+
+	return fe, nil
+}
@@ -0,0 +1,168 @@
+package state
+
+import (
+	"errors"
+	"sort"
+
+	"github.com/ardanlabs/blockchain/foundation/blockchain/consensus"
+	"github.com/ardanlabs/blockchain/foundation/blockchain/database"
+)
+
+// Finalized returns the certificate of the latest finalized block.
+func (s *State) Finalized() database.FinalityCert {
+	return s.db.Finalized()
+}
+
+// ProcessVote records a prevote or precommit received from an authority and
+// checks if the vote completes a quorum.
+func (s *State) ProcessVote(vote database.SignedVote) error {
+	if !s.finalityEnabled() {
+		return errors.New("finality is not enabled on this node")
+	}
+
+	if err := vote.Validate(s.genesis.ChainID); err != nil {
+		return err
+	}
+
+	// Votes for blocks that are already final have nothing left to decide.
+	if vote.Number <= s.db.Finalized().Number {
+		return nil
+	}
+
+	s.fmu.Lock()
+	defer s.fmu.Unlock()
+
+	added, err := s.votes.Add(vote)
+	if err != nil {
+		return err
+	}
+
+	if !added {
+		return nil
+	}
+
+	s.evHandler("state: ProcessVote: received vote[%s] blk[%s]", vote, vote.BlockHash)
+
+	s.checkQuorum(vote.Number)
+
+	return nil
+}
+
+// =============================================================================
+
+func (s *State) finalityEnabled() bool {
+	return s.engine.Name() == consensus.PoA && s.votes.Enabled()
+}
+
+// prevote is called when a block has been accepted into the local chain. An
+// authority only prevotes for the first block it accepts at a height.
+func (s *State) prevote(block database.Block) {
+	if !s.finalityEnabled() {
+		return
+	}
+
+	s.fmu.Lock()
+	defer s.fmu.Unlock()
+
+	s.castVote(database.VotePrevote, block)
+	s.checkQuorum(block.Header.Number)
+}
+
+// castVote signs and shares a vote if this node is an authority that has not
+// voted yet. The finality lock must be held by the caller.
+func (s *State) castVote(voteType database.VoteType, block database.Block) {
+	if s.beneficiaryKey == nil || !s.votes.IsAuthority(s.beneficiaryID) {
+		return
+	}
+
+	if s.votes.HasVoted(s.beneficiaryID, block.Header.Number, voteType) {
+		return
+	}
+
+	vote, err := database.NewVote(s.genesis.ChainID, voteType, block, s.beneficiaryID).Sign(s.beneficiaryKey)
+	if err != nil {
+		s.evHandler("state: castVote: ERROR signing vote: %s", err)
+		return
+	}
+
+	if _, err := s.votes.Add(vote); err != nil {
+		s.evHandler("state: castVote: ERROR recording vote: %s", err)
+		return
+	}
+
+	s.evHandler("state: castVote: vote[%s] blk[%s]", vote, vote.BlockHash)
+
+	s.Worker.SignalShareVote(vote)
+}
+
+// checkQuorum moves the block at the specified height through the precommit
+// and finalize steps once enough votes are known. The finality lock must be
+// held by the caller.
+func (s *State) checkQuorum(number uint64) {
+	if number <= s.db.Finalized().Number {
+		return
+	}
+
+	// Votes can arrive before the block does. The quorum is checked again
+	// when the block is accepted.
+	block, err := s.db.GetBlock(number)
+	if err != nil {
+		return
+	}
+	hash := block.Hash()
+
+	if prevoteHash, _, ok := s.votes.Quorum(number, database.VotePrevote); ok && prevoteHash == hash {
+		s.castVote(database.VotePrecommit, block)
+	}
+
+	precommitHash, precommits, ok := s.votes.Quorum(number, database.VotePrecommit)
+	if !ok {
+		return
+	}
+
+	if precommitHash != hash {
+		s.evHandler("state: checkQuorum: WARNING blk[%d] finalized as [%s] which conflicts with the local chain, resync required", number, precommitHash)
+		return
+	}
+
+	sort.Slice(precommits, func(i, j int) bool {
+		return precommits[i].VoterID < precommits[j].VoterID
+	})
+
+	s.finalize(database.FinalityCert{
+		Number:     number,
+		BlockHash:  hash,
+		Precommits: precommits,
+	})
+}
+
+// processCert finalizes a block using a certificate received from a peer.
+func (s *State) processCert(cert database.FinalityCert) error {
+	if err := cert.Validate(s.genesis.ChainID, s.db.Authorities()); err != nil {
+		return err
+	}
+
+	s.fmu.Lock()
+	defer s.fmu.Unlock()
+
+	if cert.Number <= s.db.Finalized().Number {
+		return nil
+	}
+
+	s.finalize(cert)
+
+	return nil
+}
+
+// finalize stores the certificate and drops the votes that are no longer
+// needed. The finality lock must be held by the caller.
+func (s *State) finalize(cert database.FinalityCert) {
+	if err := s.db.Finalize(cert); err != nil {
+		s.evHandler("state: finalize: ERROR blk[%d]: %s", cert.Number, err)
+		return
+	}
+
+	s.votes.Prune(cert.Number)
+
+	s.evHandler("state: finalize: FINALIZED blk[%d] hash[%s] precommits[%d]", cert.Number, cert.BlockHash, len(cert.Precommits))
+}
@@ -0,0 +1,179 @@
+package state
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/ardanlabs/blockchain/foundation/blockchain/p2p"
+	"github.com/ardanlabs/blockchain/foundation/blockchain/peer"
+	"github.com/ardanlabs/blockchain/foundation/blockchain/signature"
+	"github.com/ardanlabs/blockchain/foundation/events"
+)
+
+// NodeID returns the id of this node, derived from its identity key.
+func (s *State) NodeID() string {
+	return peer.NodeID(s.nodeKey)
+}
+
+// SignedHandshake returns the handshake of this node signed with its
+// identity key.
+func (s *State) SignedHandshake() (peer.SignedHandshake, error) {
+	hs := peer.NewHandshake(s.nodeKey, s.host, s.genesis.ChainID, s.genesisHash, s.LatestBlock().Header.Number)
+	return hs.Sign(s.nodeKey)
+}
+
+// ValidateHandshake checks the handshake of a peer is for this network and
+// signed by the node it names. It doesn't prove the node is at the host of
+// the handshake, only dialing the host does.
+func (s *State) ValidateHandshake(shs peer.SignedHandshake) error {
+	if err := shs.Validate(s.genesis.ChainID, s.genesisHash); err != nil {
+		return err
+	}
+
+	if shs.Host == "" || shs.NodeID == s.NodeID() {
+		return errors.New("invalid peer handshake")
+	}
+
+	if s.scores.Banned(shs.Host) {
+		return ErrPeerBanned
+	}
+
+	return nil
+}
+
+// AddPeer tracks the peer that sent its handshake once the node at the host
+// of the handshake confirms it's the same node.
+func (s *State) AddPeer(shs peer.SignedHandshake) (peer.Peer, error) {
+	if err := s.ValidateHandshake(shs); err != nil {
+		return peer.Peer{}, err
+	}
+
+	if known, exists := s.knownPeers.Get(shs.Host); exists && known.ID == shs.NodeID {
+		return known, nil
+	}
+
+	pr, err := s.NetVerifyPeer(shs.Host)
+	if err != nil {
+		return peer.Peer{}, err
+	}
+
+	if pr.ID != shs.NodeID {
+		return peer.Peer{}, peer.ErrIdentityMismatch
+	}
+
+	return pr, nil
+}
+
+// NetVerifyPeer asks the node at the host for its signed handshake and tracks
+// the peer by its node id. The handshake must name the host it was requested
+// from, so a node can't claim the host of another node.
+func (s *State) NetVerifyPeer(host string) (peer.Peer, error) {
+	pr := peer.New(host)
+
+	var shs peer.SignedHandshake
+	if err := s.send(http.MethodGet, pr.Url()+peer.HandshakeUri, nil, &shs); err != nil {
+		return peer.Peer{}, err
+	}
+
+	if err := s.ValidateHandshake(shs); err != nil {
+		return peer.Peer{}, err
+	}
+
+	if shs.Host != host {
+		return peer.Peer{}, fmt.Errorf("peer at %s claims host %s", host, shs.Host)
+	}
+
+	if err := s.bindPeer(shs.Peer()); err != nil {
+		return peer.Peer{}, err
+	}
+
+	return shs.Peer(), nil
+}
+
+// NetLearnPeer verifies a peer learned from another node before adding it to
+// the known peers. It reports false when the peer wasn't added.
+func (s *State) NetLearnPeer(pr peer.Peer) bool {
+	if pr.Match(s.host) || !s.scores.Ready(pr.Host) || s.peersFull() {
+		return false
+	}
+
+	if _, exists := s.knownPeers.Get(pr.Host); exists {
+		return false
+	}
+
+	if _, err := s.NetVerifyPeer(pr.Host); err != nil {
+		s.evHandler("state: NetLearnPeer: peer %s: WARNING %s", pr.Host, err)
+		s.scores.Failure(pr.Host)
+		return false
+	}
+
+	return true
+}
+
+// bindPeer tracks a peer by the node id it proved to have at its host.
+func (s *State) bindPeer(pr peer.Peer) error {
+	if s.scores.Banned(pr.Host) {
+		return ErrPeerBanned
+	}
+
+	s.addrBook.Seen(pr)
+
+	if _, exists := s.knownPeers.Get(pr.Host); !exists && s.peersFull() {
+		return ErrTooManyPeers
+	}
+
+	added, err := s.knownPeers.AddVerified(pr)
+	if err != nil {
+		return err
+	}
+
+	if added {
+		s.events.Publish(events.PeerAdded{Host: pr.Host, NodeID: pr.ID})
+		s.evHandler("state: bindPeer: peer %s: node id %s", pr.Host, pr.ID)
+	}
+
+	return nil
+}
+
+// =============================================================================
+
+// identity implements the p2p Identity interface for the streams of the node.
+type identity struct {
+	state *State
+}
+
+func (id identity) Handshake() (p2p.Message, error) {
+	shs, err := id.state.SignedHandshake()
+	if err != nil {
+		return p2p.Message{}, err
+	}
+
+	return p2p.NewMessage(p2p.MsgHandshake, shs)
+}
+
+func (id identity) VerifyHandshake(msg p2p.Message) (string, string, error) {
+	var shs peer.SignedHandshake
+	if err := msg.Decode(&shs); err != nil {
+		return "", "", err
+	}
+
+	if err := id.state.ValidateHandshake(shs); err != nil {
+		return "", "", err
+	}
+
+	if _, exists := id.state.knownPeers.Get(shs.Host); !exists && id.state.peersFull() {
+		return "", "", ErrTooManyPeers
+	}
+
+	return shs.Host, shs.NodeID, nil
+}
+
+func (id identity) Bind(host string, nodeID string) error {
+	return id.state.bindPeer(peer.Peer{Host: host, ID: nodeID})
+}
+
+// genesisHash returns the hash identifying the network of the genesis.
+func genesisHash(gen any) string {
+	return signature.Hash(gen)
+}
@@ -0,0 +1,294 @@
+package state
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/ardanlabs/blockchain/foundation/blockchain/database"
+	"github.com/ardanlabs/blockchain/foundation/blockchain/p2p"
+	"github.com/ardanlabs/blockchain/foundation/blockchain/peer"
+)
+
+// CORE NOTE: Over a stream, new transactions and blocks are announced by hash
+// instead of being pushed. A peer only asks for the bodies it's missing, so a
+// block relayed by several nodes is downloaded once. Blocks are requested in
+// compact form and rebuilt from the mempool of the receiver. The transactions
+// missing from the mempool are requested from the peer, which serves them from
+// its chain once the block is committed. Only when the block still can't be
+// rebuilt is the full block requested. Peers without a stream still get the
+// full transactions and blocks over HTTP.
+
+// maxTxsPerRequest is the maximum number of transactions served per request.
+const maxTxsPerRequest = 1000
+
+// maxFetchesPerPeer is the maximum number of announcements of a peer fetched
+// at the same time. The announcements received past it are dropped, the sync
+// picks up any block missed that way.
+const maxFetchesPerPeer = 4
+
+// errTooManyFetches is returned when an announcement is dropped because the
+// peer already has maxFetchesPerPeer announcements being fetched.
+var errTooManyFetches = errors.New("too many announcements being fetched, dropped")
+
+// QueryMempoolTxs returns the transactions in the mempool with the specified
+// hashes.
+func (s *State) QueryMempoolTxs(hashes []string) []database.BlockTx {
+	found := s.mempool.Lookup(hashes)
+
+	txs := make([]database.BlockTx, 0, len(found))
+	for _, hash := range hashes {
+		if tx, exists := found[hash]; exists {
+			txs = append(txs, tx)
+		}
+	}
+
+	return txs
+}
+
+// QueryTxs returns the transactions with the specified hashes from the
+// mempool or, once committed, from the chain.
+func (s *State) QueryTxs(hashes []string) []database.BlockTx {
+	if len(hashes) > maxTxsPerRequest {
+		hashes = hashes[:maxTxsPerRequest]
+	}
+
+	found := s.mempool.Lookup(hashes)
+
+	// The blocks are read once even when they hold several of the
+	// transactions.
+	blocks := make(map[uint64]database.Block)
+
+	txs := make([]database.BlockTx, 0, len(hashes))
+	for _, hash := range hashes {
+		if tx, exists := found[hash]; exists {
+			txs = append(txs, tx)
+			continue
+		}
+
+		loc, err := s.db.GetTxLocation(hash)
+		if err != nil {
+			continue
+		}
+
+		block, exists := blocks[loc.BlockNumber]
+		if !exists {
+			if block, err = s.db.GetBlock(loc.BlockNumber); err != nil {
+				continue
+			}
+			blocks[loc.BlockNumber] = block
+		}
+
+		if values := block.MerkleTree.Values(); loc.Index < len(values) {
+			txs = append(txs, values[loc.Index])
+		}
+	}
+
+	return txs
+}
+
+// QueryBlockByHash returns the block with the specified hash.
+func (s *State) QueryBlockByHash(hash string) (database.Block, error) {
+	return s.db.GetBlockByHash(hash)
+}
+
+// =============================================================================
+
+// netAnnounce announces the inventory to the peer over its stream or, without
+// a stream, posts the full payload to the HTTP endpoint.
+func (s *State) netAnnounce(pr peer.Peer, inv p2p.Inv, payload any, uri string) error {
+	if _, exists := s.streams.Conn(pr.Host); exists {
+		msg, err := p2p.NewMessage(p2p.MsgInv, inv)
+		if err != nil {
+			return err
+		}
+		return s.streams.Send(pr.Host, msg)
+	}
+
+	return s.send(http.MethodPost, pr.Url()+uri, payload, nil)
+}
+
+// netFetchInventory requests the transactions and blocks announced by the
+// peer that this node doesn't have. The error returned is the reason the
+// first block that couldn't be added was refused.
+func (s *State) netFetchInventory(pr peer.Peer, inv p2p.Inv) error {
+	if txHashes := s.claim(inv.Txs); len(txHashes) > 0 {
+		defer s.release(txHashes)
+
+		txs, err := s.netRequestTxs(pr, txHashes)
+		if err != nil {
+			s.evHandler("state: netFetchInventory: peer %s: txs: WARNING %s", pr.Host, err)
+		}
+
+		for _, tx := range txs {
+			if err := s.upsertNodeTransaction(pr.Host, tx); err != nil {
+				s.evHandler("state: netFetchInventory: peer %s: tx[%s]: WARNING %s", pr.Host, tx.HashString(), err)
+			}
+		}
+	}
+
+	var blockErr error
+	for _, hash := range s.claim(inv.Blocks) {
+		err := s.netFetchBlock(pr, hash)
+		if err != nil {
+			s.evHandler("state: netFetchInventory: peer %s: block[%s]: WARNING %s", pr.Host, hash, err)
+			if blockErr == nil {
+				blockErr = err
+			}
+		}
+		s.release([]string{hash})
+	}
+
+	return blockErr
+}
+
+// netFetchBlock requests the block announced by the peer and adds it.
+func (s *State) netFetchBlock(pr peer.Peer, hash string) error {
+	block, err := s.netRequestBlock(pr, hash)
+	if err != nil {
+		return err
+	}
+
+	return s.processProposedBlock(pr.Host, block)
+}
+
+// netRequestTxs requests the transactions with the specified hashes.
+func (s *State) netRequestTxs(pr peer.Peer, hashes []string) ([]database.BlockTx, error) {
+	var txs []database.BlockTx
+	streamed, err := s.netRequest(pr, p2p.MsgGetTxs, p2p.GetTxs{Hashes: hashes}, &txs)
+	if streamed {
+		return txs, err
+	}
+
+	for _, hash := range hashes {
+		var tx database.BlockTx
+		url := pr.Url() + fmt.Sprintf(peer.TxByHashUri, hash)
+		if err := s.send(http.MethodGet, url, nil, &tx); err != nil {
+			return txs, err
+		}
+
+		// A transaction the peer no longer has comes back empty.
+		if tx.HashString() == hash {
+			txs = append(txs, tx)
+		}
+	}
+
+	return txs, nil
+}
+
+// netRequestBlock requests the block with the specified hash. The compact
+// form is tried first and rebuilt from the mempool plus the transactions
+// requested from the peer. The full block is requested when the compact block
+// still can't be rebuilt.
+func (s *State) netRequestBlock(pr peer.Peer, hash string) (database.Block, error) {
+	var cb database.CompactBlock
+	if err := s.netRequestBlockData(pr, hash, true, &cb); err != nil {
+		return database.Block{}, err
+	}
+
+	txs := s.mempool.Lookup(cb.TxHashes)
+	if missing := cb.Missing(txs); len(missing) > 0 {
+		fetched, err := s.netRequestTxs(pr, missing)
+		if err != nil {
+			s.evHandler("state: netRequestBlock: peer %s: block[%s]: missing txs: WARNING %s", pr.Host, hash, err)
+		}
+		for _, tx := range fetched {
+			txs[tx.HashString()] = tx
+		}
+
+		s.evHandler("state: netRequestBlock: peer %s: block[%s]: requested missing txs[%d] received[%d]", pr.Host, hash, len(missing), len(fetched))
+	}
+
+	block, err := cb.ToBlock(txs)
+	if err == nil && block.Hash() == hash {
+		return block, nil
+	}
+
+	s.evHandler("state: netRequestBlock: peer %s: block[%s]: compact block not rebuilt: %v", pr.Host, hash, err)
+
+	var blockData database.BlockData
+	if err := s.netRequestBlockData(pr, hash, false, &blockData); err != nil {
+		return database.Block{}, err
+	}
+
+	block, err = database.ToBlock(blockData)
+	if err != nil {
+		return database.Block{}, err
+	}
+
+	if block.Hash() != hash {
+		return database.Block{}, s.badResponse(pr, errors.New("peer sent a different block"))
+	}
+
+	return block, nil
+}
+
+// netRequestBlockData requests a block in compact or full form.
+func (s *State) netRequestBlockData(pr peer.Peer, hash string, compact bool, dataRecv any) error {
+	streamed, err := s.netRequest(pr, p2p.MsgGetBlock, p2p.GetBlock{Hash: hash, Compact: compact}, dataRecv)
+	if streamed {
+		return err
+	}
+
+	url := pr.Url() + fmt.Sprintf(peer.BlockByHashUri, hash)
+	if compact {
+		url += "?compact=true"
+	}
+
+	return s.send(http.MethodGet, url, nil, dataRecv)
+}
+
+// claim returns the hashes not seen yet that aren't already being fetched
+// and marks them as being fetched.
+func (s *State) claim(hashes []string) []string {
+	s.imu.Lock()
+	defer s.imu.Unlock()
+
+	var claimed []string
+	for _, hash := range hashes {
+		if s.seen.Has(hash) {
+			continue
+		}
+		if _, exists := s.inflight[hash]; exists {
+			continue
+		}
+		s.inflight[hash] = struct{}{}
+		claimed = append(claimed, hash)
+	}
+
+	return claimed
+}
+
+// release marks the hashes as no longer being fetched.
+func (s *State) release(hashes []string) {
+	s.imu.Lock()
+	defer s.imu.Unlock()
+
+	for _, hash := range hashes {
+		delete(s.inflight, hash)
+	}
+}
+
+// acquireFetch reserves one of the fetches the peer can have running. It
+// reports false when the peer already has maxFetchesPerPeer running.
+func (s *State) acquireFetch(host string) bool {
+	s.imu.Lock()
+	defer s.imu.Unlock()
+
+	if s.fetches[host] >= maxFetchesPerPeer {
+		return false
+	}
+	s.fetches[host]++
+
+	return true
+}
+
+// releaseFetch returns a fetch reserved for the peer.
+func (s *State) releaseFetch(host string) {
+	s.imu.Lock()
+	defer s.imu.Unlock()
+
+	if s.fetches[host]--; s.fetches[host] <= 0 {
+		delete(s.fetches, host)
+	}
+}
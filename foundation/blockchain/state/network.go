@@ -2,14 +2,13 @@ package state
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
-	"errors"
-	"fmt"
 	"io"
 	"net/http"
-	"strconv"
 
 	"github.com/ardanlabs/blockchain/foundation/blockchain/database"
+	"github.com/ardanlabs/blockchain/foundation/blockchain/p2p"
 	"github.com/ardanlabs/blockchain/foundation/blockchain/peer"
 )
 
@@ -17,12 +16,19 @@ func (s *State) NetRequestPeerStatus(pr peer.Peer) (peer.PeerStatus, error) {
 	s.evHandler("state: NetRequestPeerStatus: started for peer %s", pr.Host)
 	defer s.evHandler("state: NetRequestPeerStatus: completed for peer %s", pr.Host)
 
-	statusUrl := pr.Url() + peer.StatusUri
 	var ps peer.PeerStatus
-	if err := send(http.MethodGet, statusUrl, nil, &ps); err != nil {
+	streamed, err := s.netRequest(pr, p2p.MsgStatus, nil, &ps)
+	if err != nil {
 		return peer.PeerStatus{}, err
 	}
 
+	if !streamed {
+		statusUrl := pr.Url() + peer.StatusUri
+		if err := s.send(http.MethodGet, statusUrl, nil, &ps); err != nil {
+			return peer.PeerStatus{}, err
+		}
+	}
+
 	s.evHandler("state: NetRequestPeerStatus: peer-node[%s]: latestBlkNum [%s]: knowPeers [%s]", pr.Host, ps.LatestBlockNum, ps.KnownPeers)
 
 	return ps, nil
@@ -35,7 +41,7 @@ func (s *State) NetRequestMempool(pr peer.Peer) ([]database.BlockTx, error) {
 	mempoolUrl := pr.Url() + peer.MempoolUri
 
 	var mempool []database.BlockTx
-	if err := send(http.MethodGet, mempoolUrl, nil, &mempool); err != nil {
+	if err := s.send(http.MethodGet, mempoolUrl, nil, &mempool); err != nil {
 		return nil, err
 	}
 
@@ -44,57 +50,48 @@ func (s *State) NetRequestMempool(pr peer.Peer) ([]database.BlockTx, error) {
 	return mempool, nil
 }
 
-func (s *State) NetRequestPeerBlocks(pr peer.Peer) error {
-	s.evHandler("stae: NetRequestPeerBlocks : started for peer %s", pr.Host)
-	defer s.evHandler("state: NetRequestPeerBlocks: completed for peer %s", pr.Host)
-
-	// CORE NOTE: Ideally, you want to start by pulling just block headers and performing the crpytographic
-	// aduit so you can verify the integrity of the blocks and to validate you are not being attacked.
-	// After that you can start pulling the full block data for each block header if you are a full node.
-	// And maybe only the last 100 blocks should be calculated If you are a light node.
-	// i.e. getBlockHeaders
-	// Currently, the Ardan blockchain is a full node only and needs the transactions to have a comple account database.
-	// The cryptographic audit takes place at each full block is downloead from peers.
-
-	from := strconv.FormatUint(s.LatestBlock().Header.Number+1, 10)
-	blocksUrl := fmt.Sprintf(pr.Url()+peer.BlocksUri, from, "latest")
-
-	// Getting all blocks from current block until peer's latest block.
-	var blocksData []database.BlockData
-	if err := send(http.MethodGet, blocksUrl, nil, &blocksData); err != nil {
-		return err
-	}
-
-	s.evHandler("state: NetRequestPeerBlocks: peer-node[%s]: blocks [%d]", pr.Host, len(blocksData))
-
-	for _, blockData := range blocksData {
-		block, err := database.ToBlock(blockData)
-		if err != nil {
-			return err
-		}
-		if err := s.ProcessProposedBlock(block); err != nil {
-			return err
-		}
-	}
-	return nil
-}
-
 func (s *State) NetSendNodeAvailableToPeers() {
 	s.evHandler("state: NetSendNodeAvailableToPeers: started")
 	defer s.evHandler("state: NetSendNodeAvailableToPeers: completed")
 
-	host := peer.New(s.Host())
+	shs, err := s.SignedHandshake()
+	if err != nil {
+		s.evHandler("state: NetSendNodeAvailableToPeers: ERROR %s", err)
+		return
+	}
 
 	for _, pr := range s.KnowExternalPeers() {
 		s.evHandler("state: NetSendNodeAvailableToPeers: sending to peer %s", pr.Host)
-		peerUrl := pr.Url() + peer.PeerUri
-		if err := send(http.MethodPost, peerUrl, host, nil); err != nil {
+		if err := s.netSendPeer(pr, shs); err != nil {
 			s.evHandler("state: NetSendNodeAvailableToPeers: error sending to peer %s: %s", pr.Host, err)
 		}
 	}
 }
 
-func send(method string, url string, dataSend any, dataRecv any) error {
+// netSendPeer tells the peer about this node with its signed handshake. The
+// peer asks this node for its handshake before adding it, since a stream
+// dialed by this node doesn't prove the host of this node to the peer.
+func (s *State) netSendPeer(pr peer.Peer, shs peer.SignedHandshake) error {
+	return s.send(http.MethodPost, pr.Url()+peer.PeerUri, shs, nil)
+}
+
+// ResponseError is returned when a peer answers a request with an error.
+type ResponseError struct {
+	StatusCode int
+	Message    string
+}
+
+func (re *ResponseError) Error() string {
+	return re.Message
+}
+
+// send makes a request to a peer through the transport of the node.
+func (s *State) send(method string, url string, dataSend any, dataRecv any) error {
+	return s.sendContext(context.Background(), method, url, dataSend, dataRecv)
+}
+
+// sendContext makes a request to a peer that is canceled with the context.
+func (s *State) sendContext(ctx context.Context, method string, url string, dataSend any, dataRecv any) error {
 	var req *http.Request
 
 	switch {
@@ -103,21 +100,20 @@ func send(method string, url string, dataSend any, dataRecv any) error {
 		if err != nil {
 			return err
 		}
-		req, err = http.NewRequest(method, url, bytes.NewReader(data))
+		req, err = http.NewRequestWithContext(ctx, method, url, bytes.NewReader(data))
 		if err != nil {
 			return err
 		}
 
 	default:
 		var err error
-		req, err = http.NewRequest(method, url, nil)
+		req, err = http.NewRequestWithContext(ctx, method, url, nil)
 		if err != nil {
 			return err
 		}
 	}
 
-	var client http.Client
-	resp, err := client.Do(req)
+	resp, err := s.transport.Do(req)
 	if err != nil {
 		return err
 	}
@@ -133,7 +129,10 @@ func send(method string, url string, dataSend any, dataRecv any) error {
 		if err != nil {
 			return err
 		}
-		return errors.New(string(msg))
+		return &ResponseError{
+			StatusCode: resp.StatusCode,
+			Message:    string(bytes.TrimSpace(msg)),
+		}
 	}
 
 	if dataRecv != nil {
@@ -153,37 +152,35 @@ func (s *State) NetSendTxToPeers(tx database.BlockTx) {
 	// can check if the y already have the the transaction or not. If the receiving node
 	// doesn't have it, then it will request the transaction based on the mempool key it received.
 
-	// FOr now, we will send the full transaction to all known peers.
-	txHash, _ := tx.Hash()
-	txHashStr := string(txHash[:])
+	// Like Bitcoin, the hash is announced to the peers with a stream that aren't
+	// known to have the transaction. See inventory.go.
+	hash := tx.HashString()
 	for _, pr := range s.KnowExternalPeers() {
+		if s.seen.KnownBy(hash, pr.Host) {
+			continue
+		}
 
-		s.evHandler("state: NetSendTxToPeers: sending tx[%s] to peer %s", txHashStr, pr.Host)
+		s.evHandler("state: NetSendTxToPeers: sending tx[%s] to peer %s", hash, pr.Host)
 
-		txUrl := pr.Url() + peer.TxSubmitUri
-		if err := send(http.MethodPost, txUrl, tx, nil); err != nil {
+		if err := s.netAnnounce(pr, p2p.Inv{Txs: []string{hash}}, tx, peer.TxSubmitUri); err != nil {
 			// In real world, you wouldn't caret if a transaction failed to send to a peer.
 			s.evHandler("state: NetSendTxToPeers: WARNING %s: %s", pr.Host, err)
+			continue
 		}
+		s.seen.Add(hash, pr.Host)
 	}
 }
 
-func (s *State) NetSendBlockToPeers(block database.Block) error {
-	s.evHandler("state: NetSendBlockToPeers: started")
-	defer s.evHandler("state: NetSendBlockToPeers: completed")
+// NetSendVoteToPeers sends a finality vote to all known peers.
+func (s *State) NetSendVoteToPeers(vote database.SignedVote) {
+	s.evHandler("state: NetSendVoteToPeers: started")
+	defer s.evHandler("state: NetSendVoteToPeers: completed")
 
-	blockData := database.NewBlockData(block)
 	for _, pr := range s.KnowExternalPeers() {
-		s.evHandler("state: NetSendBlockToPeers: sending block[%s] to peer %s", blockData.Hash, pr.Host)
+		s.evHandler("state: NetSendVoteToPeers: sending vote[%s] to peer %s", vote, pr.Host)
 
-		blockSubmitUrl := pr.Url() + peer.BlockSubmitUri
-
-		var status struct {
-			Status string `json:"status"`
-		}
-		if err := send(http.MethodPost, blockSubmitUrl, blockData, &status); err != nil {
-			return err
+		if err := s.netSend(pr, p2p.MsgVote, vote, peer.VoteSubmitUri); err != nil {
+			s.evHandler("state: NetSendVoteToPeers: WARNING %s: %s", pr.Host, err)
 		}
 	}
-	return nil
 }
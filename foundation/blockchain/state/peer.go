@@ -1,23 +1,185 @@
 package state
 
-import "github.com/ardanlabs/blockchain/foundation/blockchain/peer"
+import (
+	"errors"
 
+	"github.com/ardanlabs/blockchain/foundation/blockchain/database"
+	"github.com/ardanlabs/blockchain/foundation/blockchain/peer"
+)
+
+// maxPeerFailures is the number of consecutive failures before a peer is
+// removed from the known peers. Its health record is kept, so a peer that is
+// added back by another node still waits out its backoff.
+const maxPeerFailures = 6
+
+// Set of errors returned when a peer tries to join.
+var (
+	ErrPeerBanned   = errors.New("peer is banned")
+	ErrTooManyPeers = errors.New("too many peers")
+)
+
+// KnowExternalPeers returns the known peers that can be contacted. Peers that
+// are banned or waiting out a backoff are left out.
 func (s *State) KnowExternalPeers() []peer.Peer {
-	return s.knownPeers.Copy(s.host)
+	peers := s.knownPeers.Copy(s.host)
+
+	ready := peers[:0]
+	for _, pr := range peers {
+		if s.scores.Ready(pr.Host) {
+			ready = append(ready, pr)
+		}
+	}
+	return ready
 }
 
 func (s *State) Host() string {
 	return s.host
 }
 
+// AddKnownPeer records the peer in the address book and adds it to the active
+// peers when there is room for it.
 func (s *State) AddKnownPeer(peer peer.Peer) bool {
-	return s.knownPeers.Add(peer)
+	if s.scores.Banned(peer.Host) {
+		return false
+	}
+
+	s.addrBook.Seen(peer)
+
+	if _, exists := s.knownPeers.Get(peer.Host); !exists && s.peersFull() {
+		return false
+	}
+	return s.addPeer(peer)
 }
 
 func (s *State) RemoveKnownPeer(peer peer.Peer) bool {
-	return s.knownPeers.Remove(peer)
+	return s.removePeer(peer, "removed")
 }
 
 func (s *State) KnownPeers() []peer.Peer {
 	return s.knownPeers.Copy("")
 }
+
+// PeerHealth returns the health records of the peers.
+func (s *State) PeerHealth() []peer.Health {
+	return s.scores.Copy()
+}
+
+// AddressBook returns the peers recorded in the address book.
+func (s *State) AddressBook() []peer.Address {
+	return s.addrBook.Copy()
+}
+
+// RefreshPeers prunes the address book and the health records, fills the
+// free peer slots from the address book and saves it.
+func (s *State) RefreshPeers() {
+	if pruned := s.addrBook.Prune(); pruned > 0 {
+		s.evHandler("state: RefreshPeers: pruned %d stale peers", pruned)
+	}
+
+	if pruned := s.scores.Prune(); pruned > 0 {
+		s.evHandler("state: RefreshPeers: pruned %d idle health records", pruned)
+	}
+
+	s.fillPeers()
+
+	if err := s.addrBook.Save(); err != nil {
+		s.evHandler("state: RefreshPeers: saving address book: ERROR %s", err)
+	}
+}
+
+// RecordPeerSuccess records the peer answered a request.
+func (s *State) RecordPeerSuccess(pr peer.Peer) {
+	s.scores.Success(pr.Host)
+	s.addrBook.Success(pr.Host)
+}
+
+// RecordPeerFailure records the peer didn't answer a request. The peer is
+// removed after too many consecutive failures.
+func (s *State) RecordPeerFailure(pr peer.Peer) {
+	backoff := s.scores.Failure(pr.Host)
+	s.addrBook.Failure(pr.Host)
+	s.evHandler("state: RecordPeerFailure: peer %s: failures[%d]: retry in %v", pr.Host, s.scores.Failures(pr.Host), backoff)
+
+	if s.scores.Failures(pr.Host) >= maxPeerFailures {
+		s.evHandler("state: RecordPeerFailure: peer %s: removed", pr.Host)
+		s.removePeer(pr, "unreachable")
+		s.streams.Disconnect(pr.Host)
+	}
+}
+
+// =============================================================================
+
+// peersFull reports if the active peers reached the maximum. Zero means there
+// is no maximum.
+func (s *State) peersFull() bool {
+	return s.maxPeers > 0 && len(s.knownPeers.Copy(s.host)) >= s.maxPeers
+}
+
+// fillPeers adds peers from the address book until the maximum is reached.
+func (s *State) fillPeers() {
+	n := s.maxPeers - len(s.knownPeers.Copy(s.host))
+	if s.maxPeers == 0 {
+		n = len(s.addrBook.Copy())
+	}
+	if n <= 0 {
+		return
+	}
+
+	skip := func(host string) bool {
+		if host == s.host || !s.scores.Ready(host) {
+			return true
+		}
+		_, exists := s.knownPeers.Get(host)
+		return exists
+	}
+
+	for _, pr := range s.addrBook.Select(n, skip) {
+		if s.addPeer(pr) {
+			s.evHandler("state: fillPeers: added peer %s from the address book", pr.Host)
+		}
+	}
+}
+
+// penalize adds penalty points to the peer at the host for misbehavior. A
+// banned peer is removed and its stream closed. An empty host means the peer
+// is unknown.
+func (s *State) penalize(host string, points int, reason error) {
+	if host == "" {
+		return
+	}
+
+	s.evHandler("state: penalize: peer %s: points[%d]: %s", host, points, reason)
+
+	if s.scores.Penalize(host, points, reason.Error()) {
+		s.evHandler("state: penalize: peer %s: BANNED: %s", host, reason)
+		s.removePeer(peer.New(host), "banned: "+reason.Error())
+		s.streams.Disconnect(host)
+	}
+}
+
+// badResponse penalizes the peer for sending invalid data and returns the
+// error describing it.
+func (s *State) badResponse(pr peer.Peer, err error) error {
+	s.penalize(pr.Host, peer.PenaltyBadResponse, err)
+	return err
+}
+
+// invalidBlock reports if the block was rejected because it's invalid,
+// rather than because it lost a race with another block.
+func invalidBlock(err error) bool {
+	invalid := []error{
+		database.ErrInvalidHash,
+		database.ErrInvalidDifficulty,
+		database.ErrInvalidSignature,
+		database.ErrInvalidStateRoot,
+		database.ErrInvalidTransRoot,
+		database.ErrInvalidBlockTimestamp,
+	}
+
+	for _, e := range invalid {
+		if errors.Is(err, e) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,76 @@
+package state
+
+import (
+	"github.com/ardanlabs/blockchain/foundation/blockchain/database"
+	"github.com/ardanlabs/blockchain/foundation/blockchain/peer"
+)
+
+// CORE NOTE: Transactions and blocks accepted from a peer are relayed to the
+// other peers so they reach nodes this node's peers aren't connected to. The
+// seen cache stops the relay at nodes that already handled the message and
+// keeps the node from sending a message back to a peer that already has it.
+
+// seenCacheSize is the number of recent transaction and block hashes kept to
+// suppress duplicates.
+const seenCacheSize = 10_000
+
+// upsertNodeTransaction accepts a transaction relayed by the peer at the host
+// and relays it further when it's new. An empty host means the peer is
+// unknown. Nothing is accepted from a banned peer.
+func (s *State) upsertNodeTransaction(from string, tx database.BlockTx) error {
+	if from != "" && s.scores.Banned(from) {
+		return ErrPeerBanned
+	}
+
+	hash := tx.HashString()
+	if s.seen.Has(hash) {
+		s.seen.Add(hash, from)
+		return nil
+	}
+
+	if err := tx.Validate(s.genesis.ChainID); err != nil {
+		s.penalize(from, peer.PenaltyInvalidTx, err)
+		return err
+	}
+
+	if err := s.upsertMempool(tx); err != nil {
+		return err
+	}
+
+	if s.seen.Add(hash, from) {
+		s.Worker.SignalShareTx(tx)
+	}
+
+	s.Worker.SignalStartMining()
+
+	return nil
+}
+
+// processProposedBlock accepts a block relayed by the peer at the host and
+// relays it further when it's new. An empty host means the peer is unknown.
+// Nothing is accepted from a banned peer.
+func (s *State) processProposedBlock(from string, block database.Block) error {
+	if from != "" && s.scores.Banned(from) {
+		return ErrPeerBanned
+	}
+
+	hash := block.Hash()
+	if s.seen.Has(hash) {
+		s.seen.Add(hash, from)
+		return nil
+	}
+
+	if err := s.processBlock(block); err != nil {
+		if invalidBlock(err) {
+			s.penalize(from, peer.PenaltyInvalidBlock, err)
+		}
+		s.publishBlockRejected(from, block, err)
+		return err
+	}
+
+	if s.seen.Add(hash, from) {
+		s.Worker.SignalShareBlock(block)
+	}
+
+	return nil
+}
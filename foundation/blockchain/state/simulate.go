@@ -0,0 +1,119 @@
+package state
+
+import (
+	"errors"
+	"sort"
+
+	"github.com/ardanlabs/blockchain/foundation/blockchain/database"
+)
+
+// CORE NOTE: A transaction that fails in a block still pays for its gas, so
+// a wallet wants to know if it will fail before sending it. The simulation
+// applies the transaction to a copy of the accounts the same way a block
+// would. The transactions of the sender still in the mempool with a lower
+// nonce are applied first since they'll be mined before it. Nothing the
+// simulation does reaches the mempool or the chain.
+
+// SimBalance is the balance of an account before and after the simulated
+// transaction.
+type SimBalance struct {
+	AccountID database.AccountID
+	Before    uint64
+	After     uint64
+}
+
+// SimulateResult describes what applying the transaction would do. Pending is
+// the number of mempool transactions of the sender applied before it.
+type SimulateResult struct {
+	Tx       database.BlockTx
+	Success  bool
+	Reason   string
+	GasCost  uint64
+	Nonce    uint64
+	Pending  int
+	Balances []SimBalance
+}
+
+// SimulateWalletTx simulates a transaction signed by a wallet.
+func (s *State) SimulateWalletTx(signedTx database.SignedTx) (SimulateResult, error) {
+	if err := signedTx.Validate(s.genesis.ChainID); err != nil {
+		return SimulateResult{}, err
+	}
+
+	return s.simulate(database.NewBlockTx(signedTx, uint64(s.genesis.GasPrice), oneUnitOfGas)), nil
+}
+
+// SimulateTx simulates an unsigned transaction as if the sender signed it.
+func (s *State) SimulateTx(tx database.Tx) (SimulateResult, error) {
+	switch {
+	case tx.ChainID != s.genesis.ChainID:
+		return SimulateResult{}, errors.New("invalid chain id")
+
+	case !tx.FromID.IsAccountID():
+		return SimulateResult{}, errors.New("invalid from account id")
+
+	case !tx.ToID.IsAccountID():
+		return SimulateResult{}, errors.New("invalid to account id")
+
+	case tx.FromID == tx.ToID:
+		return SimulateResult{}, errors.New("from and to account ids are the same")
+	}
+
+	signedTx := database.SignedTx{Tx: tx}
+
+	return s.simulate(database.NewBlockTx(signedTx, uint64(s.genesis.GasPrice), oneUnitOfGas)), nil
+}
+
+// =============================================================================
+
+// simulate applies the pending transactions of the sender and then the
+// transaction to a sandbox of the accounts.
+func (s *State) simulate(tx database.BlockTx) SimulateResult {
+	sandbox := s.db.Sandbox()
+
+	// The block only tells who receives the gas and the tip.
+	var block database.Block
+	block.Header.BeneficiaryID = s.beneficiaryID
+
+	var pending []database.BlockTx
+	for _, ptx := range s.mempool.PickBest() {
+		if ptx.FromID == tx.FromID && ptx.Nonce < tx.Nonce {
+			pending = append(pending, ptx)
+		}
+	}
+	sort.Slice(pending, func(i, j int) bool { return pending[i].Nonce < pending[j].Nonce })
+
+	for _, ptx := range pending {
+		sandbox.ApplyTransaction(block, ptx)
+	}
+
+	from, _ := sandbox.Query(tx.FromID)
+	to, _ := sandbox.Query(tx.ToID)
+
+	res := SimulateResult{
+		Tx:      tx,
+		Success: true,
+		Pending: len(pending),
+	}
+
+	res.GasCost = tx.GasPrice * tx.GasUnits
+	if res.GasCost > from.Balance {
+		res.GasCost = from.Balance
+	}
+
+	if err := sandbox.ApplyTransaction(block, tx); err != nil {
+		res.Success = false
+		res.Reason = err.Error()
+	}
+
+	fromAfter, _ := sandbox.Query(tx.FromID)
+	toAfter, _ := sandbox.Query(tx.ToID)
+
+	res.Nonce = fromAfter.Nonce
+	res.Balances = []SimBalance{
+		{AccountID: tx.FromID, Before: from.Balance, After: fromAfter.Balance},
+		{AccountID: tx.ToID, Before: to.Balance, After: toAfter.Balance},
+	}
+
+	return res
+}
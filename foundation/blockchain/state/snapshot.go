@@ -0,0 +1,165 @@
+package state
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/ardanlabs/blockchain/foundation/blockchain/consensus"
+	"github.com/ardanlabs/blockchain/foundation/blockchain/database"
+	"github.com/ardanlabs/blockchain/foundation/blockchain/peer"
+)
+
+// CORE NOTE: Fast sync only runs on a node with no blocks. The header chain is
+// downloaded and verified first, then the most recent snapshot a peer can
+// serve is downloaded in chunks. The snapshot is only accepted if its hash
+// matches the StateRoot of the header that follows it. A StateRoot is only as
+// trustworthy as the seal of its header, so every header must pass the seal
+// check of the consensus engine, otherwise any peer could make up a linked
+// header chain to install the state it wants. Only proof of work makes a
+// header expensive to forge. Under PoA a header only carries difficulty 1
+// work and the signature of the authority isn't part of it, and under PoS the
+// seal can't be verified without the state itself. Fast sync is refused for
+// both and the node executes every block instead. From there the regular sync
+// downloads and executes the remaining blocks.
+
+// LatestSnapshotInfo returns the description of the most recent snapshot
+// this node can serve.
+func (s *State) LatestSnapshotInfo() (database.SnapshotInfo, error) {
+	snapshot, err := s.db.LatestSnapshot()
+	if err != nil {
+		return database.SnapshotInfo{}, err
+	}
+
+	return snapshot.Info(), nil
+}
+
+// QuerySnapshotChunk returns a chunk of the snapshot at the specified height.
+func (s *State) QuerySnapshotChunk(number uint64, chunk int) (database.SnapshotChunk, error) {
+	snapshot, err := s.db.Snapshot(number)
+	if err != nil {
+		return database.SnapshotChunk{}, err
+	}
+
+	return snapshot.Chunk(chunk)
+}
+
+// NetFastSync restores the account state from a snapshot served by a peer
+// instead of executing every block from genesis.
+func (s *State) NetFastSync(statuses map[peer.Peer]peer.PeerStatus) error {
+	s.evHandler("state: NetFastSync: started")
+	defer s.evHandler("state: NetFastSync: completed")
+
+	if name := s.engine.Name(); name != consensus.PoW {
+		return fmt.Errorf("fast sync isn't supported under %s, headers alone don't prove who sealed them", name)
+	}
+
+	latest := s.LatestBlock()
+	if latest.Header.Number > 0 {
+		s.evHandler("state: NetFastSync: node has blocks, skipping")
+		return nil
+	}
+
+	best, target := bestPeer(statuses)
+	if target <= latest.Header.Number {
+		return nil
+	}
+
+	headers, err := s.netRequestHeaders(best, latest, target)
+	if err != nil {
+		return err
+	}
+
+	if err := s.verifyHeaderSeals(latest, headers); err != nil {
+		return fmt.Errorf("headers can't be trusted for a snapshot: %w", err)
+	}
+
+	for pr := range statuses {
+		snapshot, err := s.netRequestSnapshot(pr, headers)
+		if err != nil {
+			s.evHandler("state: NetFastSync: peer %s: WARNING %s", pr.Host, err)
+			continue
+		}
+
+		if err := s.db.RestoreSnapshot(snapshot); err != nil {
+			return err
+		}
+
+		s.evHandler("state: NetFastSync: restored snapshot blk[%d] accounts[%d] from peer %s", snapshot.Header.Number, len(snapshot.Accounts), pr.Host)
+		return nil
+	}
+
+	return fmt.Errorf("no peer could serve a snapshot")
+}
+
+// =============================================================================
+
+// netRequestSnapshot downloads the latest snapshot of the peer and verifies it
+// against the header chain. The headers start at block 1.
+func (s *State) netRequestSnapshot(pr peer.Peer, headers []database.BlockHeader) (database.Snapshot, error) {
+	var info database.SnapshotInfo
+	if err := s.send(http.MethodGet, pr.Url()+peer.SnapshotUri, nil, &info); err != nil {
+		return database.Snapshot{}, err
+	}
+
+	// The header after the snapshot is needed to verify it.
+	if info.Number == 0 || info.Number >= uint64(len(headers)) {
+		return database.Snapshot{}, fmt.Errorf("no usable snapshot, peer has blk[%d]", info.Number)
+	}
+
+	snapshot := database.Snapshot{
+		Header: headers[info.Number-1],
+	}
+
+	number := strconv.FormatUint(info.Number, 10)
+	for chunk := 0; chunk < info.Chunks; chunk++ {
+		url := fmt.Sprintf(pr.Url()+peer.SnapshotChunkUri, number, strconv.Itoa(chunk))
+
+		var sc database.SnapshotChunk
+		if err := s.send(http.MethodGet, url, nil, &sc); err != nil {
+			return database.Snapshot{}, err
+		}
+
+		if sc.Number != info.Number || sc.Chunk != chunk || sc.Header != snapshot.Header {
+			return database.Snapshot{}, s.badResponse(pr, fmt.Errorf("chunk %d doesn't belong to snapshot blk[%d]", chunk, info.Number))
+		}
+
+		snapshot.Accounts = append(snapshot.Accounts, sc.Accounts...)
+	}
+
+	if err := snapshot.Verify(headers[info.Number]); err != nil {
+		return database.Snapshot{}, s.badResponse(pr, err)
+	}
+
+	s.evHandler("state: netRequestSnapshot: peer %s: verified snapshot blk[%d] chunks[%d]", pr.Host, info.Number, info.Chunks)
+
+	return snapshot, nil
+}
+
+// verifyHeaderSeals checks the seal of every header on top of the latest
+// block. Unlike a regular sync, headers the engine can't verify are rejected.
+func (s *State) verifyHeaderSeals(latest database.Block, headers []database.BlockHeader) error {
+	parent := latest.Header
+	for _, header := range headers {
+		if err := s.engine.VerifyHeader(header, parent); err != nil {
+			return fmt.Errorf("blk[%d]: %w", header.Number, err)
+		}
+		parent = header
+	}
+
+	return nil
+}
+
+// bestPeer returns the peer with the longest chain.
+func bestPeer(statuses map[peer.Peer]peer.PeerStatus) (peer.Peer, uint64) {
+	var best peer.Peer
+	var target uint64
+	for pr, status := range statuses {
+		if status.LatestBlockNum > target {
+			best = pr
+			target = status.LatestBlockNum
+		}
+	}
+
+	return best, target
+}
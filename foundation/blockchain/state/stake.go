@@ -0,0 +1,94 @@
+package state
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/ardanlabs/blockchain/foundation/blockchain/database"
+)
+
+// CORE NOTE: Under PoS a proposer that signs two different blocks at the same
+// height is reported by the first node that sees both blocks. The node signs
+// a slash transaction with its beneficiary key carrying both signed headers.
+
+// checkDoubleSign looks for a different block signed by the same proposer at
+// the same height as a rejected block. If found, the proposer is reported with
+// a slash transaction.
+func (s *State) checkDoubleSign(block database.Block) {
+	if block.Header.Number > s.db.LatestBlock().Header.Number {
+		return
+	}
+
+	local, err := s.db.GetBlock(block.Header.Number)
+	if err != nil {
+		return
+	}
+
+	if local.Header.BeneficiaryID != block.Header.BeneficiaryID || local.Hash() == block.Hash() {
+		return
+	}
+
+	evidence := database.NewDoubleSignEvidence(local, block)
+	offender, err := evidence.Offender()
+	if err != nil {
+		return
+	}
+
+	if account, exists := s.db.GetAccount(offender); exists && account.IsSlashed(block.Header.Number) {
+		return
+	}
+
+	key := fmt.Sprintf("%s:%d", offender, block.Header.Number)
+
+	s.mu.Lock()
+	if _, exists := s.reported[key]; exists {
+		s.mu.Unlock()
+		return
+	}
+	s.reported[key] = struct{}{}
+	s.mu.Unlock()
+
+	s.evHandler("state: checkDoubleSign: proposer[%s] double signed blk[%d]", offender, block.Header.Number)
+
+	if err := s.submitSlashing(evidence); err != nil {
+		s.evHandler("state: checkDoubleSign: ERROR submitting slash: %s", err)
+	}
+}
+
+// submitSlashing signs a slash transaction with the beneficiary key and adds
+// it to the mempool.
+func (s *State) submitSlashing(evidence database.DoubleSignEvidence) error {
+	if s.beneficiaryKey == nil {
+		return errors.New("no beneficiary key to sign the slash transaction")
+	}
+
+	data, err := database.StakeData{Op: database.StakeOpSlash, Evidence: &evidence}.Encode()
+	if err != nil {
+		return err
+	}
+
+	// The nonce needs to follow any transaction from the beneficiary that is
+	// still waiting in the mempool.
+	nonce := uint64(1)
+	if account, err := s.db.Query(s.beneficiaryID); err == nil {
+		nonce = account.Nonce + 1
+	}
+	for _, tx := range s.mempool.PickBest() {
+		if tx.FromID == s.beneficiaryID {
+			nonce++
+		}
+	}
+
+	tx, err := database.NewTx(s.genesis.ChainID, nonce, s.beneficiaryID, database.StakingAccountID, 0, 0, data)
+	if err != nil {
+		return err
+	}
+
+	signedTx, err := tx.Sign(s.beneficiaryKey)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.UpsertWalletTx(signedTx)
+	return err
+}
@@ -1,22 +1,23 @@
 package state
 
 import (
+	"crypto/ecdsa"
+	"errors"
 	"sync"
+	"time"
 
+	"github.com/ardanlabs/blockchain/foundation/blockchain/consensus"
 	"github.com/ardanlabs/blockchain/foundation/blockchain/database"
+	"github.com/ardanlabs/blockchain/foundation/blockchain/finality"
 	"github.com/ardanlabs/blockchain/foundation/blockchain/genesis"
+	"github.com/ardanlabs/blockchain/foundation/blockchain/gossip"
 	"github.com/ardanlabs/blockchain/foundation/blockchain/mempool"
+	"github.com/ardanlabs/blockchain/foundation/blockchain/p2p"
 	"github.com/ardanlabs/blockchain/foundation/blockchain/peer"
+	"github.com/ardanlabs/blockchain/foundation/blockchain/transport"
+	"github.com/ardanlabs/blockchain/foundation/events"
 )
 
-const (
-	ConsensusPoA = "PoA"
-	ConsensusPoW = "PoW"
-)
-
-// EventHandler defines a function that can be called when an event occurs.
-type EventHandler func(v string, args ...any)
-
 // Worker interface represents the behaviour required to be implemented by an package
 // providing support for mining, peer update and trasaction sharing.
 type Worker interface {
@@ -25,17 +26,64 @@ type Worker interface {
 	SignalStartMining()
 	SignalCancelMining()
 	SignalShareTx(blockTx database.BlockTx)
+	SignalShareBlock(block database.Block)
+	SignalResync()
+	SignalShareVote(vote database.SignedVote)
+	PauseMining()
+	ResumeMining()
+	Status() WorkerStatus
+}
+
+// WorkerStatus describes what the worker goroutines are doing. Operations
+// reports for each goroutine if it's running and the queues hold the items
+// waiting to be shared with the peers.
+type WorkerStatus struct {
+	Operations   map[string]bool
+	MiningPaused bool
+	Mining       bool
+	Syncing      bool
+	TxQueue      int
+	BlockQueue   int
+	VoteQueue    int
 }
 
 type Config struct {
 	Beneficiary    database.AccountID
+	BeneficiaryKey *ecdsa.PrivateKey
+	NodeKey        *ecdsa.PrivateKey
 	Host           string
 	Storage        database.Storage
 	Genesis        genesis.Genesis
 	SelectStrategy string
 	KnownPeers     *peer.PeerSet
-	EvHandler      EventHandler
-	Consensus      string
+	AddrBook       *peer.AddrBook
+	Transport      transport.Transport
+	MaxPeers       int
+	MiningThreads  int
+
+	// Events is the bus the state publishes on. The trace messages of the
+	// blockchain packages are published on it too.
+	Events *events.Events
+
+	// HeartbeatInterval produces an empty block when no block was added for
+	// this long. Zero disables heartbeat blocks.
+	HeartbeatInterval time.Duration
+
+	// MinBlockInterval is the minimum time between blocks mined on demand so
+	// transactions received in a burst are batched in one block.
+	MinBlockInterval time.Duration
+
+	// SnapshotInterval is the number of blocks between the state snapshots
+	// served to fast syncing nodes. Zero disables snapshots.
+	SnapshotInterval uint64
+
+	// FastSync starts an empty node from a peer snapshot instead of
+	// executing every block from genesis. It's only honored under PoW.
+	FastSync bool
+
+	// PeerToken is the API key or JWT sent to the peers when their private
+	// API requires authentication. It's only used by the default transport.
+	PeerToken string
 }
 
 type State struct {
@@ -43,24 +91,103 @@ type State struct {
 	// resyncWG    sync.WaitGroup
 	// allowMining bool
 
-	beneficiaryID database.AccountID
-	evHandler     EventHandler
-	host          string
-	consensus     string
+	beneficiaryID  database.AccountID
+	beneficiaryKey *ecdsa.PrivateKey
+	nodeKey        *ecdsa.PrivateKey
+	evHandler      func(v string, args ...any)
+	host           string
+	engine         consensus.Engine
+
+	heartbeatInterval time.Duration
+	minBlockInterval  time.Duration
+	fastSync          bool
+
+	knownPeers  *peer.PeerSet
+	addrBook    *peer.AddrBook
+	maxPeers    int
+	scores      *peer.Scores
+	streams     *p2p.Streams
+	transport   transport.Transport
+	storage     database.Storage
+	genesis     genesis.Genesis
+	genesisHash string
+	mempool     *mempool.Mempool
+	seen        *gossip.Seen
+	events      *events.Events
 
-	knownPeers *peer.PeerSet
-	storage    database.Storage
-	genesis    genesis.Genesis
-	mempool    *mempool.Mempool
+	// Announced hashes being fetched from a peer, and the number of
+	// announcements being fetched per peer.
+	imu      sync.Mutex
+	inflight map[string]struct{}
+	fetches  map[string]int
+
+	// The finality lock serializes vote processing so a node never casts
+	// two votes of the same type at the same height.
+	fmu   sync.Mutex
+	votes *finality.Votes
+
+	// Block templates handed out to external miners, keyed by work id. The
+	// ids are kept in the order the templates were built to evict the oldest.
+	wmu     sync.Mutex
+	work    map[string]database.Block
+	workIDs []string
+
+	// Totals of the block broadcasts.
+	bmu       sync.Mutex
+	broadcast BroadcastStats
+
+	// Transactions recently replaced or dropped from the mempool, oldest
+	// first.
+	hmu     sync.Mutex
+	history []txChange
+
+	// Double signs already reported under PoS, keyed by proposer and height.
+	reported map[string]struct{}
 
 	db *database.Database
 
 	Worker Worker
 }
 
-func New(cfg Config, ev func(v string, args ...any)) (*State, error) {
+func New(cfg Config) (*State, error) {
+	if cfg.NodeKey == nil {
+		return nil, errors.New("node identity key is required")
+	}
+
+	// Without a bus the events are published to nobody.
+	evts := cfg.Events
+	if evts == nil {
+		evts = events.New()
+	}
+	ev := evts.Tracef
+
+	authorities, err := database.ToAccountIDs(cfg.Genesis.Authorities)
+	if err != nil {
+		return nil, err
+	}
+
+	// The consensus engine is selected by the genesis file so every node in
+	// the network runs the same one.
+	engine, err := consensus.New(cfg.Genesis.Consensus, consensus.Config{
+		Authorities:    authorities,
+		BeneficiaryKey: cfg.BeneficiaryKey,
+		MiningThreads:  cfg.MiningThreads,
+		Difficulty:     cfg.Genesis.Difficulty,
+		EvHandler:      ev,
+	})
+	if err != nil {
+		return nil, err
+	}
 
-	db, err := database.New(cfg.Genesis, cfg.Storage, ev)
+	options := []func(db *database.Database){
+		database.WithSealVerifier(engine),
+		database.WithSnapshotInterval(cfg.SnapshotInterval),
+	}
+	if engine.Name() == consensus.PoS {
+		options = append(options, database.WithStaking())
+	}
+
+	db, err := database.New(cfg.Genesis, cfg.Storage, ev, options...)
 	if err != nil {
 		return nil, err
 	}
@@ -70,18 +197,59 @@ func New(cfg Config, ev func(v string, args ...any)) (*State, error) {
 		return nil, err
 	}
 
+	// Without an address book the peers are only tracked in memory.
+	addrBook := cfg.AddrBook
+	if addrBook == nil {
+		if addrBook, err = peer.LoadAddrBook(""); err != nil {
+			return nil, err
+		}
+	}
+
+	// Without a transport the node talks to its peers over HTTP.
+	tr := cfg.Transport
+	if tr == nil {
+		tr = transport.NewHTTP(transport.HTTPConfig{
+			RequestTimeout: requestTimeout,
+			Token:          cfg.PeerToken,
+		})
+	}
+
 	state := State{
-		beneficiaryID: cfg.Beneficiary,
-		storage:       cfg.Storage,
-		evHandler:     ev,
-		host:          cfg.Host,
-		consensus:     cfg.Consensus,
-
-		knownPeers: cfg.KnownPeers,
-		genesis:    cfg.Genesis,
-		mempool:    mempool,
-		db:         db,
+		beneficiaryID:  cfg.Beneficiary,
+		beneficiaryKey: cfg.BeneficiaryKey,
+		nodeKey:        cfg.NodeKey,
+		storage:        cfg.Storage,
+		evHandler:      ev,
+		host:           cfg.Host,
+		engine:         engine,
+
+		heartbeatInterval: cfg.HeartbeatInterval,
+		minBlockInterval:  cfg.MinBlockInterval,
+		fastSync:          cfg.FastSync,
+
+		knownPeers:  cfg.KnownPeers,
+		addrBook:    addrBook,
+		transport:   tr,
+		maxPeers:    cfg.MaxPeers,
+		scores:      peer.NewScores(),
+		genesis:     cfg.Genesis,
+		genesisHash: genesisHash(cfg.Genesis),
+		mempool:     mempool,
+		seen:        gossip.New(seenCacheSize),
+		events:      evts,
+		inflight:    make(map[string]struct{}),
+		fetches:     make(map[string]int),
+		votes:       finality.New(db.Authorities()),
+		work:        make(map[string]database.Block),
+		reported:    make(map[string]struct{}),
+		db:          db,
 	}
+	state.streams = p2p.New(cfg.Host, peer.BasePath+peer.StreamUri, state.handleMessage, identity{state: &state}, tr, ev)
+
+	// The peers recorded in the address book fill the slots left by the
+	// origin peers.
+	state.fillPeers()
+
 	// The Worker is not set here. The call to worker.Run will assign itself
 	// and start everything up and running for the node
 
@@ -99,6 +267,14 @@ func (s *State) Shutdown() error {
 	// Stop all the blockchain writing activity.
 	s.Worker.Shutdown()
 
+	// Close the streams with the peers.
+	s.streams.Close()
+
+	// Keep the peers learned during this run for the next start.
+	if err := s.addrBook.Save(); err != nil {
+		s.evHandler("state: shutdown: saving address book: ERROR %s", err)
+	}
+
 	// Wait for the resync to complete.
 	// s.resyncWG.Wait()
 
@@ -106,7 +282,29 @@ func (s *State) Shutdown() error {
 }
 
 func (s *State) Consensus() string {
-	return s.consensus
+	return s.engine.Name()
+}
+
+func (s *State) Engine() consensus.Engine {
+	return s.engine
+}
+
+// MiningStats returns the statistics of the PoW miner. The zero value is
+// returned when the engine doesn't mine.
+func (s *State) MiningStats() consensus.MiningStats {
+	if miner, ok := s.engine.(consensus.Miner); ok {
+		return miner.Stats()
+	}
+	return consensus.MiningStats{}
+}
+
+// FastSync reports if the node starts from a peer snapshot when empty.
+func (s *State) FastSync() bool {
+	return s.fastSync
+}
+
+func (s *State) Beneficiary() database.AccountID {
+	return s.beneficiaryID
 }
 
 func (s *State) Genesis() genesis.Genesis {
@@ -125,7 +323,7 @@ func (s *State) MempoolLength() int {
 }
 
 func (s *State) UpsertMempool(tx database.BlockTx) error {
-	return s.mempool.Upsert(tx)
+	return s.upsertMempool(tx)
 }
 
 func (s *State) Accounts() map[database.AccountID]database.Account {
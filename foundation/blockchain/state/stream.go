@@ -0,0 +1,240 @@
+package state
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/ardanlabs/blockchain/foundation/blockchain/database"
+	"github.com/ardanlabs/blockchain/foundation/blockchain/p2p"
+	"github.com/ardanlabs/blockchain/foundation/blockchain/peer"
+)
+
+// CORE NOTE: Nodes keep a stream open with every known peer. Gossip and the
+// requests made during a sync go over the stream when one is open and fall
+// back to the HTTP endpoints otherwise, so nodes that can't open a stream can
+// still take part in the network.
+
+// requestTimeout is how long a request over a stream can take.
+const requestTimeout = 30 * time.Second
+
+// AcceptStream takes over the connection of a peer asking to open a stream.
+func (s *State) AcceptStream(w http.ResponseWriter, r *http.Request) error {
+	return s.streams.Accept(w, r)
+}
+
+// StreamHosts returns the hosts of the peers with an open stream.
+func (s *State) StreamHosts() []string {
+	return s.streams.Hosts()
+}
+
+// NetConnectPeers opens a stream with every known peer that doesn't have one.
+func (s *State) NetConnectPeers() {
+	s.evHandler("state: NetConnectPeers: started")
+	defer s.evHandler("state: NetConnectPeers: completed")
+
+	for _, pr := range s.KnowExternalPeers() {
+		if _, exists := s.streams.Conn(pr.Host); exists {
+			continue
+		}
+
+		if _, err := s.streams.Dial(pr.Host); err != nil {
+			s.evHandler("state: NetConnectPeers: peer %s: WARNING %s", pr.Host, err)
+		}
+	}
+}
+
+// =============================================================================
+
+// netSend sends a message to the peer over its stream or, without a stream,
+// posts it to the HTTP endpoint.
+func (s *State) netSend(pr peer.Peer, msgType p2p.MsgType, payload any, uri string) error {
+	if _, exists := s.streams.Conn(pr.Host); exists {
+		msg, err := p2p.NewMessage(msgType, payload)
+		if err != nil {
+			return err
+		}
+		return s.streams.Send(pr.Host, msg)
+	}
+
+	return s.send(http.MethodPost, pr.Url()+uri, payload, nil)
+}
+
+// netRequest makes a request to the peer over its stream. The bool is false
+// when there is no stream with the peer.
+func (s *State) netRequest(pr peer.Peer, msgType p2p.MsgType, payload any, dataRecv any) (bool, error) {
+	if _, exists := s.streams.Conn(pr.Host); !exists {
+		return false, nil
+	}
+
+	msg, err := p2p.NewMessage(msgType, payload)
+	if err != nil {
+		return true, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	defer cancel()
+
+	resp, err := s.streams.Request(ctx, pr.Host, msg)
+	if err != nil {
+		return true, err
+	}
+
+	return true, resp.Decode(dataRecv)
+}
+
+// handleMessage processes the messages received over a stream.
+func (s *State) handleMessage(c *p2p.Conn, msg p2p.Message) p2p.Message {
+	reply, err := s.processMessage(c, msg)
+	if err != nil {
+		s.evHandler("state: handleMessage: peer %s: type[%s]: ERROR %s", c.Host(), msg.Type, err)
+		return p2p.ErrorMessage(err)
+	}
+
+	return reply
+}
+
+func (s *State) processMessage(c *p2p.Conn, msg p2p.Message) (p2p.Message, error) {
+	switch msg.Type {
+	case p2p.MsgStatus:
+		latestBlock := s.LatestBlock()
+		status := peer.PeerStatus{
+			NodeID:          s.NodeID(),
+			LatestBlockHash: latestBlock.Hash(),
+			LatestBlockNum:  latestBlock.Header.Number,
+			KnownPeers:      s.KnowExternalPeers(),
+		}
+		return p2p.NewMessage(p2p.MsgStatus, status)
+
+	case p2p.MsgTx:
+		var tx database.BlockTx
+		if err := msg.Decode(&tx); err != nil {
+			return p2p.Message{}, err
+		}
+		return p2p.Message{}, s.upsertNodeTransaction(c.Host(), tx)
+
+	case p2p.MsgBlock:
+		var blockData database.BlockData
+		if err := msg.Decode(&blockData); err != nil {
+			return p2p.Message{}, err
+		}
+		block, err := database.ToBlock(blockData)
+		if err != nil {
+			return p2p.Message{}, err
+		}
+		return p2p.Message{}, s.processProposedBlock(c.Host(), block)
+
+	case p2p.MsgVote:
+		var vote database.SignedVote
+		if err := msg.Decode(&vote); err != nil {
+			return p2p.Message{}, err
+		}
+		return p2p.Message{}, s.ProcessVote(vote)
+
+	case p2p.MsgPeers:
+		var peers []peer.Peer
+		if err := msg.Decode(&peers); err != nil {
+			return p2p.Message{}, err
+		}
+		// The peers are verified before they are added, which takes a
+		// request to each of them, so it can't block the processing of the
+		// messages. It takes one of the fetches the peer can have running.
+		if s.acquireFetch(c.Host()) {
+			go func() {
+				defer s.releaseFetch(c.Host())
+				for _, pr := range peers {
+					if s.NetLearnPeer(pr) {
+						s.evHandler("state: processMessage: peer %s: added peer %s", c.Host(), pr.Host)
+					}
+				}
+			}()
+		}
+		return p2p.NewMessage(p2p.MsgPeers, s.KnownPeers())
+
+	case p2p.MsgGetBlocks:
+		var req p2p.GetBlocks
+		if err := msg.Decode(&req); err != nil {
+			return p2p.Message{}, err
+		}
+		// The range is limited like over HTTP so a peer can't ask for the
+		// whole chain in one message.
+		latest := s.LatestBlock().Header.Number
+		if req.From == QueryLatest {
+			req.From = latest
+		}
+		if req.To > latest {
+			req.To = latest
+		}
+		if req.From > req.To {
+			return p2p.NewMessage(p2p.MsgBlocks, []database.BlockData{})
+		}
+		if req.To-req.From >= MaxBlocksPerRequest {
+			req.To = req.From + MaxBlocksPerRequest - 1
+		}
+		blocks, err := s.QueryBlocksByNumber(req.From, req.To)
+		if err != nil {
+			return p2p.Message{}, err
+		}
+		blocksData := make([]database.BlockData, len(blocks))
+		for i, block := range blocks {
+			blocksData[i] = database.NewBlockData(block)
+		}
+		return p2p.NewMessage(p2p.MsgBlocks, blocksData)
+
+	case p2p.MsgInv:
+		var inv p2p.Inv
+		if err := msg.Decode(&inv); err != nil {
+			return p2p.Message{}, err
+		}
+		// The bodies are requested over the same stream, so the fetch can't
+		// block the processing of the messages. An announcement sent as a
+		// request is answered once the announced blocks are processed. A
+		// peer can only have a few fetches running so a flood of
+		// announcements can't pile up goroutines.
+		pr := peer.New(c.Host())
+		if !s.acquireFetch(pr.Host) {
+			return p2p.Message{}, errTooManyFetches
+		}
+		if msg.ID == 0 {
+			go func() {
+				defer s.releaseFetch(pr.Host)
+				s.netFetchInventory(pr, inv)
+			}()
+			return p2p.Message{}, nil
+		}
+		go func() {
+			defer s.releaseFetch(pr.Host)
+
+			reply := p2p.Message{Type: p2p.MsgAck}
+			if err := s.netFetchInventory(pr, inv); err != nil {
+				reply = p2p.ErrorMessage(err)
+			}
+			c.Reply(msg, reply)
+		}()
+		return p2p.Deferred, nil
+
+	case p2p.MsgGetTxs:
+		var req p2p.GetTxs
+		if err := msg.Decode(&req); err != nil {
+			return p2p.Message{}, err
+		}
+		return p2p.NewMessage(p2p.MsgTxs, s.QueryTxs(req.Hashes))
+
+	case p2p.MsgGetBlock:
+		var req p2p.GetBlock
+		if err := msg.Decode(&req); err != nil {
+			return p2p.Message{}, err
+		}
+		block, err := s.QueryBlockByHash(req.Hash)
+		if err != nil {
+			return p2p.Message{}, err
+		}
+		if req.Compact {
+			return p2p.NewMessage(p2p.MsgCompactBlock, database.NewCompactBlock(block))
+		}
+		return p2p.NewMessage(p2p.MsgBlock, database.NewBlockData(block))
+	}
+
+	return p2p.Message{}, fmt.Errorf("unknown message type %q", msg.Type)
+}
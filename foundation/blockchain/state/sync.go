@@ -0,0 +1,298 @@
+package state
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/ardanlabs/blockchain/foundation/blockchain/consensus"
+	"github.com/ardanlabs/blockchain/foundation/blockchain/database"
+	"github.com/ardanlabs/blockchain/foundation/blockchain/p2p"
+	"github.com/ardanlabs/blockchain/foundation/blockchain/peer"
+	"github.com/ardanlabs/blockchain/foundation/events"
+)
+
+// CORE NOTE: Sync is header first. The headers from the latest local block to
+// the best known peer's latest block are downloaded first. Each header must
+// link to the previous one and, under PoW and PoA, its hash must solve its
+// difficulty as it's received, so a peer can't make the node download bodies
+// for headers it made up without doing the work. Under PoA the beneficiary
+// must also be the authority selected after the parent, but the signature of
+// the authority isn't part of the header and is only checked when the block
+// is applied. Under PoS the proposer can only be verified with the stakes at
+// that height, so the headers are not trusted: their seal is only checked
+// when each block is applied. The block
+// bodies are then downloaded in fixed size pages from every peer that has
+// them, in parallel. Each body must match the header it was announced with,
+// including the transaction root, before it's applied. Blocks are applied in
+// order and written to disk one at a time, so a sync that fails part way
+// resumes from the latest block on the next attempt.
+
+// Set of sizes used to page a sync.
+const (
+	MaxHeadersPerRequest = 1000
+	MaxBlocksPerRequest  = 100
+	blocksPerPage        = 50
+)
+
+// ErrSyncHeaders is returned when the headers received from a peer don't
+// form a sealed chain on top of the local latest block.
+var ErrSyncHeaders = errors.New("headers don't form a valid chain on top of the local chain")
+
+// NetSyncBlocks brings the local chain up to the latest block known by the
+// peers. The status of each peer tells which blocks it can serve.
+func (s *State) NetSyncBlocks(statuses map[peer.Peer]peer.PeerStatus) (err error) {
+	s.evHandler("state: NetSyncBlocks: started")
+	defer s.evHandler("state: NetSyncBlocks: completed")
+
+	latest := s.LatestBlock()
+
+	// The headers are pulled from the peer with the longest chain.
+	best, target := bestPeer(statuses)
+
+	if target <= latest.Header.Number {
+		return nil
+	}
+
+	s.evHandler("state: NetSyncBlocks: syncing blocks [%d] to [%d] headers from peer %s", latest.Header.Number+1, target, best.Host)
+
+	s.events.Publish(events.SyncStarted{From: latest.Header.Number + 1, To: target, Peer: best.Host})
+
+	start := time.Now()
+	defer func() {
+		completed := events.SyncCompleted{
+			Height:   s.LatestBlock().Header.Number,
+			Duration: time.Since(start),
+		}
+		if err != nil {
+			completed.Error = err.Error()
+		}
+		s.events.Publish(completed)
+	}()
+
+	headers, err := s.netRequestHeaders(best, latest, target)
+	if err != nil {
+		return err
+	}
+
+	if len(headers) == 0 {
+		return nil
+	}
+
+	// Only peers with the last block of a page are asked for it.
+	var sources []peer.Peer
+	var sourceLatest []uint64
+	for pr, status := range statuses {
+		if status.LatestBlockNum > latest.Header.Number {
+			sources = append(sources, pr)
+			sourceLatest = append(sourceLatest, status.LatestBlockNum)
+		}
+	}
+
+	type page struct {
+		headers []database.BlockHeader
+		blocks  []database.Block
+		err     error
+		done    chan struct{}
+	}
+
+	var pages []*page
+	for i := 0; i < len(headers); i += blocksPerPage {
+		end := i + blocksPerPage
+		if end > len(headers) {
+			end = len(headers)
+		}
+		pages = append(pages, &page{
+			headers: headers[i:end],
+			done:    make(chan struct{}),
+		})
+	}
+
+	// Pages are handed out to one goroutine per peer. A page that fails is
+	// retried with the next peer that has it.
+	jobs := make(chan int, len(pages))
+	for i := range pages {
+		jobs <- i
+	}
+	close(jobs)
+
+	quit := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(len(sources))
+
+	for w := range sources {
+		go func(w int) {
+			defer wg.Done()
+
+			for i := range jobs {
+				pg := pages[i]
+				last := pg.headers[len(pg.headers)-1].Number
+
+				for attempt := 0; attempt < len(sources); attempt++ {
+					select {
+					case <-quit:
+						pg.err = errors.New("sync stopped")
+						close(pg.done)
+						return
+					default:
+					}
+
+					src := (w + attempt) % len(sources)
+					if sourceLatest[src] < last {
+						pg.err = fmt.Errorf("no peer has block %d", last)
+						continue
+					}
+
+					pg.blocks, pg.err = s.netRequestBodies(sources[src], pg.headers)
+					if pg.err == nil {
+						break
+					}
+					s.evHandler("state: NetSyncBlocks: peer %s: page [%d]: WARNING %s", sources[src].Host, pg.headers[0].Number, pg.err)
+				}
+
+				close(pg.done)
+			}
+		}(w)
+	}
+
+	defer func() {
+		close(quit)
+		wg.Wait()
+	}()
+
+	// The blocks are applied in order as soon as their page has arrived.
+	for _, pg := range pages {
+		<-pg.done
+		if pg.err != nil {
+			return pg.err
+		}
+
+		for _, block := range pg.blocks {
+			if err := s.processBlock(block); err != nil {
+				return err
+			}
+		}
+
+		s.evHandler("state: NetSyncBlocks: applied blocks [%d] to [%d]", pg.headers[0].Number, pg.headers[len(pg.headers)-1].Number)
+	}
+
+	return nil
+}
+
+// QueryHeadersByNumber returns the headers of the blocks in the specified range.
+func (s *State) QueryHeadersByNumber(from, to uint64) ([]database.BlockHeader, error) {
+	blocks, err := s.QueryBlocksByNumber(from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	headers := make([]database.BlockHeader, len(blocks))
+	for i, block := range blocks {
+		headers[i] = block.Header
+	}
+
+	return headers, nil
+}
+
+// =============================================================================
+
+// netRequestHeaders downloads and verifies the headers on top of the latest
+// block up to the target block. The seal of each header is checked when the
+// engine can do it from the headers alone.
+func (s *State) netRequestHeaders(pr peer.Peer, latest database.Block, target uint64) ([]database.BlockHeader, error) {
+	var headers []database.BlockHeader
+
+	parent := latest.Header
+	prevHash := latest.Hash()
+	next := latest.Header.Number + 1
+
+	for next <= target {
+		to := next + MaxHeadersPerRequest - 1
+		if to > target {
+			to = target
+		}
+
+		url := fmt.Sprintf(pr.Url()+peer.HeadersUri, strconv.FormatUint(next, 10), strconv.FormatUint(to, 10))
+
+		var page []database.BlockHeader
+		if err := s.send(http.MethodGet, url, nil, &page); err != nil {
+			return nil, err
+		}
+
+		if len(page) == 0 {
+			break
+		}
+
+		for _, header := range page {
+			if header.Number != next || header.PrevBlockHash != prevHash {
+				return nil, fmt.Errorf("%w: blk[%d]", ErrSyncHeaders, header.Number)
+			}
+
+			err := s.engine.VerifyHeader(header, parent)
+			if err != nil && !errors.Is(err, consensus.ErrHeaderNotVerifiable) {
+				return nil, s.badResponse(pr, fmt.Errorf("%w: blk[%d]: %s", ErrSyncHeaders, header.Number, err))
+			}
+
+			block := database.Block{Header: header}
+			parent = header
+			prevHash = block.Hash()
+			next++
+
+			headers = append(headers, header)
+		}
+
+		s.evHandler("state: netRequestHeaders: peer %s: verified headers up to [%d]", pr.Host, next-1)
+	}
+
+	return headers, nil
+}
+
+// netRequestBodies downloads the blocks for the headers and checks each block
+// matches its header.
+func (s *State) netRequestBodies(pr peer.Peer, headers []database.BlockHeader) ([]database.Block, error) {
+	from := strconv.FormatUint(headers[0].Number, 10)
+	to := strconv.FormatUint(headers[len(headers)-1].Number, 10)
+	url := fmt.Sprintf(pr.Url()+peer.BlocksUri, from, to)
+
+	var blocksData []database.BlockData
+	req := p2p.GetBlocks{From: headers[0].Number, To: headers[len(headers)-1].Number}
+	streamed, err := s.netRequest(pr, p2p.MsgGetBlocks, req, &blocksData)
+	if err != nil {
+		return nil, err
+	}
+
+	if !streamed {
+		if err := s.send(http.MethodGet, url, nil, &blocksData); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(blocksData) != len(headers) {
+		return nil, s.badResponse(pr, fmt.Errorf("expected %d blocks, got %d", len(headers), len(blocksData)))
+	}
+
+	blocks := make([]database.Block, len(blocksData))
+	for i, blockData := range blocksData {
+		block, err := database.ToBlock(blockData)
+		if err != nil {
+			return nil, err
+		}
+
+		if block.Header != headers[i] {
+			return nil, s.badResponse(pr, fmt.Errorf("blk[%d] doesn't match its header", headers[i].Number))
+		}
+
+		if block.MerkleTree.RootHex() != headers[i].TransRoot {
+			return nil, s.badResponse(pr, fmt.Errorf("blk[%d]: %w", headers[i].Number, database.ErrInvalidTransRoot))
+		}
+
+		blocks[i] = block
+	}
+
+	s.evHandler("state: netRequestBodies: peer %s: blocks [%s] to [%s]", pr.Host, from, to)
+
+	return blocks, nil
+}
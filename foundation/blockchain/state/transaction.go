@@ -4,15 +4,19 @@ import (
 	"github.com/ardanlabs/blockchain/foundation/blockchain/database"
 )
 
-func (s *State) UpsertWalletTx(signedTx database.SignedTx) error {
+// oneUnitOfGas is the gas every transaction uses.
+const oneUnitOfGas = 1
+
+// UpsertWalletTx accepts a transaction signed by a wallet and returns it as
+// added to the mempool. Its hash covers the gas and time set by the node.
+func (s *State) UpsertWalletTx(signedTx database.SignedTx) (database.BlockTx, error) {
 	if err := signedTx.Validate(s.genesis.ChainID); err != nil {
-		return err
+		return database.BlockTx{}, err
 	}
 
-	const oneUnitOfGas = 1
 	tx := database.NewBlockTx(signedTx, uint64(s.genesis.GasPrice), oneUnitOfGas)
-	if err := s.mempool.Upsert(tx); err != nil {
-		return err
+	if err := s.upsertMempool(tx); err != nil {
+		return database.BlockTx{}, err
 	}
 
 	// Hack to mine a block when the mempool is full.
@@ -23,22 +27,16 @@ func (s *State) UpsertWalletTx(signedTx database.SignedTx) error {
 	// 	}()
 	// }
 
+	s.seen.Add(tx.HashString(), "")
 	s.Worker.SignalShareTx(tx)
 	s.Worker.SignalStartMining()
 
-	return nil
+	return tx, nil
 }
 
-func (s *State) UpsertNodeTransaction(tx database.BlockTx) error {
-	if err := tx.Validate(s.genesis.ChainID); err != nil {
-		return err
-	}
-
-	if err := s.mempool.Upsert(tx); err != nil {
-		return err
-	}
-
-	s.Worker.SignalStartMining()
-
-	return nil
+// UpsertNodeTransaction accepts a transaction sent by another node. The from
+// is the address the transaction came from, the sender is penalized by it
+// when the transaction is invalid.
+func (s *State) UpsertNodeTransaction(from string, tx database.BlockTx) error {
+	return s.upsertNodeTransaction(from, tx)
 }
@@ -0,0 +1,180 @@
+package state
+
+import (
+	"errors"
+	"time"
+
+	"github.com/ardanlabs/blockchain/foundation/blockchain/database"
+)
+
+// CORE NOTE: A transaction leaves the mempool when it's mined, when a
+// transaction with the same nonce and a higher tip or one mined by another
+// node takes its place, or when its nonce is used up. The chain only records
+// the mined ones, so the node keeps a short history of the others to tell a
+// wallet what happened to its transaction. The history lives in memory and is
+// lost on restart.
+
+// historySize is the number of replaced and dropped transactions remembered.
+const historySize = 1000
+
+// Set of statuses a transaction can have.
+const (
+	TxUnknown  = "unknown"
+	TxPending  = "pending"
+	TxReplaced = "replaced"
+	TxMined    = "mined"
+	TxDropped  = "dropped"
+)
+
+// TxStatus describes what happened to a transaction. Position is the place of
+// a pending transaction in the order the mempool would fill a block, starting
+// at 1. A mined transaction that failed has a reason and didn't change any
+// balance beyond the gas.
+type TxStatus struct {
+	Status        string
+	Hash          string
+	FromID        database.AccountID
+	Nonce         uint64
+	Position      int
+	BlockNumber   uint64
+	BlockHash     string
+	Confirmations uint64
+	Success       bool
+	Reason        string
+	ReplacedBy    string
+	Time          time.Time
+}
+
+// txChange is a transaction that left the mempool without being mined.
+type txChange struct {
+	tx         database.BlockTx
+	status     string
+	replacedBy string
+	reason     string
+	time       time.Time
+}
+
+// QueryTxStatus returns the status of the transaction with the specified hash.
+func (s *State) QueryTxStatus(hash string) (TxStatus, error) {
+	rec, err := s.QueryTx(hash)
+	switch {
+	case err == nil:
+		return s.txStatus(rec), nil
+
+	case !errors.Is(err, database.ErrTxNotFound):
+		return TxStatus{}, err
+	}
+
+	if change, exists := s.findChange(func(c txChange) bool { return c.tx.HashString() == hash }); exists {
+		return change.txStatus(), nil
+	}
+
+	return TxStatus{Status: TxUnknown, Hash: hash}, nil
+}
+
+// QueryTxStatusByNonce returns the status of the latest transaction sent by
+// the account with the specified nonce.
+func (s *State) QueryTxStatusByNonce(accountID database.AccountID, nonce uint64) (TxStatus, error) {
+	for _, tx := range s.mempool.PickBest() {
+		if tx.FromID == accountID && tx.Nonce == nonce {
+			return s.QueryTxStatus(tx.HashString())
+		}
+	}
+
+	hash, err := s.db.GetTxHashByNonce(accountID, nonce)
+	switch {
+	case err == nil:
+		return s.QueryTxStatus(hash)
+
+	case !errors.Is(err, database.ErrTxNotFound):
+		return TxStatus{}, err
+	}
+
+	if change, exists := s.findChange(func(c txChange) bool { return c.tx.FromID == accountID && c.tx.Nonce == nonce }); exists {
+		return change.txStatus(), nil
+	}
+
+	return TxStatus{Status: TxUnknown, FromID: accountID, Nonce: nonce}, nil
+}
+
+// =============================================================================
+
+// txStatus builds the status of a transaction found in the chain or the
+// mempool.
+func (s *State) txStatus(rec TxRecord) TxStatus {
+	hash := rec.Tx.HashString()
+
+	ts := TxStatus{
+		Hash:   hash,
+		FromID: rec.Tx.FromID,
+		Nonce:  rec.Tx.Nonce,
+	}
+
+	if rec.Pending {
+		ts.Status = TxPending
+		for i, tx := range s.mempool.PickBest() {
+			if tx.HashString() == hash {
+				ts.Position = i + 1
+				break
+			}
+		}
+		return ts
+	}
+
+	ts.Status = TxMined
+	ts.BlockNumber = rec.BlockNumber
+	ts.BlockHash = rec.BlockHash
+	ts.Confirmations = rec.Confirmations
+
+	reason, failed := s.db.GetTxFailure(hash)
+	ts.Success = !failed
+	ts.Reason = reason
+
+	return ts
+}
+
+// recordChange adds the transaction to the history, forgetting the oldest one
+// when it's full.
+func (s *State) recordChange(tx database.BlockTx, status string, replacedBy string, reason string) {
+	s.hmu.Lock()
+	defer s.hmu.Unlock()
+
+	if len(s.history) == historySize {
+		s.history = s.history[1:]
+	}
+
+	s.history = append(s.history, txChange{
+		tx:         tx,
+		status:     status,
+		replacedBy: replacedBy,
+		reason:     reason,
+		time:       time.Now().UTC(),
+	})
+}
+
+// findChange returns the latest change in the history the function reports
+// true for.
+func (s *State) findChange(fn func(c txChange) bool) (txChange, bool) {
+	s.hmu.Lock()
+	defer s.hmu.Unlock()
+
+	for i := len(s.history) - 1; i >= 0; i-- {
+		if fn(s.history[i]) {
+			return s.history[i], true
+		}
+	}
+
+	return txChange{}, false
+}
+
+func (c txChange) txStatus() TxStatus {
+	return TxStatus{
+		Status:     c.status,
+		Hash:       c.tx.HashString(),
+		FromID:     c.tx.FromID,
+		Nonce:      c.tx.Nonce,
+		Reason:     c.reason,
+		ReplacedBy: c.replacedBy,
+		Time:       c.time,
+	}
+}
@@ -0,0 +1,161 @@
+package state
+
+import (
+	"errors"
+
+	"github.com/ardanlabs/blockchain/foundation/blockchain/consensus"
+	"github.com/ardanlabs/blockchain/foundation/blockchain/database"
+	"github.com/ardanlabs/blockchain/foundation/blockchain/signature"
+)
+
+// CORE NOTE: External miners don't hold any chain state. They ask the node for
+// a block template, search for a nonce that solves the hash and submit it back.
+// The node keeps the templates it handed out, keyed by the hash of the header
+// without a nonce, so the block can be rebuilt with the transactions it was
+// built with. Templates are dropped as soon as a new block is added since they
+// can't be solved on top of the new head anymore. A template is built per
+// beneficiary, so the number kept is capped and the oldest is evicted to stop
+// callers from filling the memory of the node within a block.
+
+// maxWork is the maximum number of templates kept at the same time.
+const maxWork = 64
+
+// Set of errors returned by the work api.
+var (
+	ErrWorkNotSupported = errors.New("external mining requires the PoW consensus")
+	ErrUnknownWork      = errors.New("unknown or stale work")
+)
+
+// Work represents a block template for an external miner to solve.
+type Work struct {
+	ID     string               `json:"id"`
+	Header database.BlockHeader `json:"header"`
+	Target string               `json:"target"`
+}
+
+// GetWork returns a block template paying the mining reward to the specified
+// beneficiary. The same template is returned until the head of the chain
+// changes so miners don't restart their search on every call.
+func (s *State) GetWork(beneficiaryID database.AccountID) (Work, error) {
+	if s.engine.Name() != consensus.PoW {
+		return Work{}, ErrWorkNotSupported
+	}
+
+	if beneficiaryID == "" {
+		beneficiaryID = s.beneficiaryID
+	}
+
+	if s.mempool.Count() == 0 && !s.HeartbeatDue() {
+		return Work{}, ErrNoTransactions
+	}
+
+	s.wmu.Lock()
+	defer s.wmu.Unlock()
+
+	latest := s.db.LatestBlock()
+	s.pruneWork(latest)
+
+	for id, block := range s.work {
+		if block.Header.BeneficiaryID == beneficiaryID {
+			return newWork(id, block), nil
+		}
+	}
+
+	block, err := database.NewBlock(database.BlockArgs{
+		BeneficiaryID: beneficiaryID,
+		Difficulty:    s.genesis.Difficulty,
+		MiningReward:  s.genesis.MiningReward,
+		PrevBlock:     latest,
+		StateRoot:     s.db.HashState(),
+		Trans:         s.mempool.PickBest(s.genesis.TransPerBlock),
+	})
+	if err != nil {
+		return Work{}, err
+	}
+
+	if err := s.engine.PrepareHeader(s.db, &block.Header); err != nil {
+		return Work{}, err
+	}
+
+	id := signature.Hash(block.Header)
+	s.addWork(id, block)
+
+	s.evHandler("state: GetWork: new template id[%s] blk[%d] beneficiary[%s] txs[%d]", id, block.Header.Number, beneficiaryID, len(block.MerkleTree.Values()))
+
+	return newWork(id, block), nil
+}
+
+// SubmitWork accepts the nonce found by an external miner for a template. The
+// block is validated, added to the chain and sent to the known peers.
+func (s *State) SubmitWork(id string, nonce uint64) (database.Block, error) {
+	s.wmu.Lock()
+	s.pruneWork(s.db.LatestBlock())
+	block, exists := s.work[id]
+	s.wmu.Unlock()
+
+	if !exists {
+		return database.Block{}, ErrUnknownWork
+	}
+
+	block.Header.Nonce = nonce
+
+	if err := s.engine.VerifySeal(s.db, block, s.db.LatestBlock()); err != nil {
+		return database.Block{}, err
+	}
+
+	s.evHandler("state: SubmitWork: solved template id[%s] blk[%d] hash[%s]", id, block.Header.Number, block.Hash())
+
+	if err := s.validateUpdateDatabase(block); err != nil {
+		return database.Block{}, err
+	}
+
+	s.publishBlockMined(block, 0)
+
+	// The local miner is working on the same height which is now taken.
+	s.Worker.SignalCancelMining()
+
+	s.NetSendBlockToPeers(block)
+
+	return block, nil
+}
+
+// =============================================================================
+
+// addWork keeps the template, evicting the oldest ones when the maximum is
+// reached. The work lock must be held by the caller.
+func (s *State) addWork(id string, block database.Block) {
+	for len(s.workIDs) >= maxWork {
+		evicted := s.workIDs[0]
+		s.workIDs = s.workIDs[1:]
+		delete(s.work, evicted)
+
+		s.evHandler("state: GetWork: evicted template id[%s]", evicted)
+	}
+
+	s.work[id] = block
+	s.workIDs = append(s.workIDs, id)
+}
+
+// pruneWork drops the templates not built on top of the latest block. The work
+// lock must be held by the caller.
+func (s *State) pruneWork(latest database.Block) {
+	number := latest.Header.Number + 1
+
+	ids := s.workIDs[:0]
+	for _, id := range s.workIDs {
+		if s.work[id].Header.Number != number {
+			delete(s.work, id)
+			continue
+		}
+		ids = append(ids, id)
+	}
+	s.workIDs = ids
+}
+
+func newWork(id string, block database.Block) Work {
+	return Work{
+		ID:     id,
+		Header: block.Header,
+		Target: consensus.Target(block.Header.Difficulty),
+	}
+}
@@ -8,12 +8,22 @@ import (
 	"os"
 	"path"
 	"strconv"
+	"sync"
 
 	"github.com/ardanlabs/blockchain/foundation/blockchain/database"
 )
 
+// snapshotFile is the name of the file holding the snapshot of a fast synced
+// node.
+const snapshotFile = "snapshot.json"
+
+// Disk stores every block in its own file named after the block number. The
+// number of the blocks written or read is indexed by hash in memory.
 type Disk struct {
 	dbPath string
+
+	mu     sync.RWMutex
+	hashes map[string]uint64
 }
 
 func New(dbPath string) (*Disk, error) {
@@ -22,6 +32,7 @@ func New(dbPath string) (*Disk, error) {
 	}
 	return &Disk{
 		dbPath: dbPath,
+		hashes: make(map[string]uint64),
 	}, nil
 }
 
@@ -35,7 +46,7 @@ func (d *Disk) Write(blockData database.BlockData) error {
 	if err != nil {
 		return err
 	}
-	f, err := os.OpenFile(d.getPath(blockData.Header.Number), os.O_CREATE|os.O_RDWR, 0755)
+	f, err := os.OpenFile(d.getPath(blockData.Header.Number), os.O_CREATE|os.O_TRUNC|os.O_RDWR, 0755)
 	if err != nil {
 		return err
 	}
@@ -45,9 +56,19 @@ func (d *Disk) Write(blockData database.BlockData) error {
 		return err
 	}
 
+	d.index(blockData)
+
 	return nil
 }
 
+// index records the number of the block under its hash.
+func (d *Disk) index(blockData database.BlockData) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.hashes[blockData.Hash] = blockData.Header.Number
+}
+
 func (d *Disk) getPath(blockNum uint64) string {
 	name := strconv.FormatUint(blockNum, 10)
 	return path.Join(d.dbPath, fmt.Sprintf("%s.json", name))
@@ -66,12 +87,33 @@ func (d *Disk) GetBlockByNumber(num uint64) (database.BlockData, error) {
 		return database.BlockData{}, err
 	}
 
+	d.index(blockData)
+
 	return blockData, nil
 }
 
+// GetBlock returns the block with the hash. Every block is read when the
+// database starts, so the index covers all the stored blocks.
 func (d *Disk) GetBlock(hash string) (database.BlockData, error) {
-	// return d.GetBlock(num)
-	return database.BlockData{}, errors.New("not implemented")
+	d.mu.RLock()
+	num, exists := d.hashes[hash]
+	d.mu.RUnlock()
+
+	if !exists {
+		return database.BlockData{}, database.ErrBlockNotFound
+	}
+
+	blockData, err := d.GetBlockByNumber(num)
+	if err != nil {
+		return database.BlockData{}, err
+	}
+
+	// The block could have been replaced since it was indexed.
+	if blockData.Hash != hash {
+		return database.BlockData{}, database.ErrBlockNotFound
+	}
+
+	return blockData, nil
 }
 
 func (d *Disk) Reset() error {
@@ -79,12 +121,52 @@ func (d *Disk) Reset() error {
 		return err
 	}
 
+	d.mu.Lock()
+	d.hashes = make(map[string]uint64)
+	d.mu.Unlock()
+
 	return os.MkdirAll(d.dbPath, 0755)
 }
 
+// WriteSnapshot stores the snapshot a fast synced node starts from.
+func (d *Disk) WriteSnapshot(snapshot database.Snapshot) error {
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(path.Join(d.dbPath, snapshotFile), data, 0600)
+}
+
+// ReadSnapshot returns the snapshot a fast synced node starts from.
+func (d *Disk) ReadSnapshot() (database.Snapshot, error) {
+	data, err := os.ReadFile(path.Join(d.dbPath, snapshotFile))
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return database.Snapshot{}, database.ErrSnapshotNotFound
+		}
+		return database.Snapshot{}, err
+	}
+
+	var snapshot database.Snapshot
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		return database.Snapshot{}, err
+	}
+
+	return snapshot, nil
+}
+
+// ForEach iterates over the stored blocks. When the node started from a
+// snapshot the blocks before it were never stored.
 func (d *Disk) ForEach() database.Iterator {
+	var current uint64
+	if snapshot, err := d.ReadSnapshot(); err == nil {
+		current = snapshot.Header.Number
+	}
+
 	return &diskIterator{
 		storage: d,
+		current: current,
 	}
 }
 
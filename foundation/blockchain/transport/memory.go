@@ -0,0 +1,324 @@
+package transport
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Set of errors returned by the memory network.
+var (
+	ErrUnreachable = errors.New("host unreachable")
+	ErrDropped     = errors.New("request dropped")
+)
+
+// Network connects nodes running in the same process. Every node registers the
+// handler of its private api under its host and uses the transport returned by
+// Transport to reach the others.
+type Network struct {
+	mu        sync.RWMutex
+	handlers  map[string]http.Handler
+	latency   time.Duration
+	jitter    time.Duration
+	dropRate  float64
+	partition map[string]int
+	rand      *rand.Rand
+}
+
+// NewNetwork constructs an empty network. The seed makes the dropped requests
+// and the latency jitter repeatable.
+func NewNetwork(seed int64) *Network {
+	return &Network{
+		handlers: make(map[string]http.Handler),
+		rand:     rand.New(rand.NewSource(seed)),
+	}
+}
+
+// Register makes the handler reachable at the host.
+func (n *Network) Register(host string, h http.Handler) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	n.handlers[host] = h
+}
+
+// Unregister makes the host unreachable, as if the node was stopped.
+func (n *Network) Unregister(host string) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	delete(n.handlers, host)
+}
+
+// SetLatency delays every request and every write on a connection by the
+// latency plus a random amount up to the jitter.
+func (n *Network) SetLatency(latency time.Duration, jitter time.Duration) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	n.latency = latency
+	n.jitter = jitter
+}
+
+// SetDropRate sets the fraction of requests, between 0 and 1, that fail.
+func (n *Network) SetDropRate(rate float64) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	n.dropRate = rate
+}
+
+// Partition splits the network into the groups of hosts. Hosts in different
+// groups can't reach each other. Hosts not listed form a group of their own.
+func (n *Network) Partition(groups ...[]string) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	n.partition = make(map[string]int)
+	for i, group := range groups {
+		for _, host := range group {
+			n.partition[host] = i + 1
+		}
+	}
+}
+
+// Heal removes the partitions.
+func (n *Network) Heal() {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	n.partition = nil
+}
+
+// Transport returns the transport used by the node at the host.
+func (n *Network) Transport(host string) Transport {
+	return &memory{
+		network: n,
+		host:    host,
+	}
+}
+
+// =============================================================================
+
+// route returns the handler at the host if the request can go through.
+func (n *Network) route(from string, to string) (http.Handler, error) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	h, exists := n.handlers[to]
+	if !exists || !n.connected(from, to) {
+		return nil, fmt.Errorf("%s: %w", to, ErrUnreachable)
+	}
+
+	if n.dropRate > 0 && n.rand.Float64() < n.dropRate {
+		return nil, fmt.Errorf("%s: %w", to, ErrDropped)
+	}
+
+	return h, nil
+}
+
+// connected reports if the hosts are in the same partition. The lock must be
+// held by the caller.
+func (n *Network) connected(from string, to string) bool {
+	if n.partition == nil {
+		return true
+	}
+	return n.partition[from] == n.partition[to]
+}
+
+// delay waits for the latency of the network.
+func (n *Network) delay() {
+	n.mu.Lock()
+	d := n.latency
+	if n.jitter > 0 {
+		d += time.Duration(n.rand.Int63n(int64(n.jitter)))
+	}
+	n.mu.Unlock()
+
+	if d > 0 {
+		time.Sleep(d)
+	}
+}
+
+// =============================================================================
+
+// memory is the transport of one node on the memory network.
+type memory struct {
+	network *Network
+	host    string
+}
+
+// Do serves the request with the handler registered at the host of the url.
+func (m *memory) Do(req *http.Request) (*http.Response, error) {
+	h, err := m.network.route(m.host, req.URL.Host)
+	if err != nil {
+		return nil, err
+	}
+
+	m.network.delay()
+
+	// The request is served as if it came in from the node, so handlers
+	// keyed by the address of the sender tell the nodes apart.
+	req = req.Clone(req.Context())
+	req.RemoteAddr = m.host
+
+	var w response
+	h.ServeHTTP(&w, req)
+
+	return w.result(req), nil
+}
+
+// Dial opens a connection with the node at the host. The other end of the
+// connection is served by its handler like an incoming TCP connection, so
+// requests to open a stream work as on the real network.
+func (m *memory) Dial(host string) (net.Conn, error) {
+	h, err := m.network.route(m.host, host)
+	if err != nil {
+		return nil, err
+	}
+
+	client, server := net.Pipe()
+
+	go serveConn(h, &partitionedConn{Conn: server, network: m.network, from: host, to: m.host}, m.host)
+
+	return &partitionedConn{Conn: client, network: m.network, from: m.host, to: host}, nil
+}
+
+// partitionedConn fails the writes made while the hosts are partitioned.
+type partitionedConn struct {
+	net.Conn
+	network *Network
+	from    string
+	to      string
+}
+
+func (c *partitionedConn) Write(b []byte) (int, error) {
+	c.network.mu.RLock()
+	connected := c.network.connected(c.from, c.to)
+	_, exists := c.network.handlers[c.to]
+	c.network.mu.RUnlock()
+
+	if !connected || !exists {
+		c.Conn.Close()
+		return 0, fmt.Errorf("%s: %w", c.to, ErrUnreachable)
+	}
+
+	c.network.delay()
+
+	return c.Conn.Write(b)
+}
+
+// serveConn reads the requests from the connection opened by the node at the
+// remote host and serves them until the handler takes over the connection or
+// it closes.
+func serveConn(h http.Handler, conn net.Conn, remote string) {
+	rd := bufio.NewReader(conn)
+
+	for {
+		req, err := http.ReadRequest(rd)
+		if err != nil {
+			conn.Close()
+			return
+		}
+		req.RemoteAddr = remote
+
+		w := connWriter{
+			conn: conn,
+			rw:   bufio.NewReadWriter(rd, bufio.NewWriter(conn)),
+		}
+		h.ServeHTTP(&w, req)
+
+		if w.hijacked {
+			return
+		}
+
+		if err := w.finish(req); err != nil {
+			conn.Close()
+			return
+		}
+	}
+}
+
+// response is the response writer for the requests served by the memory
+// transport. The response is kept until the handler returns.
+type response struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (w *response) Header() http.Header {
+	if w.header == nil {
+		w.header = make(http.Header)
+	}
+	return w.header
+}
+
+func (w *response) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	return w.body.Write(b)
+}
+
+func (w *response) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+}
+
+// result returns the response written by the handler.
+func (w *response) result(req *http.Request) *http.Response {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", w.status, http.StatusText(w.status)),
+		StatusCode:    w.status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        w.Header(),
+		ContentLength: int64(w.body.Len()),
+		Body:          readCloser{&w.body},
+		Request:       req,
+	}
+}
+
+// connWriter is the response writer for the requests served on a memory
+// connection. It supports taking over the connection.
+type connWriter struct {
+	response
+	conn     net.Conn
+	rw       *bufio.ReadWriter
+	hijacked bool
+}
+
+// Hijack hands the connection over to the handler.
+func (w *connWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	w.hijacked = true
+	return w.conn, w.rw, nil
+}
+
+// finish writes the response.
+func (w *connWriter) finish(req *http.Request) error {
+	if err := w.result(req).Write(w.rw); err != nil {
+		return err
+	}
+	return w.rw.Flush()
+}
+
+type readCloser struct {
+	*bytes.Buffer
+}
+
+func (readCloser) Close() error {
+	return nil
+}
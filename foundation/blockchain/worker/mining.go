@@ -0,0 +1,174 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/ardanlabs/blockchain/foundation/blockchain/consensus"
+	"github.com/ardanlabs/blockchain/foundation/blockchain/state"
+)
+
+// CORE NOTE: The mining operation is managed by this function which runs on its
+// own goroutine. The consensus engine schedules block production. Engines that
+// mine on demand (PoW) start when a startMining signal is received, mainly
+// because a wallet transaction was received. A minimum block interval delays
+// that start so transactions received in a burst are mined in one block.
+// Engines that work in cycles or slots (PoA, PoS) wake up at the scheduled time
+// and the engine decides if this node is the one to produce the block. When
+// heartbeat blocks are enabled, an empty block is produced once no block was
+// added for the heartbeat interval. The operation can be cancelled if a
+// proposed block is received and it's validated. While an operator has mining
+// paused, every operation is skipped.
+
+// miningOperations is the main block production loop.
+func (w *Worker) miningOperations() {
+	w.evHandler("worker: miningOperations: Goroutine started")
+	defer w.evHandler("worker: miningOperations: Goroutine completed")
+
+	engine := w.state.Engine()
+
+	// Set when a start mining signal was deferred by the minimum block interval.
+	var pending bool
+
+	for {
+		next := engine.Schedule(time.Now())
+		onDemand := next.IsZero()
+
+		if onDemand {
+			if pending {
+				next = w.state.NextBlockTime()
+			}
+			if hb := w.state.NextHeartbeat(); !hb.IsZero() && (next.IsZero() || hb.Before(next)) {
+				next = hb
+			}
+		}
+
+		// A nil channel blocks forever which leaves on demand engines
+		// waiting for the start mining signal.
+		var scheduled <-chan time.Time
+		var timer *time.Timer
+		if !next.IsZero() {
+			timer = time.NewTimer(time.Until(next))
+			scheduled = timer.C
+		}
+
+		select {
+		case <-w.startMining:
+			if onDemand && !w.isShutdown() {
+				if wait := time.Until(w.state.NextBlockTime()); wait > 0 {
+					w.evHandler("worker: miningOperations: mining deferred %s by the minimum block interval", wait.Round(time.Millisecond))
+					pending = true
+					break
+				}
+				pending = false
+				w.runMiningOperation()
+			}
+		case <-scheduled:
+			pending = false
+			if !w.isShutdown() {
+				w.runMiningOperation()
+			}
+		case <-w.shutdown:
+			if timer != nil {
+				timer.Stop()
+			}
+			w.evHandler("worker: miningOperations: received shut signal")
+			return
+		}
+
+		if timer != nil {
+			timer.Stop()
+		}
+	}
+}
+
+func (w *Worker) runMiningOperation() {
+	w.evHandler("worker: runMiningOperation: started")
+	defer w.evHandler("worker: runMiningOperation: completed")
+
+	if w.isPaused() {
+		w.evHandler("worker: runMiningOperation: mining is paused")
+		return
+	}
+
+	w.setMining(true)
+	defer w.setMining(false)
+
+	memLen := w.state.MempoolLength()
+	if memLen == 0 && !w.state.HeartbeatDue() {
+		w.evHandler("worker: runMiningOperation: mempool is empty")
+		return
+	}
+
+	// After running a mining operation, check if a new operation should
+	// signaled again. Only on demand engines listen for the signal.
+	defer func() {
+		length := w.state.MempoolLength()
+		if length > 0 {
+			w.evHandler("worker: runMiningOperation: signaling new mining operations Txs[%d]", length)
+			w.SignalStartMining()
+		}
+	}()
+
+	// Drain the cancel signal channel
+	select {
+	case <-w.cancelMining:
+		w.evHandler("worker: runMiningOperation: drained cancel channel")
+	default:
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+
+	go func() {
+		defer func() {
+			cancel()
+			wg.Done()
+		}()
+
+		select {
+		case <-w.cancelMining:
+			w.evHandler("worker: runMiningOperation: Cancel Requested")
+		case <-ctx.Done():
+		}
+	}()
+
+	// The GoRoutine that performs mining operations
+	go func() {
+		defer func() {
+			cancel()
+			wg.Done()
+		}()
+
+		t := time.Now()
+		block, err := w.state.MineNewBlock(ctx)
+		duration := time.Since(t)
+
+		w.evHandler("worker: runMiningOperation: MineNewBlock completed in %s", duration)
+
+		if err != nil {
+			switch {
+			case errors.Is(err, consensus.ErrNotProposer):
+				w.evHandler("worker: runMiningOperation: MineNewBlock: not selected to propose")
+			case errors.Is(err, state.ErrNoTransactions):
+				w.evHandler("worker: runMiningOperation: MineNewBlock: no transactions in mempool")
+			case ctx.Err() != nil:
+				w.evHandler("worker: runMiningOperation: MineNewBlock: context canceled")
+			default:
+				w.evHandler("worker: runMiningOperation: MineNewBlock: %s", err)
+			}
+			return
+		}
+
+		// WOW, we mined a block
+		w.state.NetSendBlockToPeers(block)
+
+	}()
+
+	wg.Wait()
+}
@@ -15,7 +15,8 @@ const poaPeerInterval = time.Second * 10
 // All new peer nodes will connect to the leader node to identify the network.
 // The topology of the network is a star topology. The leader node is the center
 // of the star and all other nodes are the points of the star.
-// If a node does not respond to a network call it will be removed from the network.
+// If a node does not respond to a network call it is retried with a backoff and
+// removed from the network when it keeps failing.
 func (w *Worker) peerOperations() {
 	w.evHandler("worker: peerOperations: Goroutine started")
 	defer w.evHandler("worker: peerOperations Goroutine completed")
@@ -33,6 +34,10 @@ func (w *Worker) peerOperations() {
 			if !w.isShutdown() {
 				w.runPeerOperations()
 			}
+		case <-w.resync:
+			if !w.isShutdown() {
+				w.Sync()
+			}
 		case <-w.shutdown:
 			w.evHandler("worker: peerOperations: shutdown received")
 			return
@@ -50,7 +55,7 @@ func (w *Worker) addNewPeers(knownPeers []peer.Peer) error {
 			continue
 		}
 
-		if w.state.AddKnownPeer(peer) {
+		if w.state.NetLearnPeer(peer) {
 			w.evHandler("worker: runPeerUpdateOperations: addNewPeers: added peer %s", peer.Host)
 		}
 	}
@@ -67,14 +72,23 @@ func (w *Worker) runPeerOperations() {
 		if err != nil {
 			w.evHandler("worker: runPeerOperations: queryPeerStatus: %s ERROR %s", peer.Host, err)
 
-			// Remove the peer from the network.
-			w.state.RemoveKnownPeer(peer)
+			// The peer is retried after a backoff and removed from the
+			// network if it keeps failing.
+			w.state.RecordPeerFailure(peer)
 			continue
 		}
+		w.state.RecordPeerSuccess(peer)
 
 		// Add new peers to this nodes list
 		w.addNewPeers(peerStatus.KnownPeers)
 	}
+
+	// Replace the peers that were dropped with peers from the address book.
+	w.state.RefreshPeers()
+
+	// Open a stream with the peers that don't have one yet.
+	w.state.NetConnectPeers()
+
 	// Share with peers this node is available to participate in the network.
 	w.state.NetSendNodeAvailableToPeers()
 }
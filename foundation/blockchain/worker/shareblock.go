@@ -0,0 +1,27 @@
+package worker
+
+// CORE NOTE: Blocks received from a peer are relayed to the other peers by this
+// goroutine so they reach nodes that aren't connected to the proposer. The
+// state hands over a block only the first time it's accepted.
+
+// maxBlockShareRequests is the maximum number of blocks that can be pending to
+// be relayed over the p2p network.
+const maxBlockShareRequests = 10
+
+func (w *Worker) shareBlockOperations() {
+	w.evHandler("worker: shareBlockOperations: started")
+	defer w.evHandler("worker: shareBlockOperations: stopped")
+
+	for {
+		select {
+		case block := <-w.blockSharing:
+			if !w.isShutdown() {
+				w.evHandler("worker: shareBlockOperations: received block to share")
+				w.state.NetSendBlockToPeers(block)
+			}
+		case <-w.shutdown:
+			w.evHandler("worker: shareBlockOperations: shutdown")
+			return
+		}
+	}
+}
@@ -1,6 +1,6 @@
 package worker
 
-// CORE NOTE: Sharing new transactions received directly by a wallet is performed by this goroutines.
+// CORE NOTE: Sharing new transactions received by a wallet or relayed by a peer is performed by this goroutines.
 // When a wallet transaction is received, the request goroutine shares it with this goroutine to send it over the p2p network.
 // Up to 100 transactions can be pending to be sent before new transactions are dropped and not sent.
 
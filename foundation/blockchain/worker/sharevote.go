@@ -0,0 +1,27 @@
+package worker
+
+// CORE NOTE: Finality votes cast by this node are shared by this goroutine. The
+// state casts a vote while it holds its locks, so the network calls happen here
+// to keep block processing from waiting on peers.
+
+// maxVoteShareRequests is the maximum number of votes that can be pending to be
+// sent over the p2p network. Each block produces at most two votes per node.
+const maxVoteShareRequests = 100
+
+func (w *Worker) shareVoteOperations() {
+	w.evHandler("worker: shareVoteOperations: started")
+	defer w.evHandler("worker: shareVoteOperations: stopped")
+
+	for {
+		select {
+		case vote := <-w.voteSharing:
+			if !w.isShutdown() {
+				w.evHandler("worker: shareVoteOperations: received vote to share")
+				w.state.NetSendVoteToPeers(vote)
+			}
+		case <-w.shutdown:
+			w.evHandler("worker: shareVoteOperations: shutdown")
+			return
+		}
+	}
+}
@@ -1,5 +1,7 @@
 package worker
 
+import "github.com/ardanlabs/blockchain/foundation/blockchain/peer"
+
 // CORE NOTE: This function is called when the node starts. It will sync the node with the
 // network. This is a blocking operation. It includes the mempool and blochain database.
 // This operation needs to finish before the node can participate in the network.
@@ -8,37 +10,69 @@ func (w *Worker) Sync() {
 	w.evHandler("worker: SYNC: started")
 	defer w.evHandler("worker: SYNC: completed")
 
-	for _, peer := range w.state.KnowExternalPeers() {
+	w.setSyncing(true)
+	defer w.setSyncing(false)
 
-		peerStatus, err := w.state.NetRequestPeerStatus(peer)
-		if err != nil {
-			w.evHandler("worker: SYNC: queryPeerStatus: %s ERROR %s", peer.Host, err)
-			continue
-		}
+	// Open the streams with the known peers so the sync can use them.
+	w.state.NetConnectPeers()
 
-		// Add new peers to this nodes list
-		w.addNewPeers(peerStatus.KnownPeers)
+	statuses := make(map[peer.Peer]peer.PeerStatus)
+	visited := make(map[string]bool)
 
-		pool, err := w.state.NetRequestMempool(peer)
-		if err != nil {
-			w.evHandler("worker: SYNC: retrievePeerMempool: %s ERROR %s", peer.Host, err)
-			continue
+	// Peers learned from the status of other peers are queried as well so
+	// the blocks can be downloaded from as many peers as possible.
+	for {
+		var pending []peer.Peer
+		for _, pr := range w.state.KnowExternalPeers() {
+			if !visited[pr.Host] {
+				pending = append(pending, pr)
+			}
 		}
 
-		for _, tx := range pool {
-			w.evHandler("worker: SYNC: retrievePeerMempool: %s TX %s", peer.Host, tx.SignatureString()[:16])
-			w.state.UpsertMempool(tx)
+		if len(pending) == 0 {
+			break
 		}
 
-		if peerStatus.LatestBlockNum > w.state.LatestBlock().Header.Number {
-			w.evHandler("worker: SYNC: retrivePeerBlocks: %s: latestBlockNumber [%d]", peer.Host, peerStatus.LatestBlockNum)
+		for _, peer := range pending {
+			visited[peer.Host] = true
 
-			if err := w.state.NetRequestPeerBlocks(peer); err != nil {
-				w.evHandler("worker: SYNC: retrievePeerBlockchain: %s ERROR %s", peer.Host, err)
+			peerStatus, err := w.state.NetRequestPeerStatus(peer)
+			if err != nil {
+				w.evHandler("worker: SYNC: queryPeerStatus: %s ERROR %s", peer.Host, err)
+				w.state.RecordPeerFailure(peer)
+				continue
 			}
+			w.state.RecordPeerSuccess(peer)
+			statuses[peer] = peerStatus
+
+			// Add new peers to this nodes list
+			w.addNewPeers(peerStatus.KnownPeers)
+
+			pool, err := w.state.NetRequestMempool(peer)
+			if err != nil {
+				w.evHandler("worker: SYNC: retrievePeerMempool: %s ERROR %s", peer.Host, err)
+				continue
+			}
+
+			for _, tx := range pool {
+				w.evHandler("worker: SYNC: retrievePeerMempool: %s TX %s", peer.Host, tx.SignatureString()[:16])
+				w.state.UpsertMempool(tx)
+			}
+		}
+	}
+
+	// An empty node can skip executing the blocks covered by a snapshot.
+	if w.state.FastSync() {
+		if err := w.state.NetFastSync(statuses); err != nil {
+			w.evHandler("worker: SYNC: NetFastSync: ERROR %s", err)
 		}
 	}
 
+	// The blocks are downloaded from all the peers at once.
+	if err := w.state.NetSyncBlocks(statuses); err != nil {
+		w.evHandler("worker: SYNC: NetSyncBlocks: ERROR %s", err)
+	}
+
 	// Share with peers this is available to participate in the network.
 	w.state.NetSendNodeAvailableToPeers()
 }
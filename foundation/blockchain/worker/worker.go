@@ -15,12 +15,23 @@ type Worker struct {
 	shutdown     chan struct{}
 	startMining  chan bool
 	cancelMining chan bool
+	resync       chan bool
 	txSharing    chan database.BlockTx
-	evHandler    state.EventHandler
+	blockSharing chan database.Block
+	voteSharing  chan database.SignedVote
+	evHandler    func(v string, args ...any)
+
+	// What the goroutines are doing, reported to the operator.
+	smu     sync.Mutex
+	running map[string]bool
+	paused  bool
+	mining  bool
+	syncing bool
 }
 
-// Run creates a worker, registers it with the state and starts it.
-func Run(st *state.State, evHandler state.EventHandler) {
+// Run creates a worker, registers it with the state and starts it. The worker
+// publishes its trace messages on the event bus of the state.
+func Run(st *state.State) {
 
 	w := Worker{
 		state:        st,
@@ -28,15 +39,12 @@ func Run(st *state.State, evHandler state.EventHandler) {
 		shutdown:     make(chan struct{}),
 		startMining:  make(chan bool, 1),
 		cancelMining: make(chan bool, 1),
+		resync:       make(chan bool, 1),
 		txSharing:    make(chan database.BlockTx, maxTxShareRequests),
-		evHandler:    evHandler,
-	}
-
-	consensusOperation := w.powOperations
-	if st.Consensus() == state.ConsensusPoA {
-		consensusOperation = w.poaOperations
-		// Validate ticker works as intended
-		// w.ticker = *time.NewTicker(poaPeerInterval)
+		blockSharing: make(chan database.Block, maxBlockShareRequests),
+		voteSharing:  make(chan database.SignedVote, maxVoteShareRequests),
+		evHandler:    st.Events().Tracef,
+		running:      make(map[string]bool),
 	}
 
 	st.Worker = &w
@@ -46,10 +54,12 @@ func Run(st *state.State, evHandler state.EventHandler) {
 
 	// Load the set of operations to run.
 
-	operations := []func(){
-		consensusOperation,
-		w.peerOperations,
-		w.shareTxOperations,
+	operations := map[string]func(){
+		"mining":      w.miningOperations,
+		"peer":        w.peerOperations,
+		"share_tx":    w.shareTxOperations,
+		"share_block": w.shareBlockOperations,
+		"share_vote":  w.shareVoteOperations,
 	}
 
 	g := len(operations)
@@ -58,12 +68,14 @@ func Run(st *state.State, evHandler state.EventHandler) {
 	// We don't want to return until we know all the goroutines have started.
 	hasStarted := make(chan bool)
 
-	for _, op := range operations {
-		go func(op func()) {
+	for name, op := range operations {
+		go func(name string, op func()) {
 			defer w.wg.Done()
+			w.setRunning(name, true)
+			defer w.setRunning(name, false)
 			hasStarted <- true
 			op()
-		}(op)
+		}(name, op)
 	}
 
 	// Wait for all the goroutines to start, before returnin to main.
@@ -121,6 +133,76 @@ func (w *Worker) SignalShareTx(tx database.BlockTx) {
 	}
 }
 
+func (w *Worker) SignalShareBlock(block database.Block) {
+	select {
+	case w.blockSharing <- block:
+		w.evHandler("worker: SignalShareBlock: block sharing signaled")
+	default:
+		w.evHandler("worker: SignalShareBlock: block sharing signaled (dropped)")
+	}
+}
+
+func (w *Worker) SignalShareVote(vote database.SignedVote) {
+	select {
+	case w.voteSharing <- vote:
+		w.evHandler("worker: SignalShareVote: vote sharing signaled")
+	default:
+		w.evHandler("worker: SignalShareVote: vote sharing signaled (dropped)")
+	}
+}
+
+// SignalResync asks the peer operations to sync the node with the network.
+func (w *Worker) SignalResync() {
+	select {
+	case w.resync <- true:
+	default:
+	}
+	w.evHandler("worker: SignalResync: resync signaled")
+}
+
+// PauseMining stops the node from producing blocks until mining is resumed.
+// A mining operation in progress is canceled. Blocks from the peers are still
+// accepted.
+func (w *Worker) PauseMining() {
+	w.smu.Lock()
+	w.paused = true
+	w.smu.Unlock()
+
+	w.SignalCancelMining()
+	w.evHandler("worker: PauseMining: mining paused")
+}
+
+// ResumeMining lets the node produce blocks again.
+func (w *Worker) ResumeMining() {
+	w.smu.Lock()
+	w.paused = false
+	w.smu.Unlock()
+
+	w.evHandler("worker: ResumeMining: mining resumed")
+	w.SignalStartMining()
+}
+
+// Status reports what the goroutines are doing.
+func (w *Worker) Status() state.WorkerStatus {
+	w.smu.Lock()
+	defer w.smu.Unlock()
+
+	running := make(map[string]bool, len(w.running))
+	for name, r := range w.running {
+		running[name] = r
+	}
+
+	return state.WorkerStatus{
+		Operations:   running,
+		MiningPaused: w.paused,
+		Mining:       w.mining,
+		Syncing:      w.syncing,
+		TxQueue:      len(w.txSharing),
+		BlockQueue:   len(w.blockSharing),
+		VoteQueue:    len(w.voteSharing),
+	}
+}
+
 func (w *Worker) SignalCancelMining() {
 	select {
 	case w.cancelMining <- true:
@@ -128,3 +210,33 @@ func (w *Worker) SignalCancelMining() {
 	}
 	w.evHandler("worker: SignalCancelMining: mining canceled")
 }
+
+// =============================================================================
+
+func (w *Worker) setRunning(name string, running bool) {
+	w.smu.Lock()
+	defer w.smu.Unlock()
+
+	w.running[name] = running
+}
+
+func (w *Worker) setMining(mining bool) {
+	w.smu.Lock()
+	defer w.smu.Unlock()
+
+	w.mining = mining
+}
+
+func (w *Worker) setSyncing(syncing bool) {
+	w.smu.Lock()
+	defer w.smu.Unlock()
+
+	w.syncing = syncing
+}
+
+func (w *Worker) isPaused() bool {
+	w.smu.Lock()
+	defer w.smu.Unlock()
+
+	return w.paused
+}
@@ -0,0 +1,188 @@
+// Package events provides an in-process bus that delivers the events published
+// by the node to every subscriber.
+package events
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// subscriberBuffer is the number of events held for a subscription before new
+// events are dropped. A slow client never blocks the publisher.
+const subscriberBuffer = 100
+
+// Event is implemented by every event published on the bus.
+type Event interface {
+	EventType() string
+}
+
+// accounter is implemented by the events that concern specific accounts.
+type accounter interface {
+	EventAccounts() []string
+}
+
+// Filter selects the events delivered to a subscription. An empty field
+// matches everything.
+type Filter struct {
+	Types    []string
+	Accounts []string
+}
+
+// Match reports if the event passes the filter. Accounts are matched without
+// regard to case since clients don't always send checksummed addresses.
+func (f Filter) Match(evt Event) bool {
+	if len(f.Types) > 0 && !contains(f.Types, evt.EventType()) {
+		return false
+	}
+
+	if len(f.Accounts) == 0 {
+		return true
+	}
+
+	acc, ok := evt.(accounter)
+	if !ok {
+		return false
+	}
+
+	for _, account := range acc.EventAccounts() {
+		if contains(f.Accounts, account) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// =============================================================================
+
+// Subscription receives the events that pass its filter on C. C is closed
+// when the subscription ends.
+type Subscription struct {
+	C <-chan Event
+
+	id     uint64
+	ch     chan Event
+	filter Filter
+}
+
+// Events is the bus the events are published on. Handlers are called with
+// every event on the publishing goroutine and never miss one. Subscriptions
+// receive the events on a channel and miss the ones published while their
+// channel is full.
+type Events struct {
+	mu       sync.RWMutex
+	handlers map[uint64]func(Event)
+	subs     map[uint64]*Subscription
+	next     uint64
+}
+
+// New constructs an Events for use.
+func New() *Events {
+	return &Events{
+		handlers: make(map[uint64]func(Event)),
+		subs:     make(map[uint64]*Subscription),
+	}
+}
+
+// Handle registers a function called with every event published. The function
+// runs on the goroutine publishing the event, so it must return quickly and
+// must not publish events itself. The function returned removes the handler.
+func (evts *Events) Handle(fn func(Event)) func() {
+	evts.mu.Lock()
+	defer evts.mu.Unlock()
+
+	evts.next++
+	id := evts.next
+	evts.handlers[id] = fn
+
+	return func() {
+		evts.mu.Lock()
+		defer evts.mu.Unlock()
+
+		delete(evts.handlers, id)
+	}
+}
+
+// Subscribe adds a subscription for the events that pass the filter.
+func (evts *Events) Subscribe(filter Filter) *Subscription {
+	evts.mu.Lock()
+	defer evts.mu.Unlock()
+
+	evts.next++
+
+	ch := make(chan Event, subscriberBuffer)
+	sub := Subscription{
+		C:      ch,
+		id:     evts.next,
+		ch:     ch,
+		filter: filter,
+	}
+	evts.subs[sub.id] = &sub
+
+	return &sub
+}
+
+// Unsubscribe removes the subscription and closes its channel.
+func (evts *Events) Unsubscribe(sub *Subscription) {
+	evts.mu.Lock()
+	defer evts.mu.Unlock()
+
+	if _, exists := evts.subs[sub.id]; !exists {
+		return
+	}
+
+	delete(evts.subs, sub.id)
+	close(sub.ch)
+}
+
+// Publish delivers the event to the handlers and to every subscription it
+// matches.
+func (evts *Events) Publish(evt Event) {
+	evts.mu.RLock()
+	defer evts.mu.RUnlock()
+
+	for _, fn := range evts.handlers {
+		fn(evt)
+	}
+
+	for _, sub := range evts.subs {
+		if !sub.filter.Match(evt) {
+			continue
+		}
+
+		select {
+		case sub.ch <- evt:
+		default:
+		}
+	}
+}
+
+// Tracef publishes a trace message. It has the signature of the event handler
+// functions taken by the blockchain packages.
+func (evts *Events) Tracef(format string, args ...any) {
+	evts.Publish(Trace{Message: fmt.Sprintf(format, args...)})
+}
+
+// Shutdown ends every subscription. The handlers keep receiving the events
+// published during the rest of the shutdown.
+func (evts *Events) Shutdown() {
+	evts.mu.Lock()
+	defer evts.mu.Unlock()
+
+	for id, sub := range evts.subs {
+		delete(evts.subs, id)
+		close(sub.ch)
+	}
+}
+
+// =============================================================================
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, value) {
+			return true
+		}
+	}
+	return false
+}
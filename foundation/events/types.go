@@ -0,0 +1,183 @@
+package events
+
+import "time"
+
+// Set of event types published by the node.
+const (
+	TypeTrace          = "trace"
+	TypeBlockMined     = "block_mined"
+	TypeBlockAccepted  = "block_accepted"
+	TypeBlockRejected  = "block_rejected"
+	TypeTxAdded        = "tx_added"
+	TypeTxEvicted      = "tx_evicted"
+	TypeBalanceChanged = "balance_changed"
+	TypePeerAdded      = "peer_added"
+	TypePeerRemoved    = "peer_removed"
+	TypeSyncStarted    = "sync_started"
+	TypeSyncCompleted  = "sync_completed"
+)
+
+// ChainTypes is the list of event types describing the chain, the mempool and
+// the peers. Traces are left out since they're only meant for the logs.
+var ChainTypes = []string{
+	TypeBlockMined,
+	TypeBlockAccepted,
+	TypeBlockRejected,
+	TypeTxAdded,
+	TypeTxEvicted,
+	TypeBalanceChanged,
+	TypePeerAdded,
+	TypePeerRemoved,
+	TypeSyncStarted,
+	TypeSyncCompleted,
+}
+
+// Set of reasons a transaction is evicted from the mempool.
+const (
+	EvictCommitted = "committed"
+	EvictReplaced  = "replaced"
+	EvictDropped   = "dropped"
+)
+
+// =============================================================================
+
+// Trace is a message describing what the node is doing.
+type Trace struct {
+	Message string `json:"message"`
+}
+
+// EventType implements the Event interface.
+func (Trace) EventType() string { return TypeTrace }
+
+// Tx describes a transaction carried by an event.
+type Tx struct {
+	Hash  string `json:"hash"`
+	From  string `json:"from"`
+	To    string `json:"to"`
+	Nonce uint64 `json:"nonce"`
+	Value uint64 `json:"value"`
+	Tip   uint64 `json:"tip"`
+}
+
+// BlockMined is published when this node produced a block.
+type BlockMined struct {
+	Number   uint64        `json:"number"`
+	Hash     string        `json:"hash"`
+	TxCount  int           `json:"tx_count"`
+	Duration time.Duration `json:"duration,omitempty"`
+}
+
+// EventType implements the Event interface.
+func (BlockMined) EventType() string { return TypeBlockMined }
+
+// BlockAccepted is published when a block is added to the chain, whoever
+// produced it.
+type BlockAccepted struct {
+	Number        uint64 `json:"number"`
+	Hash          string `json:"hash"`
+	PrevBlockHash string `json:"prev_block_hash"`
+	Timestamp     uint64 `json:"timestamp"`
+	Beneficiary   string `json:"beneficiary"`
+	Txs           []Tx   `json:"txs"`
+}
+
+// EventType implements the Event interface.
+func (BlockAccepted) EventType() string { return TypeBlockAccepted }
+
+// EventAccounts returns the beneficiary and the accounts of the transactions.
+func (e BlockAccepted) EventAccounts() []string {
+	accounts := []string{e.Beneficiary}
+	for _, tx := range e.Txs {
+		accounts = append(accounts, tx.From, tx.To)
+	}
+	return accounts
+}
+
+// BlockRejected is published when a block proposed by a peer is refused.
+type BlockRejected struct {
+	Number uint64 `json:"number"`
+	Hash   string `json:"hash"`
+	Peer   string `json:"peer,omitempty"`
+	Reason string `json:"reason"`
+}
+
+// EventType implements the Event interface.
+func (BlockRejected) EventType() string { return TypeBlockRejected }
+
+// TxAdded is published when a transaction enters the mempool.
+type TxAdded struct {
+	Tx
+}
+
+// EventType implements the Event interface.
+func (TxAdded) EventType() string { return TypeTxAdded }
+
+// EventAccounts returns the accounts of the transaction.
+func (e TxAdded) EventAccounts() []string { return []string{e.From, e.To} }
+
+// TxEvicted is published when a transaction leaves the mempool.
+type TxEvicted struct {
+	Tx
+	Reason string `json:"reason"`
+}
+
+// EventType implements the Event interface.
+func (TxEvicted) EventType() string { return TypeTxEvicted }
+
+// EventAccounts returns the accounts of the transaction.
+func (e TxEvicted) EventAccounts() []string { return []string{e.From, e.To} }
+
+// BalanceChanged is published when a block changes an account.
+type BalanceChanged struct {
+	Account     string `json:"account"`
+	Balance     uint64 `json:"balance"`
+	Stake       uint64 `json:"stake"`
+	Nonce       uint64 `json:"nonce"`
+	BlockNumber uint64 `json:"block_number"`
+}
+
+// EventType implements the Event interface.
+func (BalanceChanged) EventType() string { return TypeBalanceChanged }
+
+// EventAccounts returns the account that changed.
+func (e BalanceChanged) EventAccounts() []string { return []string{e.Account} }
+
+// PeerAdded is published when a peer joins the active peers.
+type PeerAdded struct {
+	Host   string `json:"host"`
+	NodeID string `json:"node_id,omitempty"`
+}
+
+// EventType implements the Event interface.
+func (PeerAdded) EventType() string { return TypePeerAdded }
+
+// PeerRemoved is published when a peer leaves the active peers.
+type PeerRemoved struct {
+	Host   string `json:"host"`
+	Reason string `json:"reason"`
+}
+
+// EventType implements the Event interface.
+func (PeerRemoved) EventType() string { return TypePeerRemoved }
+
+// SyncStarted is published when the node starts downloading blocks from its
+// peers.
+type SyncStarted struct {
+	From uint64 `json:"from"`
+	To   uint64 `json:"to"`
+	Peer string `json:"peer"`
+}
+
+// EventType implements the Event interface.
+func (SyncStarted) EventType() string { return TypeSyncStarted }
+
+// SyncCompleted is published when a sync ends. Error is set when it didn't
+// reach the target block.
+type SyncCompleted struct {
+	Height   uint64        `json:"height"`
+	Duration time.Duration `json:"duration"`
+	Error    string        `json:"error,omitempty"`
+}
+
+// EventType implements the Event interface.
+func (SyncCompleted) EventType() string { return TypeSyncCompleted }
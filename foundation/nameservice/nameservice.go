@@ -58,3 +58,14 @@ func (ns *NameService) Copy() map[database.AccountID]string {
 	}
 	return accounts
 }
+
+// Reverse returns the account with the specified name. Names are matched
+// without regard to case.
+func (ns *NameService) Reverse(name string) (database.AccountID, bool) {
+	for ac, n := range ns.accounts {
+		if strings.EqualFold(n, name) {
+			return ac, true
+		}
+	}
+	return "", false
+}